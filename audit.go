@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxAuditOutput caps the command output kept per audit entry
+	maxAuditOutput = 4096
+	// maxAuditArgv caps the bytes of command line kept per audit entry; an
+	// undochange of many files lists every path
+	maxAuditArgv = 16 * 1024
+	// maxAuditLine is the longest log line read back; longer lines are skipped
+	maxAuditLine = 4*maxAuditOutput + 2*maxAuditArgv + 64*1024
+	// systemAuditPath is where the audit log goes when running as root
+	systemAuditPath = "/var/log/snapper-tui/audit.jsonl"
+)
+
+// AuditEntry is one action started from the TUI: a snapper command that ran
+// or, in dry-run mode, would have run, or a file restore. It is one line of
+// the audit log.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	SudoUser   string    `json:"sudo_user,omitempty"`
+	Action     string    `json:"action"`
+	Config     string    `json:"config,omitempty"`
+	Numbers    []int     `json:"numbers,omitempty"`
+	Invocation           // command line or D-Bus call the backend runs
+	Path       string    `json:"path,omitempty"` // live path of a file restore
+	DryRun     bool      `json:"dry_run,omitempty"`
+	ExitStatus *int      `json:"exit_status,omitempty"` // nil for dry runs; -1 when the action failed without an exit code
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	Output     string    `json:"output,omitempty"`
+}
+
+// operationEntry is the audit entry of an operation the backend runs as inv
+func operationEntry(op Operation, inv Invocation) AuditEntry {
+	return AuditEntry{
+		Action:     op.Kind,
+		Config:     op.Config,
+		Numbers:    op.Numbers,
+		Invocation: Invocation{Argv: elideArgv(inv.Argv), Method: inv.Method, Args: inv.Args},
+	}
+}
+
+// elideArgv keeps the leading arguments that fit in maxAuditArgv bytes and
+// replaces the rest with a count
+func elideArgv(argv []string) []string {
+	size := 0
+	for i, arg := range argv {
+		size += len(arg) + 1
+		if size > maxAuditArgv {
+			return append(argv[:i:i], fmt.Sprintf("…[%d more arguments]", len(argv)-i))
+		}
+	}
+	return argv
+}
+
+// command is the entry as a command line or D-Bus call, or the action and
+// path for actions that run neither
+func (e AuditEntry) command() string {
+	if len(e.Argv) == 0 && e.Method == "" {
+		return e.Action + " " + e.Path
+	}
+	return e.Invocation.String()
+}
+
+// outcome is the entry's result in a word or two
+func (e AuditEntry) outcome() string {
+	switch {
+	case e.DryRun:
+		return "dry run"
+	case e.ExitStatus == nil:
+		return "unknown"
+	case *e.ExitStatus == 0:
+		return "ok"
+	case *e.ExitStatus < 0:
+		return "failed"
+	}
+	return "exit " + strconv.Itoa(*e.ExitStatus)
+}
+
+// finish records the result of a run that took since start
+func (e *AuditEntry) finish(start time.Time, output string, err error) {
+	e.DurationMS = time.Since(start).Milliseconds()
+	e.Output = truncateOutput(output)
+	status := 0
+	if err != nil {
+		e.Error = err.Error()
+		status = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			status = exitErr.ExitCode()
+		}
+	}
+	e.ExitStatus = &status
+}
+
+// truncateOutput keeps the first maxAuditOutput bytes of output, cut at a
+// rune boundary
+func truncateOutput(output string) string {
+	if len(output) <= maxAuditOutput {
+		return output
+	}
+	cut := maxAuditOutput
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return output[:cut] + "\n…[truncated]"
+}
+
+// invokingUser returns the user running the TUI and, under sudo, the user
+// who ran sudo
+func invokingUser() (name, sudoUser string) {
+	name = strconv.Itoa(os.Getuid())
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	return name, os.Getenv("SUDO_USER")
+}
+
+// defaultAuditPath is the system log for root and a per-user state file
+// otherwise
+func defaultAuditPath() string {
+	if os.Geteuid() == 0 {
+		return systemAuditPath
+	}
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "snapper-tui", "audit.jsonl")
+}
+
+// AuditLog appends entries to a JSON Lines file; it is only ever appended to
+type AuditLog struct {
+	Path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openAuditLog opens the log for appending, creating it and its directory
+// readable by the owner only
+func openAuditLog(path string) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{Path: path, file: file}, nil
+}
+
+// Append writes one entry as a single line, so concurrent writers never
+// interleave
+func (a *AuditLog) Append(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.file.Write(append(line, '\n'))
+	return err
+}
+
+// readAuditLog reads every entry of a log, oldest first; lines that do not
+// parse or are longer than maxAuditLine are skipped
+func readAuditLog(path string) ([]AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	var line []byte
+	oversized := false
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		chunk, more, err := reader.ReadLine()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		if !oversized {
+			line = append(line, chunk...)
+			oversized = len(line) > maxAuditLine
+		}
+		if more {
+			continue
+		}
+		var entry AuditEntry
+		if !oversized && json.Unmarshal(line, &entry) == nil {
+			entries = append(entries, entry)
+		}
+		line, oversized = line[:0], false
+	}
+}
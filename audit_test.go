@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestAuditEntryFinish(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	if exitErr == nil {
+		t.Fatal("sh did not fail")
+	}
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"success", nil, 0},
+		{"exit status", exitErr, 3},
+		{"wrapped exit status", fmt.Errorf("snapper create failed: %w", exitErr), 3},
+		{"no exit code", errors.New("snapperd: unknown config"), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry AuditEntry
+			entry.finish(time.Now(), "output", tt.err)
+			if entry.ExitStatus == nil || *entry.ExitStatus != tt.status {
+				t.Errorf("exit status = %v, want %d", entry.ExitStatus, tt.status)
+			}
+			if (entry.Error != "") != (tt.err != nil) {
+				t.Errorf("error = %q", entry.Error)
+			}
+			if entry.Output != "output" {
+				t.Errorf("output = %q", entry.Output)
+			}
+		})
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	short := strings.Repeat("a", maxAuditOutput)
+	if got := truncateOutput(short); got != short {
+		t.Error("output at the limit was truncated")
+	}
+
+	// "é" is two bytes; the limit falls inside the last one kept whole
+	long := strings.Repeat("a", maxAuditOutput-1) + strings.Repeat("é", 10)
+	got := truncateOutput(long)
+	kept := strings.TrimSuffix(got, "\n…[truncated]")
+	if kept == got {
+		t.Fatalf("no truncation marker in %q", got[len(got)-20:])
+	}
+	if !utf8.ValidString(kept) {
+		t.Error("cut inside a rune")
+	}
+	if len(kept) != maxAuditOutput-1 {
+		t.Errorf("kept %d bytes, want %d", len(kept), maxAuditOutput-1)
+	}
+}
+
+func TestAuditLogRoundTrip(t *testing.T) {
+	path := t.TempDir() + "/state/audit.jsonl"
+	audit, err := openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := 1
+	written := []AuditEntry{
+		{
+			Time: time.Date(2025, 11, 18, 9, 0, 0, 0, time.UTC), User: "root", SudoUser: "alice",
+			Action: "delete", Config: "root", Numbers: []int{4, 5},
+			Invocation: Invocation{Argv: []string{"snapper", "-c", "root", "delete", "4", "5"}},
+			ExitStatus: &status, Error: "exit status 1", DurationMS: 12, Output: "snapshot in use",
+		},
+		{
+			Time: time.Date(2025, 11, 18, 9, 1, 0, 0, time.UTC), User: "root", Action: "delete", Config: "home", Numbers: []int{2},
+			Invocation: Invocation{Method: "DeleteSnapshots", Args: []interface{}{"home", []uint32{2}}}, DryRun: true,
+		},
+		{Time: time.Date(2025, 11, 18, 9, 2, 0, 0, time.UTC), User: "root", Action: "restore-file", Config: "root", Numbers: []int{3}, Path: "/etc/fstab"},
+	}
+	for _, entry := range written {
+		if err := audit.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A line that does not parse is skipped, not fatal
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("not json\n")
+	file.Close()
+
+	read, err := readAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(written) {
+		t.Fatalf("read %d entries, want %d", len(read), len(written))
+	}
+	wantCommands := []string{"snapper -c root delete 4 5", `snapperd DeleteSnapshots("home", [2])`, "restore-file /etc/fstab"}
+	wantOutcomes := []string{"exit 1", "dry run", "unknown"}
+	for i, entry := range read {
+		if !entry.Time.Equal(written[i].Time) || entry.SudoUser != written[i].SudoUser || fmt.Sprint(entry.Numbers) != fmt.Sprint(written[i].Numbers) {
+			t.Errorf("entry %d = %+v", i, entry)
+		}
+		if got := entry.command(); got != wantCommands[i] {
+			t.Errorf("entry %d command = %q, want %q", i, got, wantCommands[i])
+		}
+		if got := entry.outcome(); got != wantOutcomes[i] {
+			t.Errorf("entry %d outcome = %q, want %q", i, got, wantOutcomes[i])
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("log mode = %v, %v", info.Mode(), err)
+	}
+}
+
+func TestElideArgv(t *testing.T) {
+	short := []string{"snapper", "-c", "root", "undochange", "1..2", "/etc/fstab"}
+	if got := elideArgv(short); strings.Join(got, " ") != strings.Join(short, " ") {
+		t.Errorf("short argv = %q", got)
+	}
+
+	long := []string{"snapper", "-c", "root", "undochange", "1..2"}
+	for i := 0; i < 2000; i++ {
+		long = append(long, fmt.Sprintf("/srv/data/file-%04d", i))
+	}
+	got := elideArgv(long)
+	size := 0
+	for _, arg := range got[:len(got)-1] {
+		size += len(arg) + 1
+	}
+	if size > maxAuditArgv {
+		t.Errorf("kept %d bytes of arguments", size)
+	}
+	kept := len(got) - 1
+	if want := fmt.Sprintf("…[%d more arguments]", len(long)-kept); got[kept] != want {
+		t.Errorf("last argument = %q, want %q", got[kept], want)
+	}
+	if got[5] != "/srv/data/file-0000" || long[kept] == got[kept] {
+		t.Errorf("argv = %q…", got[:6])
+	}
+}
+
+func TestReadAuditLogSkipsOversizedLines(t *testing.T) {
+	path := t.TempDir() + "/audit.jsonl"
+	audit, err := openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A big undochange is elided enough to read back
+	paths := make([]string, 5000)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/srv/data/%s-%04d", strings.Repeat("x", 40), i)
+	}
+	op := Operation{Kind: "undochange", Config: "srv", Numbers: []int{1, 2}, Paths: paths}
+	big := operationEntry(op, Invocation{Argv: append([]string{"snapper", "-c", "srv", "undochange", "1..2"}, paths...)})
+	big.Output = strings.Repeat("\x00", maxAuditOutput)
+	for _, entry := range []AuditEntry{{Action: "delete", Config: "root"}, big} {
+		if err := audit.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A line past the limit is skipped without losing the entries after it
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"action": "huge", "output": "` + strings.Repeat("a", 2*maxAuditLine) + "\"}\n")
+	file.Close()
+	if err := audit.Append(AuditEntry{Action: "rollback", Config: "root"}); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := readAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, entry := range read {
+		actions = append(actions, entry.Action)
+	}
+	if got := strings.Join(actions, " "); got != "delete undochange rollback" {
+		t.Errorf("actions = %q", got)
+	}
+}
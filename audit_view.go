@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// auditDetailHeight is the number of lines showing the entry under the cursor
+const auditDetailHeight = 9
+
+// AuditView browses the audit log, newest entry first
+type AuditView struct {
+	Entries []AuditEntry
+	Cursor  int
+	Offset  int
+	Source  string // log file, or "" for this session's actions
+	Err     error
+}
+
+// AuditLoadedMsg carries the audit log as read from disk
+type AuditLoadedMsg struct {
+	Entries []AuditEntry
+	Err     error
+}
+
+// loadAuditCmd reads the audit log; without one it falls back to the
+// actions recorded this session
+func loadAuditCmd(recorder *RecorderClient) tea.Cmd {
+	return func() tea.Msg {
+		if recorder.Audit == nil {
+			return AuditLoadedMsg{Entries: recorder.Log()}
+		}
+		entries, err := readAuditLog(recorder.Audit.Path)
+		return AuditLoadedMsg{Entries: entries, Err: err}
+	}
+}
+
+// openAudit shows the audit history screen
+func (m UIState) openAudit() (tea.Model, tea.Cmd) {
+	v := &AuditView{}
+	if m.Recorder.Audit != nil {
+		v.Source = m.Recorder.Audit.Path
+	}
+	m.AuditView = v
+	m.Screen = "audit"
+	m.Status = "Loading the audit log..."
+	return m, loadAuditCmd(m.Recorder)
+}
+
+// handleAuditLoaded shows the entries newest first
+func (m UIState) handleAuditLoaded(msg AuditLoadedMsg) (tea.Model, tea.Cmd) {
+	v := m.AuditView
+	if v == nil {
+		return m, nil
+	}
+	v.Err = msg.Err
+	v.Entries = make([]AuditEntry, 0, len(msg.Entries))
+	for i := len(msg.Entries) - 1; i >= 0; i-- {
+		v.Entries = append(v.Entries, msg.Entries[i])
+	}
+	v.Cursor, v.Offset = 0, 0
+	if msg.Err != nil {
+		m.Status = fmt.Sprintf("Reading the audit log failed: %v", msg.Err)
+	} else {
+		m.Status = fmt.Sprintf("%d audit entries", len(v.Entries))
+	}
+	return m, nil
+}
+
+// auditListHeight is the number of entries shown above the details
+func (m UIState) auditListHeight() int {
+	return max(1, m.pageHeight()-auditDetailHeight-1)
+}
+
+// moveCursor moves the cursor by delta entries, keeping it in view
+func (v *AuditView) moveCursor(delta, page int) {
+	v.Cursor = max(0, min(len(v.Entries)-1, v.Cursor+delta))
+	if v.Cursor < v.Offset {
+		v.Offset = v.Cursor
+	}
+	if v.Cursor >= v.Offset+page {
+		v.Offset = v.Cursor - page + 1
+	}
+}
+
+// handleAuditKey drives the audit history screen
+func (m UIState) handleAuditKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.AuditView
+	page := m.auditListHeight()
+	switch msg.String() {
+	case "j", "down":
+		v.moveCursor(1, page)
+	case "k", "up":
+		v.moveCursor(-1, page)
+	case "pgdn", "pagedown":
+		v.moveCursor(page, page)
+	case "pgup", "pageup":
+		v.moveCursor(-page, page)
+	case "g", "home":
+		v.moveCursor(-len(v.Entries), page)
+	case "G", "end":
+		v.moveCursor(len(v.Entries), page)
+	case "r":
+		m.Status = "Reloading the audit log..."
+		return m, loadAuditCmd(m.Recorder)
+	case "esc", "q":
+		m.Screen = ""
+		m.AuditView = nil
+		m.Status = "Closed the audit history"
+	}
+	return m, nil
+}
+
+// handleAuditMouse scrolls the list and selects entries on click
+func (m UIState) handleAuditMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	v := m.AuditView
+	page := m.auditListHeight()
+	switch msg.Type {
+	case tea.MouseWheelUp:
+		v.moveCursor(-3, page)
+	case tea.MouseWheelDown:
+		v.moveCursor(3, page)
+	case tea.MouseLeft:
+		if row := msg.Y - 3; row >= 0 && row < page {
+			v.moveCursor(v.Offset+row-v.Cursor, page)
+		}
+	}
+	return m, nil
+}
+
+// auditDetails lists every field of an entry, then its output
+func auditDetails(e AuditEntry) []string {
+	user := e.User
+	if e.SudoUser != "" {
+		user += " (sudo by " + e.SudoUser + ")"
+	}
+	lines := []string{
+		fmt.Sprintf("Time: %s   User: %s", e.Time.Local().Format("2006-01-02 15:04:05"), user),
+		fmt.Sprintf("Action: %s   Config: %s   Snapshots: %s", e.Action, nonEmpty(e.Config, "-"), nonEmpty(strings.Trim(fmt.Sprint(e.Numbers), "[]"), "-")),
+		fmt.Sprintf("Command: %s", e.command()),
+		fmt.Sprintf("Result: %s   Duration: %d ms", e.outcome(), e.DurationMS),
+	}
+	if e.Error != "" {
+		lines = append(lines, "Error: "+e.Error)
+	}
+	for _, line := range strings.Split(e.Output, "\n") {
+		if line != "" {
+			lines = append(lines, "  "+line)
+		}
+	}
+	return lines
+}
+
+// renderAuditView draws the audit entries above the details of the one
+// under the cursor
+func (m UIState) renderAuditView(width, height int) string {
+	v := m.AuditView
+	page := m.auditListHeight()
+
+	info := fmt.Sprintf("%d entries | %s", len(v.Entries), nonEmpty(v.Source, "this session only; audit logging is off"))
+	if err := m.Recorder.AuditErr(); err != nil {
+		info += " | writing failed: " + err.Error()
+	}
+
+	var list []string
+	if v.Err != nil {
+		list = append(list, browserWarnStyle.Render(padOrTruncate(v.Err.Error(), width)))
+	}
+	for pos := v.Offset; pos < min(v.Offset+page, len(v.Entries)); pos++ {
+		e := v.Entries[pos]
+		line := padOrTruncate(fmt.Sprintf("%s  %-10s %-8s %-9s %s",
+			e.Time.Local().Format("2006-01-02 15:04:05"), padOrTruncate(nonEmpty(e.SudoUser, e.User), 10),
+			e.outcome(), fmt.Sprintf("%dms", e.DurationMS), e.command()), width)
+		if pos == v.Cursor {
+			line = pagerCursorStyle.Render(line)
+		}
+		list = append(list, line)
+	}
+	if len(v.Entries) == 0 && v.Err == nil {
+		list = append(list, pagerHelpStyle.Render("No actions recorded yet."))
+	}
+
+	var details []string
+	if v.Cursor < len(v.Entries) {
+		for _, line := range auditDetails(v.Entries[v.Cursor]) {
+			details = append(details, padOrTruncate(line, width))
+		}
+	}
+
+	ui := lipgloss.JoinVertical(lipgloss.Left,
+		headerStyle.Width(width).Render(m.headerTitle()),
+		detailHeaderStyle.Render("Audit history"),
+		summaryStyle.Render(padOrTruncate(info, width)),
+		lipgloss.NewStyle().Height(page).MaxHeight(page).Render(strings.Join(list, "\n")),
+		pagerHelpStyle.Render(strings.Repeat("─", width)),
+		lipgloss.NewStyle().Height(auditDetailHeight).MaxHeight(auditDetailHeight).Render(strings.Join(details, "\n")),
+		pagerHelpStyle.Render("↑↓/PgUp/PgDn: Move | g/G: Newest/oldest | r: Reload | esc/q: Back"),
+	)
+	return lipgloss.Place(width, height, lipgloss.Top, lipgloss.Left, ui)
+}
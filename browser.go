@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxSymlinkHops bounds symlink resolution like the kernel's ELOOP limit
+const maxSymlinkHops = 40
+
+// previewBytes is how much of a file the browser reads for its text preview
+const previewBytes = 64 * 1024
+
+// errEscapesSnapshot is returned for paths that would leave the snapshot
+var errEscapesSnapshot = errors.New("symlink points outside the snapshot")
+
+// BrowserEntry is one directory entry of a snapshot, with its link target
+// when it is a symlink
+type BrowserEntry struct {
+	Name   string
+	Info   fs.FileInfo
+	Target string
+}
+
+// FileMeta is the metadata the browser shows for the entry under the cursor
+type FileMeta struct {
+	Path    string // snapshot-relative path
+	Mode    fs.FileMode
+	Size    int64
+	ModTime time.Time
+	Owner   string
+	Group   string
+	Target  string // symlink target as stored
+	Resolve string // snapshot-relative path the symlink resolves to
+	LinkErr error  // why the symlink is not followed
+}
+
+// snapshotRoot is the directory holding the files of a snapshot
+func snapshotRoot(snap Snapshot) string {
+	return filepath.Join(snap.Subvolume, ".snapshots", strconv.Itoa(snap.Number), "snapshot")
+}
+
+// livePath maps a snapshot-relative path to the path it has on the live system
+func livePath(snap Snapshot, rel string) string {
+	return filepath.Join(snap.Subvolume, rel)
+}
+
+// snapshotRelPath is the inverse of livePath: it maps a live path to its
+// path inside snapshots of the config. Paths outside the subvolume have none.
+func snapshotRelPath(snap Snapshot, live string) (string, error) {
+	sub := strings.TrimRight(snap.Subvolume, "/")
+	if sub == "" {
+		return live, nil
+	}
+	if live != sub && !strings.HasPrefix(live, sub+"/") {
+		return "", fmt.Errorf("%s is outside %s, the subvolume of config %s", live, sub, snap.Config)
+	}
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(live, sub), "/"), nil
+}
+
+// resolveInSnapshot resolves symlinks in a snapshot-relative path without
+// leaving root. Absolute link targets are read relative to the snapshot, as
+// they were when it was the live system; ".." above the snapshot is refused.
+func resolveInSnapshot(root, path string) (string, error) {
+	resolved := "/"
+	pending := strings.Split(path, "/")
+	hops := 0
+	for len(pending) > 0 {
+		part := pending[0]
+		pending = pending[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if resolved == "/" {
+				return "", errEscapesSnapshot
+			}
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, part)
+		info, err := os.Lstat(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			return "", errors.New("too many levels of symbolic links")
+		}
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+	return resolved, nil
+}
+
+// readSnapshotDir lists a snapshot directory, directories first, without
+// following any symlinks
+func readSnapshotDir(root, dir string) ([]BrowserEntry, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(root, dir))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]BrowserEntry, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		entry := BrowserEntry{Name: de.Name(), Info: info}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			entry.Target, _ = os.Readlink(filepath.Join(root, dir, de.Name()))
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].Info.IsDir(), entries[j].Info.IsDir()
+		if a != b {
+			return a
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// fileMeta describes an entry, resolving symlinks inside the snapshot
+func fileMeta(root, rel string, entry BrowserEntry) FileMeta {
+	meta := FileMeta{
+		Path:    rel,
+		Mode:    entry.Info.Mode(),
+		Size:    entry.Info.Size(),
+		ModTime: entry.Info.ModTime(),
+		Target:  entry.Target,
+	}
+	if stat, ok := entry.Info.Sys().(*syscall.Stat_t); ok {
+		meta.Owner = userName(stat.Uid)
+		meta.Group = groupName(stat.Gid)
+	}
+	if entry.Target != "" {
+		meta.Resolve, meta.LinkErr = resolveInSnapshot(root, rel)
+	}
+	return meta
+}
+
+// groupName looks up a group name, falling back to the numeric id
+func groupName(gid uint32) string {
+	id := strconv.FormatUint(uint64(gid), 10)
+	if group, err := user.LookupGroupId(id); err == nil {
+		return group.Name
+	}
+	return id
+}
+
+// readPreview returns the start of a text file as lines, or a note when the
+// file is binary
+func readPreview(path string, maxLines int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(file, previewBytes))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return []string{fmt.Sprintf("(binary file, %d bytes read)", len(buf))}, nil
+	}
+	text := strings.ReplaceAll(string(bytes.ToValidUTF8(buf, []byte("�"))), "\t", "    ")
+	lines := strings.Split(text, "\n")
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+	return lines, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotPaths(t *testing.T) {
+	tests := []struct {
+		subvolume string
+		live      string
+		rel       string
+		root      string
+	}{
+		{"/", "/etc/fstab", "/etc/fstab", "/.snapshots/7/snapshot"},
+		{"/home", "/home/alice/.bashrc", "/alice/.bashrc", "/home/.snapshots/7/snapshot"},
+		{"/home/", "/home/alice/.bashrc", "/alice/.bashrc", "/home/.snapshots/7/snapshot"},
+		{"/home", "/home", "/", "/home/.snapshots/7/snapshot"},
+	}
+	for _, tt := range tests {
+		snap := Snapshot{Config: "test", Subvolume: tt.subvolume, Number: 7}
+		if got := snapshotRoot(snap); got != tt.root {
+			t.Errorf("snapshotRoot(%s) = %q, want %q", tt.subvolume, got, tt.root)
+		}
+		if got, err := snapshotRelPath(snap, tt.live); got != tt.rel || err != nil {
+			t.Errorf("snapshotRelPath(%s, %s) = %q, %v, want %q", tt.subvolume, tt.live, got, err, tt.rel)
+		}
+		if got := livePath(snap, tt.rel); got != tt.live {
+			t.Errorf("livePath(%s, %s) = %q, want %q", tt.subvolume, tt.rel, got, tt.live)
+		}
+	}
+
+	// Live paths outside the subvolume, including ones that merely share its
+	// name as a prefix, have no snapshot path
+	for _, live := range []string{"/homework/x", "/etc/fstab", "/"} {
+		if rel, err := snapshotRelPath(Snapshot{Config: "home", Subvolume: "/home"}, live); err == nil {
+			t.Errorf("snapshotRelPath(/home, %s) = %q, want an error", live, rel)
+		}
+	}
+
+	// Binary comparisons look the file up through the same mapping
+	cmp := compareFiles(Snapshot{Subvolume: "/home"}, 3, 0, "/home/alice/.bashrc")
+	if cmp.Old.Path != "/home/.snapshots/3/snapshot/alice/.bashrc" || cmp.New.Path != "/home/alice/.bashrc" {
+		t.Errorf("compared %s with %s", cmp.Old.Path, cmp.New.Path)
+	}
+}
+
+func TestResolveInSnapshot(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "etc/fstab"), "UUID=1 / btrfs defaults\n", 0o644)
+	writeFile(t, filepath.Join(root, "usr/lib/os-release"), "NAME=test\n", 0o644)
+	links := map[string]string{
+		"etc/os-release": "../usr/lib/os-release",
+		"etc/mtab":       "/etc/fstab", // absolute: re-rooted inside the snapshot
+		"etc/abs-dir":    "/usr/lib",
+		"etc/chain1":     "chain2",
+		"etc/chain2":     "/etc/chain3",
+		"etc/chain3":     "os-release",
+		"etc/escape":     "../../outside",
+		"etc/loop-a":     "loop-b",
+		"etc/loop-b":     "loop-a",
+		"etc/self":       "self",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want string
+		err  string
+	}{
+		{"/etc/fstab", "/etc/fstab", ""},
+		{"etc//./fstab", "/etc/fstab", ""},
+		{"/etc/mtab", "/etc/fstab", ""},
+		{"/etc/abs-dir/os-release", "/usr/lib/os-release", ""},
+		{"/etc/os-release", "/usr/lib/os-release", ""},
+		{"/etc/chain1", "/usr/lib/os-release", ""},
+		{"/usr/lib/../../etc/fstab", "/etc/fstab", ""},
+		{"/..", "", errEscapesSnapshot.Error()},
+		{"/etc/../../etc/fstab", "", errEscapesSnapshot.Error()},
+		{"/etc/escape", "", errEscapesSnapshot.Error()},
+		{"/etc/loop-a", "", "too many levels of symbolic links"},
+		{"/etc/self", "", "too many levels of symbolic links"},
+		{"/etc/missing", "", "no such file or directory"},
+	}
+	for _, tt := range tests {
+		got, err := resolveInSnapshot(root, tt.path)
+		if tt.err == "" {
+			if err != nil || got != tt.want {
+				t.Errorf("resolveInSnapshot(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("resolveInSnapshot(%q) = %q, %v; want error %q", tt.path, got, err, tt.err)
+		}
+	}
+
+	// A chain of exactly maxSymlinkHops links still resolves; one more does not
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "end"), "", 0o644)
+	for i := 0; i <= maxSymlinkHops; i++ {
+		target := "end"
+		if i > 0 {
+			target = "hop" + strings.Repeat("x", i-1)
+		}
+		if err := os.Symlink(target, filepath.Join(dir, "hop"+strings.Repeat("x", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := "/hop" + strings.Repeat("x", maxSymlinkHops-1)
+	if got, err := resolveInSnapshot(dir, last); err != nil || got != "/end" {
+		t.Errorf("%d hops: %q, %v", maxSymlinkHops, got, err)
+	}
+	if _, err := resolveInSnapshot(dir, last+"x"); err == nil || !strings.Contains(err.Error(), "too many levels") {
+		t.Errorf("%d hops: %v", maxSymlinkHops+1, err)
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	browserDirStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7dd3fc"))
+	browserLinkStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#e879f9"))
+	browserWarnStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#f87171"))
+)
+
+// BrowserView is the read-only file browser over a snapshot's files
+type BrowserView struct {
+	Snap       Snapshot
+	Root       string // <subvolume>/.snapshots/<n>/snapshot
+	Dir        string // snapshot-relative directory shown, never through a symlink
+	Entries    []BrowserEntry
+	Cursor     int
+	Offset     int
+	Meta       *FileMeta
+	Preview    []string
+	PreviewErr error
+	Err        error
+	Back       string // screen to return to
+}
+
+// BrowserDirMsg carries a listed snapshot directory; Focus names the entry to
+// put the cursor on
+type BrowserDirMsg struct {
+	Dir     string
+	Focus   string
+	Entries []BrowserEntry
+	Err     error
+}
+
+// BrowserPreviewMsg carries the text preview of a snapshot file
+type BrowserPreviewMsg struct {
+	Path  string
+	Lines []string
+	Err   error
+}
+
+// browseDirCmd lists a snapshot directory
+func browseDirCmd(root, dir, focus string) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := readSnapshotDir(root, dir)
+		return BrowserDirMsg{Dir: dir, Focus: focus, Entries: entries, Err: err}
+	}
+}
+
+// browsePreviewCmd reads the preview of a snapshot-relative file
+func browsePreviewCmd(root, path string, maxLines int) tea.Cmd {
+	return func() tea.Msg {
+		lines, err := readPreview(filepath.Join(root, path), maxLines)
+		return BrowserPreviewMsg{Path: path, Lines: lines, Err: err}
+	}
+}
+
+// openBrowser shows the files of the snapshot under the cursor
+func (m UIState) openBrowser() (tea.Model, tea.Cmd) {
+	snap := m.currentSnapshot()
+	if snap == nil {
+		return m, nil
+	}
+	if snap.Number == 0 {
+		m.Status = "Snapshot 0 is the live system; pick a snapshot to browse"
+		return m, nil
+	}
+	root := snapshotRoot(*snap)
+	m.BrowserView = &BrowserView{Snap: *snap, Root: root, Dir: "/"}
+	m.Screen = "browse"
+	m.Status = fmt.Sprintf("Browsing %s (read-only)", root)
+	return m, browseDirCmd(root, "/", "")
+}
+
+// handleBrowserDir shows a listed directory, staying put if it cannot be read
+func (m UIState) handleBrowserDir(msg BrowserDirMsg) (tea.Model, tea.Cmd) {
+	v := m.BrowserView
+	if v == nil {
+		return m, nil
+	}
+	if msg.Err != nil {
+		m.Status = fmt.Sprintf("Cannot open %s: %v", msg.Dir, msg.Err)
+		if v.Entries == nil {
+			v.Err = msg.Err
+		}
+		return m, nil
+	}
+	v.Dir = msg.Dir
+	v.Entries = msg.Entries
+	v.Err = nil
+	v.Cursor, v.Offset = 0, 0
+	for i, entry := range v.Entries {
+		if entry.Name == msg.Focus {
+			v.Cursor = i
+		}
+	}
+	page := m.pageHeight()
+	v.moveCursor(0, page)
+	return m, v.selectionChanged(page)
+}
+
+// handleBrowserPreview shows a preview unless the cursor has moved on
+func (m UIState) handleBrowserPreview(msg BrowserPreviewMsg) (tea.Model, tea.Cmd) {
+	v := m.BrowserView
+	if v == nil || v.Meta == nil || v.previewPath() != msg.Path {
+		return m, nil
+	}
+	v.Preview, v.PreviewErr = msg.Lines, msg.Err
+	return m, nil
+}
+
+// current returns the entry under the cursor
+func (v *BrowserView) current() *BrowserEntry {
+	if v.Cursor < len(v.Entries) {
+		return &v.Entries[v.Cursor]
+	}
+	return nil
+}
+
+// entryPath is the snapshot-relative path of an entry in the shown directory
+func (v *BrowserView) entryPath(entry BrowserEntry) string {
+	return filepath.Join(v.Dir, entry.Name)
+}
+
+// previewPath is the file the preview comes from: the entry itself, or where
+// its symlink resolves inside the snapshot
+func (v *BrowserView) previewPath() string {
+	if v.Meta.Target != "" {
+		return v.Meta.Resolve
+	}
+	return v.Meta.Path
+}
+
+// selectionChanged refreshes the metadata of the entry under the cursor and
+// loads its preview
+func (v *BrowserView) selectionChanged(page int) tea.Cmd {
+	v.Meta, v.Preview, v.PreviewErr = nil, nil, nil
+	entry := v.current()
+	if entry == nil {
+		return nil
+	}
+	meta := fileMeta(v.Root, v.entryPath(*entry), *entry)
+	v.Meta = &meta
+
+	path := v.previewPath()
+	if meta.LinkErr != nil || path == "" {
+		return nil
+	}
+	info, err := os.Lstat(filepath.Join(v.Root, path))
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	return browsePreviewCmd(v.Root, path, page)
+}
+
+// moveCursor moves the highlighted entry and scrolls it into view
+func (v *BrowserView) moveCursor(delta, page int) {
+	if len(v.Entries) == 0 {
+		v.Cursor, v.Offset = 0, 0
+		return
+	}
+	v.Cursor = max(0, min(len(v.Entries)-1, v.Cursor+delta))
+	if v.Cursor < v.Offset {
+		v.Offset = v.Cursor
+	}
+	if v.Cursor >= v.Offset+page {
+		v.Offset = v.Cursor - page + 1
+	}
+	v.Offset = max(0, min(v.Offset, len(v.Entries)-page))
+}
+
+// browserOpen enters the directory under the cursor, following symlinks only
+// while they stay inside the snapshot
+func (m UIState) browserOpen() (tea.Model, tea.Cmd) {
+	v := m.BrowserView
+	entry := v.current()
+	if entry == nil || v.Meta == nil {
+		return m, nil
+	}
+	if entry.Info.IsDir() {
+		return m, browseDirCmd(v.Root, v.entryPath(*entry), "")
+	}
+	if entry.Target == "" {
+		return m, nil
+	}
+	if v.Meta.LinkErr != nil {
+		m.Status = fmt.Sprintf("Not following %s: %v", v.Meta.Path, v.Meta.LinkErr)
+		return m, nil
+	}
+	info, err := os.Lstat(filepath.Join(v.Root, v.Meta.Resolve))
+	if err != nil {
+		m.Status = fmt.Sprintf("Cannot open %s: %v", v.Meta.Resolve, err)
+		return m, nil
+	}
+	if info.IsDir() {
+		return m, browseDirCmd(v.Root, v.Meta.Resolve, "")
+	}
+	// A link to a file: show the file in its own directory
+	return m, browseDirCmd(v.Root, filepath.Dir(v.Meta.Resolve), filepath.Base(v.Meta.Resolve))
+}
+
+// handleBrowserKey drives the file browser
+func (m UIState) handleBrowserKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.BrowserView
+	page := m.pageHeight()
+	before := v.Cursor
+	switch msg.String() {
+	case "esc", "q":
+		m.Screen = v.Back
+		m.BrowserView = nil
+		m.Status = "Closed file browser"
+		return m, nil
+	case "j", "down":
+		v.moveCursor(1, page)
+	case "k", "up":
+		v.moveCursor(-1, page)
+	case "pgdn", "pagedown", " ":
+		v.moveCursor(page, page)
+	case "pgup", "pageup":
+		v.moveCursor(-page, page)
+	case "g", "home":
+		v.moveCursor(-len(v.Entries), page)
+	case "G", "end":
+		v.moveCursor(len(v.Entries), page)
+	case "enter", "l", "right":
+		return m.browserOpen()
+	case "R":
+		if entry := v.current(); entry != nil {
+			return m, m.openRestoreForm(v.Snap, v.entryPath(*entry))
+		}
+	case "H":
+		if entry := v.current(); entry != nil {
+			return m.openHistory(v.Snap, livePath(v.Snap, v.entryPath(*entry)))
+		}
+	case "h", "left", "backspace":
+		if v.Dir != "/" {
+			return m, browseDirCmd(v.Root, filepath.Dir(v.Dir), filepath.Base(v.Dir))
+		}
+	}
+	if v.Cursor != before {
+		return m, v.selectionChanged(page)
+	}
+	return m, nil
+}
+
+// handleBrowserMouse scrolls with the wheel; a click selects an entry and a
+// click on the selected entry opens it
+func (m UIState) handleBrowserMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	v := m.BrowserView
+	page := m.pageHeight()
+	before := v.Cursor
+	switch msg.Type {
+	case tea.MouseWheelUp:
+		v.moveCursor(-3, page)
+	case tea.MouseWheelDown:
+		v.moveCursor(3, page)
+	case tea.MouseLeft:
+		row := msg.Y - 3
+		pos := v.Offset + row
+		if row < 0 || row >= page || pos >= len(v.Entries) || msg.X >= m.browserListWidth() {
+			return m, nil
+		}
+		if pos == v.Cursor {
+			return m.browserOpen()
+		}
+		v.moveCursor(pos-v.Cursor, page)
+	}
+	if v.Cursor != before {
+		return m, v.selectionChanged(page)
+	}
+	return m, nil
+}
+
+// browserListWidth is the width of the entry list; metadata and preview take
+// the rest
+func (m UIState) browserListWidth() int {
+	width := m.TermWidth
+	if width == 0 {
+		width = 80
+	}
+	return max(30, width*2/5)
+}
+
+// renderBrowserView draws the entry list beside metadata and preview
+func (m UIState) renderBrowserView(width, height int) string {
+	v := m.BrowserView
+	page := m.pageHeight()
+	listWidth := m.browserListWidth()
+	sideWidth := max(10, width-listWidth-3)
+
+	title := detailHeaderStyle.Render(fmt.Sprintf("Browse %s #%d (read-only)", v.Snap.Config, v.Snap.Number))
+	info := fmt.Sprintf("%s | live path %s | %d entries", v.Dir, livePath(v.Snap, v.Dir), len(v.Entries))
+
+	var list []string
+	switch {
+	case v.Err != nil:
+		list = append(list, browserWarnStyle.Render(padOrTruncate(v.Err.Error(), listWidth)))
+	case len(v.Entries) == 0:
+		list = append(list, "(empty directory)")
+	}
+	for pos := v.Offset; pos < min(v.Offset+page, len(v.Entries)); pos++ {
+		line := browserEntryLine(v.Entries[pos], listWidth)
+		if pos == v.Cursor {
+			line = pagerCursorStyle.Render(line)
+		}
+		list = append(list, line)
+	}
+
+	side := v.renderMeta(sideWidth)
+	if rest := page - len(side) - 1; rest > 0 && (v.Preview != nil || v.PreviewErr != nil) {
+		side = append(side, pagerHelpStyle.Render(strings.Repeat("─", sideWidth)))
+		if v.PreviewErr != nil {
+			side = append(side, browserWarnStyle.Render(padOrTruncate(v.PreviewErr.Error(), sideWidth)))
+		}
+		for _, line := range v.Preview[:min(rest, len(v.Preview))] {
+			side = append(side, padOrTruncate(line, sideWidth))
+		}
+	}
+
+	body := lipgloss.JoinHorizontal(lipgloss.Top,
+		lipgloss.NewStyle().Width(listWidth).Height(page).Render(strings.Join(list, "\n")),
+		pagerHelpStyle.Render(strings.TrimSuffix(strings.Repeat(" │ \n", page), "\n")),
+		lipgloss.NewStyle().Width(sideWidth).Height(page).MaxHeight(page).Render(strings.Join(side, "\n")),
+	)
+	footer := pagerHelpStyle.Render("↑↓/PgUp/PgDn/g/G: Move | enter/→: Open | ←/backspace: Up | R: Restore | H: History | esc/q: Back")
+	ui := lipgloss.JoinVertical(lipgloss.Left,
+		headerStyle.Width(width).Render(m.headerTitle()),
+		title,
+		summaryStyle.Render(padOrTruncate(info, width)),
+		body,
+		footer,
+	)
+	return lipgloss.Place(width, height, lipgloss.Top, lipgloss.Left, ui)
+}
+
+// browserEntryLine draws one entry: name with a type suffix, and its size
+func browserEntryLine(entry BrowserEntry, width int) string {
+	name := entry.Name
+	size := ""
+	style := lipgloss.NewStyle()
+	switch {
+	case entry.Info.IsDir():
+		name += "/"
+		style = browserDirStyle
+	case entry.Target != "":
+		name += " -> " + entry.Target
+		style = browserLinkStyle
+	case entry.Info.Mode().IsRegular():
+		size = humanReadableBytes(ptrInt64(entry.Info.Size()))
+	}
+	nameWidth := max(1, width-len(size)-1)
+	return style.Render(padOrTruncate(name, nameWidth)) + " " + size
+}
+
+// renderMeta lists the metadata of the entry under the cursor
+func (v *BrowserView) renderMeta(width int) []string {
+	meta := v.Meta
+	if meta == nil {
+		return nil
+	}
+	kind := "file"
+	switch {
+	case meta.Mode.IsDir():
+		kind = "directory"
+	case meta.Mode&fs.ModeSymlink != 0:
+		kind = "symlink"
+	case !meta.Mode.IsRegular():
+		kind = meta.Mode.Type().String()
+	}
+	lines := []string{
+		"Path:     " + meta.Path,
+		"Live:     " + livePath(v.Snap, meta.Path),
+		"Type:     " + kind,
+		fmt.Sprintf("Mode:     %s (%04o)", meta.Mode, meta.Mode.Perm()),
+		"Owner:    " + meta.Owner + ":" + meta.Group,
+		fmt.Sprintf("Size:     %s (%d bytes)", humanReadableBytes(ptrInt64(meta.Size)), meta.Size),
+		"Modified: " + meta.ModTime.Format("2006-01-02 15:04:05"),
+	}
+	for i, line := range lines {
+		lines[i] = padOrTruncate(line, width)
+	}
+	if meta.Target != "" {
+		lines = append(lines, padOrTruncate("Target:   "+meta.Target, width))
+		if meta.LinkErr != nil {
+			lines = append(lines, browserWarnStyle.Render(padOrTruncate("Not followed: "+meta.LinkErr.Error(), width)))
+		} else {
+			lines = append(lines, padOrTruncate("Resolves: "+meta.Resolve, width))
+		}
+	}
+	return lines
+}
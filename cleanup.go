@@ -0,0 +1,408 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// snapperDateLayout is how snapshot dates are listed
+const snapperDateLayout = "2006-01-02 15:04:05"
+
+// cleanupRangeNote explains how limits like "2-10" are simulated
+const cleanupRangeNote = "ranges use the upper limit; snapper goes down to the lower one only when SPACE_LIMIT or FREE_LIMIT is exceeded"
+
+// CleanupPlan is what one run of a cleanup algorithm would delete
+type CleanupPlan struct {
+	Config    string
+	Algorithm string // "number", "timeline" or "empty-pre-post"
+	Doomed    []Snapshot
+	Kept      int      // candidates the algorithm keeps
+	Notes     []string // caveats of the simulation, e.g. ranges
+	doomed    map[SnapshotKey]bool
+}
+
+// Dooms reports whether the plan deletes a snapshot
+func (p *CleanupPlan) Dooms(key SnapshotKey) bool {
+	return p != nil && p.doomed[key]
+}
+
+// sizeText sums the space of the doomed snapshots; sizes snapper does not
+// know are counted separately
+func (p *CleanupPlan) sizeText() string {
+	total, unknown := int64(0), 0
+	for _, snap := range p.Doomed {
+		size := snapshotBytes(snap)
+		if size == nil {
+			unknown++
+			continue
+		}
+		total += *size
+	}
+	if unknown == len(p.Doomed) {
+		return "an unknown amount of space"
+	}
+	text := humanReadableBytes(ptrInt64(total))
+	if unknown > 0 {
+		text += fmt.Sprintf(" (+%d of unknown size)", unknown)
+	}
+	return text
+}
+
+// summary describes the plan in one line
+func (p *CleanupPlan) summary() string {
+	if len(p.Doomed) == 0 {
+		return fmt.Sprintf("%s cleanup of %s would delete nothing (%d candidate(s) kept)", p.Algorithm, p.Config, p.Kept)
+	}
+	return fmt.Sprintf("%s cleanup of %s would delete %d snapshot(s) using %s: %s",
+		p.Algorithm, p.Config, len(p.Doomed), p.sizeText(), numberRanges(p.Doomed, 12))
+}
+
+// numberRanges lists ascending snapshot numbers compactly, e.g. "2-4 6 9-12",
+// eliding ranges past the limit
+func numberRanges(snaps []Snapshot, limit int) string {
+	var ranges []string
+	for i := 0; i < len(snaps); {
+		j := i
+		for j+1 < len(snaps) && snaps[j+1].Number == snaps[j].Number+1 {
+			j++
+		}
+		if len(ranges) == limit {
+			ranges = append(ranges, "…")
+			break
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(snaps[i].Number))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", snaps[i].Number, snaps[j].Number))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, " ")
+}
+
+// CleanupPreviewMsg carries the simulated outcome of a cleanup
+type CleanupPreviewMsg struct {
+	Plan CleanupPlan
+	Err  error
+}
+
+// cleanupSettings are the config values the cleanup rules read
+type cleanupSettings struct {
+	values map[string]string
+}
+
+// limit returns the upper bound of a NUMBER_LIMIT-style value and its lower
+// bound when it is a range
+func (s cleanupSettings) limit(name string) (upper, lower int) {
+	low, high, isRange := strings.Cut(strings.TrimSpace(s.values[name]), "-")
+	lower, _ = strconv.Atoi(low)
+	if !isRange {
+		return lower, lower
+	}
+	upper, _ = strconv.Atoi(high)
+	return upper, lower
+}
+
+// minAge returns a *_MIN_AGE setting; snapper defaults to 1800 seconds
+func (s cleanupSettings) minAge(name string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(s.values[name]))
+	if err != nil {
+		seconds = 1800
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// snapshotTime parses a snapshot date in local time
+func snapshotTime(snap Snapshot) (time.Time, bool) {
+	t, err := time.ParseInLocation(snapperDateLayout, snap.Date, time.Local)
+	return t, err == nil
+}
+
+// cleanupCandidates returns the snapshots of a config marked with the
+// algorithm, oldest first; the live system, the default and the active
+// snapshot are never deleted
+func cleanupCandidates(snaps []Snapshot, config, algorithm string) []Snapshot {
+	var candidates []Snapshot
+	for _, snap := range snaps {
+		if snap.Config != config || snap.Number == 0 || snap.Default || snap.Active {
+			continue
+		}
+		if algorithm != "empty-pre-post" && snap.Cleanup != algorithm {
+			continue
+		}
+		candidates = append(candidates, snap)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Number < candidates[j].Number })
+	return candidates
+}
+
+// emptyPrePostPairs lists the pre snapshots of a config with a post partner;
+// the cleanup needs to know which of these pairs have no changes
+func emptyPrePostPairs(snaps []Snapshot, config string) map[int]int {
+	pairs := map[int]int{}
+	for _, snap := range cleanupCandidates(snaps, config, "empty-pre-post") {
+		if snap.SnapshotType == "pre" && snap.PostNumber != nil {
+			pairs[snap.Number] = *snap.PostNumber
+		}
+	}
+	return pairs
+}
+
+// simulateCleanup applies snapper's cleanup rules to the snapshot list.
+// empty holds the pre numbers whose pre/post comparison has no changes; it is
+// only read by the empty-pre-post algorithm
+func simulateCleanup(snaps []Snapshot, cfg ConfigInfo, algorithm string, empty map[int]bool, now time.Time) CleanupPlan {
+	settings := cleanupSettings{values: cfg.Values}
+	candidates := cleanupCandidates(snaps, cfg.Name, algorithm)
+	plan := CleanupPlan{Config: cfg.Name, Algorithm: algorithm, doomed: map[SnapshotKey]bool{}}
+
+	keep := map[int]bool{}
+	var minAge time.Duration
+	switch algorithm {
+	case "number":
+		minAge = settings.minAge("NUMBER_MIN_AGE")
+		limit, limitLow := settings.limit("NUMBER_LIMIT")
+		important, importantLow := settings.limit("NUMBER_LIMIT_IMPORTANT")
+		kept, keptImportant := 0, 0
+		for i := len(candidates) - 1; i >= 0; i-- {
+			snap := candidates[i]
+			if snap.Userdata["important"] == "yes" {
+				if keptImportant < important {
+					keptImportant++
+					keep[snap.Number] = true
+				}
+			} else if kept < limit {
+				kept++
+				keep[snap.Number] = true
+			}
+		}
+		if limit != limitLow || important != importantLow {
+			plan.Notes = append(plan.Notes, cleanupRangeNote)
+		}
+	case "timeline":
+		minAge = settings.minAge("TIMELINE_MIN_AGE")
+		periods := []struct {
+			setting string
+			period  func(time.Time) string
+		}{
+			{"TIMELINE_LIMIT_HOURLY", func(t time.Time) string { return t.Format("2006-01-02 15") }},
+			{"TIMELINE_LIMIT_DAILY", func(t time.Time) string { return t.Format("2006-01-02") }},
+			{"TIMELINE_LIMIT_WEEKLY", func(t time.Time) string { y, w := t.ISOWeek(); return fmt.Sprintf("%d-W%d", y, w) }},
+			{"TIMELINE_LIMIT_MONTHLY", func(t time.Time) string { return t.Format("2006-01") }},
+			{"TIMELINE_LIMIT_QUARTERLY", func(t time.Time) string { return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3) }},
+			{"TIMELINE_LIMIT_YEARLY", func(t time.Time) string { return t.Format("2006") }},
+		}
+		times := make([]time.Time, len(candidates))
+		for i, snap := range candidates {
+			times[i], _ = snapshotTime(snap)
+		}
+		kept := make([]int, len(periods))
+		ranged := false
+		// Newest first, keep the first snapshot of each period until the
+		// period's limit is reached
+		for i := len(candidates) - 1; i >= 0; i-- {
+			for p, period := range periods {
+				limit, low := settings.limit(period.setting)
+				ranged = ranged || limit != low
+				first := i == 0 || period.period(times[i-1]) != period.period(times[i])
+				if first && kept[p] < limit {
+					kept[p]++
+					keep[candidates[i].Number] = true
+				}
+			}
+		}
+		if ranged {
+			plan.Notes = append(plan.Notes, cleanupRangeNote)
+		}
+	case "empty-pre-post":
+		minAge = settings.minAge("EMPTY_PRE_POST_MIN_AGE")
+		pairs := emptyPrePostPairs(snaps, cfg.Name)
+		doomedPair := map[int]bool{}
+		for pre, post := range pairs {
+			if empty[pre] {
+				doomedPair[pre], doomedPair[post] = true, true
+			}
+		}
+		for _, snap := range candidates {
+			if !doomedPair[snap.Number] {
+				keep[snap.Number] = true
+			}
+		}
+	}
+
+	// Young snapshots survive, as does either half of a pre/post pair whose
+	// partner survives
+	doomed := map[int]bool{}
+	for _, snap := range candidates {
+		t, ok := snapshotTime(snap)
+		if keep[snap.Number] || !ok || now.Sub(t) < minAge {
+			continue
+		}
+		doomed[snap.Number] = true
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, snap := range candidates {
+			if !doomed[snap.Number] {
+				continue
+			}
+			partner := snap.PostNumber
+			if snap.SnapshotType == "post" {
+				partner = snap.PreNumber
+			}
+			if partner != nil && !doomed[*partner] && containsNumber(candidates, *partner) {
+				delete(doomed, snap.Number)
+				changed = true
+			}
+		}
+	}
+
+	for _, snap := range candidates {
+		if doomed[snap.Number] {
+			plan.Doomed = append(plan.Doomed, snap)
+			plan.doomed[snap.Key()] = true
+		} else {
+			plan.Kept++
+		}
+	}
+	return plan
+}
+
+// containsNumber reports whether a snapshot number is in the list
+func containsNumber(snaps []Snapshot, number int) bool {
+	for _, snap := range snaps {
+		if snap.Number == number {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanupPreviewCmd reads the config, compares the pre/post pairs when the
+// algorithm needs it and simulates the cleanup
+func cleanupPreviewCmd(client SnapperClient, config, algorithm string, snaps []Snapshot) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := client.GetConfig(config)
+		if err != nil {
+			return CleanupPreviewMsg{Err: err}
+		}
+		empty := map[int]bool{}
+		if algorithm == "empty-pre-post" {
+			for pre, post := range emptyPrePostPairs(snaps, config) {
+				output, err := client.Status(config, pre, post)
+				if err != nil {
+					return CleanupPreviewMsg{Err: fmt.Errorf("comparing %d..%d: %s", pre, post, nonEmpty(output, err.Error()))}
+				}
+				empty[pre] = strings.TrimSpace(output) == ""
+			}
+		}
+		return CleanupPreviewMsg{Plan: simulateCleanup(snaps, cfg, algorithm, empty, time.Now())}
+	}
+}
+
+// cleanupCmd runs the real "snapper cleanup"
+func cleanupCmd(client SnapperClient, plan CleanupPlan) tea.Cmd {
+	return func() tea.Msg {
+		output, err := client.Cleanup(plan.Config, plan.Algorithm)
+		return ActionResultMsg{Kind: ActionCleanup, Snap: Snapshot{Config: plan.Config}, Output: output, Err: err}
+	}
+}
+
+// cleanupFormInput holds the values bound to the cleanup dialogs
+type cleanupFormInput struct {
+	Config    string
+	Algorithm string
+	Confirmed bool
+}
+
+// openCleanupForm picks the config and algorithm to simulate, or, when a
+// simulation is already marked in the table, asks to run the real cleanup
+func (m *UIState) openCleanupForm() tea.Cmd {
+	if plan := m.CleanupPlan; plan != nil {
+		return m.openRunCleanupForm(plan)
+	}
+	configs := m.configNames()
+	if len(configs) == 0 {
+		m.Status = "No configs to clean up"
+		return nil
+	}
+	input := &cleanupFormInput{Config: configs[0], Algorithm: "number"}
+	if snap := m.currentSnapshot(); snap != nil {
+		input.Config = snap.Config
+		if snap.Cleanup != "" {
+			input.Algorithm = snap.Cleanup
+		}
+	}
+	m.CleanupInput = input
+	m.FormKind = "cleanup"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Config").
+				Options(huh.NewOptions(configs...)...).
+				Value(&input.Config),
+			huh.NewSelect[string]().
+				Title("Cleanup algorithm").
+				Description("Simulated locally; nothing is deleted yet").
+				Options(huh.NewOptions(cleanupAlgorithms[1:]...)...).
+				Value(&input.Algorithm),
+		),
+	).WithShowHelp(true).WithWidth(60)
+	m.Status = "Preview cleanup: enter to simulate, esc to cancel"
+	return m.Form.Init()
+}
+
+// openRunCleanupForm confirms running the cleanup that was simulated
+func (m *UIState) openRunCleanupForm(plan *CleanupPlan) tea.Cmd {
+	input := &cleanupFormInput{Config: plan.Config, Algorithm: plan.Algorithm}
+	description := plan.summary()
+	for _, note := range plan.Notes {
+		description += "\nNote: " + note
+	}
+	description += "\nRuns: " + m.Client.Describe(Operation{Kind: "cleanup", Config: plan.Config, Algorithm: plan.Algorithm}).String()
+	m.CleanupInput = input
+	m.FormKind = "runcleanup"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("Run the %s cleanup of %s?", plan.Algorithm, plan.Config)).
+				Description(description).
+				Affirmative("Run cleanup").
+				Negative("Cancel").
+				Value(&input.Confirmed),
+		),
+	).WithShowHelp(true).WithWidth(80)
+	m.Status = "Run cleanup: confirm or esc to cancel"
+	return m.Form.Init()
+}
+
+// handleCleanupPreview marks the snapshots the cleanup would delete
+func (m UIState) handleCleanupPreview(msg CleanupPreviewMsg) (tea.Model, tea.Cmd) {
+	m.ActionInProgress = false
+	if msg.Err != nil {
+		m.CleanupPlan = nil
+		m.ActionMessage = fmt.Sprintf("Cleanup preview failed: %v", msg.Err)
+		m.Status = "Cleanup preview failed"
+		return m, nil
+	}
+	plan := msg.Plan
+	m.ActionMessage = plan.summary()
+	for _, note := range plan.Notes {
+		m.ActionMessage += "\nNote: " + note
+	}
+	if len(plan.Doomed) == 0 {
+		m.CleanupPlan = nil
+		m.Status = "Nothing to clean up"
+		return m, nil
+	}
+	m.CleanupPlan = &plan
+	m.ActionMessage += "\nX: Run this cleanup • esc: Discard the preview"
+	m.Status = fmt.Sprintf("Marked %d snapshot(s) the %s cleanup would delete", len(plan.Doomed), plan.Algorithm)
+	return m, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cleanupNow is the moment every simulation in these tests runs at
+var cleanupNow = time.Date(2025, 6, 15, 12, 0, 0, 0, time.Local)
+
+// cleanupSnap builds a snapshot of config "root" taken age before cleanupNow
+func cleanupSnap(number int, cleanup string, age time.Duration) Snapshot {
+	return Snapshot{
+		Config:       "root",
+		Number:       number,
+		SnapshotType: "single",
+		Cleanup:      cleanup,
+		Date:         cleanupNow.Add(-age).Format(snapperDateLayout),
+	}
+}
+
+// at builds a timeline snapshot taken at a wall-clock time
+func at(number int, date string) Snapshot {
+	return Snapshot{Config: "root", Number: number, SnapshotType: "single", Cleanup: "timeline", Date: date}
+}
+
+// important marks a snapshot with userdata important=yes
+func important(snap Snapshot) Snapshot {
+	snap.Userdata = map[string]string{"important": "yes"}
+	return snap
+}
+
+// pair turns two snapshots into a pre/post pair
+func pair(pre, post Snapshot) []Snapshot {
+	pre.SnapshotType, post.SnapshotType = "pre", "post"
+	pre.PostNumber = ptrInt(post.Number)
+	post.PreNumber = ptrInt(pre.Number)
+	return []Snapshot{pre, post}
+}
+
+func ptrInt(v int) *int {
+	return &v
+}
+
+// numbers lists the numbers of snapshots
+func numbers(snaps []Snapshot) string {
+	var parts []string
+	for _, snap := range snaps {
+		parts = append(parts, strconv.Itoa(snap.Number))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestSimulateCleanup(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name      string
+		algorithm string
+		values    map[string]string
+		snaps     []Snapshot
+		empty     map[int]bool
+		doomed    string
+		kept      int
+		ranged    bool
+	}{
+		{
+			name:      "number keeps the newest and the important ones",
+			algorithm: "number",
+			values:    map[string]string{"NUMBER_LIMIT": "1", "NUMBER_LIMIT_IMPORTANT": "1", "NUMBER_MIN_AGE": "1800"},
+			snaps: []Snapshot{
+				cleanupSnap(1, "number", 5*day),
+				important(cleanupSnap(2, "number", 4*day)),
+				cleanupSnap(3, "number", 3*day),
+				cleanupSnap(4, "number", 10*time.Minute), // too young
+				important(cleanupSnap(5, "number", 2*day)),
+				cleanupSnap(6, "number", time.Minute),
+				cleanupSnap(7, "timeline", 9*day), // other algorithm
+				{Config: "root", Number: 0, Cleanup: "number"},
+				{Config: "home", Number: 8, Cleanup: "number", Date: "2020-01-01 00:00:00"},
+			},
+			doomed: "1 2 3",
+			kept:   3,
+		},
+		{
+			name:      "number never deletes the default or active snapshot",
+			algorithm: "number",
+			values:    map[string]string{"NUMBER_LIMIT": "0", "NUMBER_MIN_AGE": "0"},
+			snaps: []Snapshot{
+				func() Snapshot { s := cleanupSnap(1, "number", day); s.Default = true; return s }(),
+				func() Snapshot { s := cleanupSnap(2, "number", day); s.Active = true; return s }(),
+				cleanupSnap(3, "number", day),
+			},
+			doomed: "3",
+			kept:   0,
+		},
+		{
+			name:      "number ranges use the upper limit",
+			algorithm: "number",
+			values:    map[string]string{"NUMBER_LIMIT": "1-2", "NUMBER_MIN_AGE": "0"},
+			snaps:     []Snapshot{cleanupSnap(1, "number", 3*day), cleanupSnap(2, "number", 2*day), cleanupSnap(3, "number", day)},
+			doomed:    "1",
+			kept:      2,
+			ranged:    true,
+		},
+		{
+			name:      "number keeps a pre whose post survives",
+			algorithm: "number",
+			values:    map[string]string{"NUMBER_LIMIT": "0", "NUMBER_MIN_AGE": "1800"},
+			snaps: append(append(
+				pair(cleanupSnap(1, "number", 2*day), cleanupSnap(2, "number", 2*day)),
+				pair(cleanupSnap(3, "number", day), cleanupSnap(4, "number", time.Minute))...),
+				// Only the pre half is a candidate: the post does not protect it
+				pair(cleanupSnap(5, "number", day), cleanupSnap(6, "", day))...),
+			doomed: "1 2 5",
+			kept:   2,
+		},
+		{
+			name:      "timeline keeps the first of each period",
+			algorithm: "timeline",
+			values:    map[string]string{"TIMELINE_LIMIT_HOURLY": "2", "TIMELINE_LIMIT_DAILY": "1", "TIMELINE_MIN_AGE": "0"},
+			snaps: []Snapshot{
+				at(1, "2025-06-10 10:00:00"),
+				at(2, "2025-06-10 10:30:00"),
+				at(3, "2025-06-10 11:00:00"),
+				at(4, "2025-06-11 09:00:00"),
+				at(5, "2025-06-11 09:30:00"),
+			},
+			doomed: "1 2 5",
+			kept:   2,
+		},
+		{
+			name:      "timeline keeps a yearly snapshot past the other limits",
+			algorithm: "timeline",
+			values:    map[string]string{"TIMELINE_LIMIT_DAILY": "1", "TIMELINE_LIMIT_YEARLY": "1-2", "TIMELINE_MIN_AGE": "0"},
+			snaps: []Snapshot{
+				at(1, "2023-03-01 08:00:00"),
+				at(2, "2024-03-01 08:00:00"),
+				at(3, "2024-03-02 08:00:00"),
+				at(4, "2025-03-01 08:00:00"),
+			},
+			doomed: "1 3",
+			kept:   2,
+			ranged: true,
+		},
+		{
+			name:      "timeline spares young snapshots",
+			algorithm: "timeline",
+			values:    map[string]string{"TIMELINE_MIN_AGE": "1800"},
+			snaps:     []Snapshot{cleanupSnap(1, "timeline", day), cleanupSnap(2, "timeline", time.Minute)},
+			doomed:    "1",
+			kept:      1,
+		},
+		{
+			name:      "empty-pre-post deletes empty pairs whose halves are both old",
+			algorithm: "empty-pre-post",
+			values:    map[string]string{"EMPTY_PRE_POST_MIN_AGE": "1800"},
+			snaps: append(append(append(
+				pair(cleanupSnap(1, "number", day), cleanupSnap(2, "number", day)),
+				pair(cleanupSnap(3, "", day), cleanupSnap(4, "", day))...),
+				// Empty, but the post is too young, so the pre stays too
+				pair(cleanupSnap(5, "", day), cleanupSnap(6, "", time.Minute))...),
+				cleanupSnap(7, "", day)),
+			empty:  map[int]bool{1: true, 3: false, 5: true},
+			doomed: "1 2",
+			kept:   5,
+		},
+	}
+	for _, tt := range tests {
+		cfg := ConfigInfo{Name: "root", Values: tt.values}
+		plan := simulateCleanup(tt.snaps, cfg, tt.algorithm, tt.empty, cleanupNow)
+		if got := numbers(plan.Doomed); got != tt.doomed || plan.Kept != tt.kept {
+			t.Errorf("%s: doomed %q, kept %d; want %q, %d", tt.name, got, plan.Kept, tt.doomed, tt.kept)
+		}
+		for _, snap := range plan.Doomed {
+			if !plan.Dooms(snap.Key()) {
+				t.Errorf("%s: #%d listed but not marked", tt.name, snap.Number)
+			}
+		}
+		if ranged := len(plan.Notes) > 0; ranged != tt.ranged {
+			t.Errorf("%s: notes %v", tt.name, plan.Notes)
+		}
+	}
+}
+
+func TestNumberRanges(t *testing.T) {
+	snaps := func(numbers ...int) []Snapshot {
+		var list []Snapshot
+		for _, n := range numbers {
+			list = append(list, Snapshot{Number: n})
+		}
+		return list
+	}
+	tests := []struct {
+		snaps []Snapshot
+		limit int
+		want  string
+	}{
+		{nil, 12, ""},
+		{snaps(4), 12, "4"},
+		{snaps(1, 2, 3, 5, 7, 8, 9), 12, "1-3 5 7-9"},
+		{snaps(1, 2, 3, 5, 7, 8, 9), 3, "1-3 5 7-9"},
+		{snaps(1, 2, 3, 5, 7, 8, 9), 2, "1-3 5 …"},
+		{snaps(2, 4, 6, 8), 1, "2 …"},
+	}
+	for _, tt := range tests {
+		if got := numberRanges(tt.snaps, tt.limit); got != tt.want {
+			t.Errorf("numberRanges(%s, %d) = %q, want %q", numbers(tt.snaps), tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestCleanupPlanSummary(t *testing.T) {
+	plan := CleanupPlan{Config: "root", Algorithm: "number", Doomed: []Snapshot{
+		{Number: 3, Exclusive: ptrInt64(1 << 20)},
+		{Number: 4, Exclusive: ptrInt64(1 << 20)},
+		{Number: 7},
+	}}
+	if got, want := plan.summary(), "number cleanup of root would delete 3 snapshot(s) using 2.0 MiB (+1 of unknown size): 3-4 7"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	empty := CleanupPlan{Config: "home", Algorithm: "timeline", Kept: 4}
+	if got, want := empty.summary(), "timeline cleanup of home would delete nothing (4 candidate(s) kept)"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// SnapperClient abstracts the snapper backend so the UI never shells out directly
+type SnapperClient interface {
+	// List returns the snapshots of every config
+	List() ([]Snapshot, error)
+	// ListConfig returns the snapshots of a single config
+	ListConfig(config string) ([]Snapshot, error)
+	// Get returns one snapshot of a config
+	Get(config string, number int) (Snapshot, error)
+	// Delete removes the given snapshot numbers from one config
+	Delete(config string, numbers []int) (string, error)
+	// Rollback rolls the config back to the given snapshot
+	Rollback(config string, number int) (string, error)
+	// Status returns the raw "snapper status from..to" output
+	Status(config string, from, to int) (string, error)
+	// Diff returns the unified "snapper diff from..to path" output for one file
+	Diff(config string, from, to int, path string) (string, error)
+	// UndoChange reverts the given files to their state in snapshot from
+	UndoChange(config string, from, to int, paths []string) (string, error)
+	// Create makes a new snapshot and returns its number
+	Create(opts CreateOptions) (int, error)
+	// Modify replaces the description, cleanup algorithm and userdata of a snapshot
+	Modify(config string, number int, opts ModifyOptions) (string, error)
+	// ListConfigs returns every config with its subvolume
+	ListConfigs() ([]ConfigInfo, error)
+	// GetConfig returns a config with all of its settings
+	GetConfig(config string) (ConfigInfo, error)
+	// SetConfig changes settings of a config
+	SetConfig(config string, values map[string]string) (string, error)
+	// CreateConfig sets up a new config for a subvolume
+	CreateConfig(opts ConfigOptions) (string, error)
+	// DeleteConfig removes a config together with its snapshots
+	DeleteConfig(config string) (string, error)
+	// Cleanup runs a cleanup algorithm ("number", "timeline", "empty-pre-post")
+	Cleanup(config, algorithm string) (string, error)
+	// SetupQuota enables btrfs quota groups for a config
+	SetupQuota(config string) (string, error)
+	// Describe returns what the backend runs for an operation, without running it
+	Describe(op Operation) Invocation
+}
+
+// SnapshotWatcher is implemented by backends that can push snapshot changes
+// as they happen instead of waiting for the next refresh
+type SnapshotWatcher interface {
+	Watch() (<-chan SnapperEvent, error)
+}
+
+// QgroupReader is implemented by backends that can read the btrfs quota
+// group sizes of snapshots on the local filesystem
+type QgroupReader interface {
+	// QgroupSizes fills in the referenced and exclusive sizes of snapshots and
+	// returns the configs on btrfs whose quotas are off
+	QgroupSizes(snaps []Snapshot) ([]Snapshot, []string)
+	// ConfigExclusive returns the exclusive bytes of the QGROUP of each
+	// config with snapshots, keyed by config
+	ConfigExclusive(snaps []Snapshot) map[string]int64
+}
+
+// readQgroupSizes fills in qgroup sizes when the backend can read them and
+// leaves the snapshots alone otherwise
+func readQgroupSizes(client SnapperClient, snaps []Snapshot) ([]Snapshot, []string) {
+	if reader, ok := client.(QgroupReader); ok {
+		return reader.QgroupSizes(snaps)
+	}
+	return snaps, nil
+}
+
+// readConfigExclusive reads the configs' qgroup sizes when the backend can,
+// and returns none otherwise
+func readConfigExclusive(client SnapperClient, snaps []Snapshot) map[string]int64 {
+	if reader, ok := client.(QgroupReader); ok {
+		return reader.ConfigExclusive(snaps)
+	}
+	return nil
+}
+
+// ExecClient implements SnapperClient by running the snapper binary
+type ExecClient struct {
+	Binary string
+}
+
+// newExecClient creates a client that runs snapper from PATH
+func newExecClient() *ExecClient {
+	return &ExecClient{Binary: "snapper"}
+}
+
+// List executes snapper list command and returns parsed snapshots
+func (c *ExecClient) List() ([]Snapshot, error) {
+	return listSnapshots(c.Binary, "")
+}
+
+// ListConfig runs "snapper -c <config> list"
+func (c *ExecClient) ListConfig(config string) ([]Snapshot, error) {
+	return listSnapshots(c.Binary, config)
+}
+
+// Get lists the config and picks out one snapshot; the CLI has no single-snapshot query
+func (c *ExecClient) Get(config string, number int) (Snapshot, error) {
+	snaps, err := listSnapshots(c.Binary, config)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return findSnapshot(snaps, config, number)
+}
+
+// Watch subscribes to snapperd signals on the system bus; the CLI talks to
+// the same daemon, so its snapshots show up there too
+func (c *ExecClient) Watch() (<-chan SnapperEvent, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to D-Bus: %w", err)
+	}
+	return subscribeSnapperSignals(conn)
+}
+
+// QgroupSizes reads the snapshots' qgroups through btrfs ioctls and sysfs
+func (c *ExecClient) QgroupSizes(snaps []Snapshot) ([]Snapshot, []string) {
+	return withQgroupSizes(snaps)
+}
+
+// ConfigExclusive reads each config's QGROUP from its settings and sysfs
+func (c *ExecClient) ConfigExclusive(snaps []Snapshot) map[string]int64 {
+	return configExclusive(c, snaps)
+}
+
+// Delete runs "snapper -c <config> delete <numbers...>"
+func (c *ExecClient) Delete(config string, numbers []int) (string, error) {
+	return c.run(deleteArgs(config, numbers)...)
+}
+
+// Rollback runs "snapper -c <config> rollback <number>"
+func (c *ExecClient) Rollback(config string, number int) (string, error) {
+	return c.run(rollbackArgs(config, number)...)
+}
+
+// Status runs "snapper -c <config> status <from>..<to>"
+func (c *ExecClient) Status(config string, from, to int) (string, error) {
+	return c.run(statusArgs(config, from, to)...)
+}
+
+// Diff runs "snapper -c <config> diff <from>..<to> <path>"
+func (c *ExecClient) Diff(config string, from, to int, path string) (string, error) {
+	return c.run(diffArgs(config, from, to, path)...)
+}
+
+// UndoChange runs "snapper --verbose -c <config> undochange <from>..<to> <paths...>"
+func (c *ExecClient) UndoChange(config string, from, to int, paths []string) (string, error) {
+	return c.run(undoChangeArgs(config, from, to, paths)...)
+}
+
+// Create runs "snapper -c <config> create --print-number ..." and parses the new number
+func (c *ExecClient) Create(opts CreateOptions) (int, error) {
+	output, err := c.run(createArgs(opts)...)
+	if err != nil {
+		// Keep the exit status reachable for the audit log
+		if output == "" {
+			return 0, fmt.Errorf("snapper create failed: %w", err)
+		}
+		return 0, fmt.Errorf("snapper create failed: %s (%w)", output, err)
+	}
+	number, err := strconv.Atoi(output)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected snapper create output %q", output)
+	}
+	return number, nil
+}
+
+// Modify runs "snapper -c <config> modify ..."; the CLI merges userdata, so keys
+// of opts.Current missing from opts.Userdata are cleared explicitly with "key="
+func (c *ExecClient) Modify(config string, number int, opts ModifyOptions) (string, error) {
+	return c.run(modifyArgs(config, number, opts)...)
+}
+
+// ListConfigs runs "snapper --jsonout list-configs"
+func (c *ExecClient) ListConfigs() ([]ConfigInfo, error) {
+	return listConfigs(c.Binary)
+}
+
+// GetConfig runs "snapper --jsonout -c <config> get-config"
+func (c *ExecClient) GetConfig(config string) (ConfigInfo, error) {
+	return getConfig(c.Binary, config)
+}
+
+// SetConfig runs "snapper -c <config> set-config KEY=VALUE..."
+func (c *ExecClient) SetConfig(config string, values map[string]string) (string, error) {
+	return c.run(setConfigArgs(config, values)...)
+}
+
+// CreateConfig runs "snapper -c <name> create-config --fstype ... --template ... <subvolume>"
+func (c *ExecClient) CreateConfig(opts ConfigOptions) (string, error) {
+	return c.run(createConfigArgs(opts)...)
+}
+
+// DeleteConfig runs "snapper -c <config> delete-config"
+func (c *ExecClient) DeleteConfig(config string) (string, error) {
+	return c.run(deleteConfigArgs(config)...)
+}
+
+// Cleanup runs "snapper -c <config> cleanup <algorithm>"
+func (c *ExecClient) Cleanup(config, algorithm string) (string, error) {
+	return c.run(cleanupArgs(config, algorithm)...)
+}
+
+// SetupQuota runs "snapper -c <config> setup-quota"
+func (c *ExecClient) SetupQuota(config string) (string, error) {
+	return c.run(setupQuotaArgs(config)...)
+}
+
+// Describe returns the snapper command line of an operation
+func (c *ExecClient) Describe(op Operation) Invocation {
+	return Invocation{Argv: append([]string{c.Binary}, operationArgs(op)...)}
+}
+
+// run executes snapper with the given arguments and returns its combined output
+func (c *ExecClient) run(args ...string) (string, error) {
+	cmd := exec.Command(c.Binary, args...)
+	cmd.Env = os.Environ()
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The snapper arguments of every action. ExecClient runs them and describes
+// them through Describe, which the recorder logs and the action panel
+// previews, so all three always agree. Backends that do not shell out
+// describe their own calls instead.
+
+// Operation is one snapper action as handed to SnapperClient.Describe
+type Operation struct {
+	Kind      string            // snapper subcommand: "delete", "rollback", "status", "undochange", "create", "modify", "set-config", "create-config", "delete-config", "cleanup" or "setup-quota"
+	Config    string            // config acted on; the new config's name for create-config
+	Numbers   []int             // snapshots acted on; from and to for status and undochange
+	Paths     []string          // files of an undochange
+	Create    CreateOptions     // snapshot of a create
+	Modify    ModifyOptions     // metadata of a modify
+	Values    map[string]string // settings of a set-config
+	NewConfig ConfigOptions     // config of a create-config
+	Algorithm string            // algorithm of a cleanup
+}
+
+// Invocation is what a backend runs for an operation: a command line, or a
+// snapperd D-Bus method and its arguments
+type Invocation struct {
+	Argv   []string      `json:"argv,omitempty"`
+	Method string        `json:"dbus_method,omitempty"`
+	Args   []interface{} `json:"dbus_args,omitempty"`
+}
+
+// String renders the invocation as a shell command line or as a D-Bus call
+func (inv Invocation) String() string {
+	if inv.Method == "" {
+		return commandLine(inv.Argv)
+	}
+	args := make([]string, 0, len(inv.Args))
+	for _, arg := range inv.Args {
+		args = append(args, formatDBusArg(reflect.ValueOf(arg)))
+	}
+	return fmt.Sprintf("snapperd %s(%s)", inv.Method, strings.Join(args, ", "))
+}
+
+// formatDBusArg renders a D-Bus argument; maps are sorted by key so the same
+// call always reads the same
+func formatDBusArg(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		return formatDBusArg(v.Elem())
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Slice, reflect.Array:
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, formatDBusArg(v.Index(i)))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		items := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			items = append(items, formatDBusArg(iter.Key())+": "+formatDBusArg(iter.Value()))
+		}
+		sort.Strings(items)
+		return "{" + strings.Join(items, ", ") + "}"
+	case reflect.Invalid:
+		return "nil"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// operationArgs builds the snapper arguments of an operation
+func operationArgs(op Operation) []string {
+	switch op.Kind {
+	case "delete":
+		return deleteArgs(op.Config, op.Numbers)
+	case "rollback":
+		return rollbackArgs(op.Config, op.Numbers[0])
+	case "status":
+		return statusArgs(op.Config, op.Numbers[0], op.Numbers[1])
+	case "undochange":
+		return undoChangeArgs(op.Config, op.Numbers[0], op.Numbers[1], op.Paths)
+	case "create":
+		return createArgs(op.Create)
+	case "modify":
+		return modifyArgs(op.Config, op.Numbers[0], op.Modify)
+	case "set-config":
+		return setConfigArgs(op.Config, op.Values)
+	case "create-config":
+		return createConfigArgs(op.NewConfig)
+	case "delete-config":
+		return deleteConfigArgs(op.Config)
+	case "cleanup":
+		return cleanupArgs(op.Config, op.Algorithm)
+	case "setup-quota":
+		return setupQuotaArgs(op.Config)
+	}
+	return []string{"-c", op.Config, op.Kind}
+}
+
+// deleteArgs builds "snapper -c <config> delete <numbers...>"
+func deleteArgs(config string, numbers []int) []string {
+	args := []string{"-c", config, "delete"}
+	for _, n := range numbers {
+		args = append(args, fmt.Sprint(n))
+	}
+	return args
+}
+
+// rollbackArgs builds "snapper -c <config> rollback <number>"
+func rollbackArgs(config string, number int) []string {
+	return []string{"-c", config, "rollback", fmt.Sprint(number)}
+}
+
+// statusArgs builds "snapper -c <config> status <from>..<to>"
+func statusArgs(config string, from, to int) []string {
+	return []string{"-c", config, "status", fmt.Sprintf("%d..%d", from, to)}
+}
+
+// diffArgs builds "snapper -c <config> diff <from>..<to> <path>"
+func diffArgs(config string, from, to int, path string) []string {
+	return []string{"-c", config, "diff", fmt.Sprintf("%d..%d", from, to), path}
+}
+
+// undoChangeArgs builds "snapper --verbose -c <config> undochange <from>..<to>
+// <paths...>"; verbose makes snapper name every file it undoes
+func undoChangeArgs(config string, from, to int, paths []string) []string {
+	return append([]string{"--verbose", "-c", config, "undochange", fmt.Sprintf("%d..%d", from, to)}, paths...)
+}
+
+// createArgs builds "snapper -c <config> create --print-number ..."
+func createArgs(opts CreateOptions) []string {
+	args := []string{"-c", opts.Config, "create", "--print-number", "--type", opts.Type}
+	if opts.Type == "post" {
+		args = append(args, "--pre-number", fmt.Sprint(opts.PreNumber))
+	}
+	if opts.Description != "" {
+		args = append(args, "--description", opts.Description)
+	}
+	if opts.Cleanup != "" {
+		args = append(args, "--cleanup-algorithm", opts.Cleanup)
+	}
+	if len(opts.Userdata) > 0 {
+		args = append(args, "--userdata", formatUserdata(opts.Userdata))
+	}
+	return args
+}
+
+// modifyArgs builds "snapper -c <config> modify ..."; the CLI merges
+// userdata, so keys of opts.Current missing from opts.Userdata are cleared
+// explicitly with "key="
+func modifyArgs(config string, number int, opts ModifyOptions) []string {
+	userdata := map[string]string{}
+	for key := range opts.Current {
+		userdata[key] = ""
+	}
+	for key, val := range opts.Userdata {
+		userdata[key] = val
+	}
+
+	args := []string{"-c", config, "modify", "--description", opts.Description, "--cleanup-algorithm", opts.Cleanup}
+	if len(userdata) > 0 {
+		args = append(args, "--userdata", formatUserdata(userdata))
+	}
+	return append(args, fmt.Sprint(number))
+}
+
+// setConfigArgs builds "snapper -c <config> set-config KEY=VALUE..."
+func setConfigArgs(config string, values map[string]string) []string {
+	return append([]string{"-c", config, "set-config"}, formatConfigValues(values)...)
+}
+
+// createConfigArgs builds "snapper -c <name> create-config --fstype ... --template ... <subvolume>"
+func createConfigArgs(opts ConfigOptions) []string {
+	args := []string{"-c", opts.Name, "create-config", "--fstype", opts.FSType}
+	if opts.Template != "" {
+		args = append(args, "--template", opts.Template)
+	}
+	return append(args, opts.Subvolume)
+}
+
+// deleteConfigArgs builds "snapper -c <config> delete-config"
+func deleteConfigArgs(config string) []string {
+	return []string{"-c", config, "delete-config"}
+}
+
+// cleanupArgs builds "snapper -c <config> cleanup <algorithm>"
+func cleanupArgs(config, algorithm string) []string {
+	return []string{"-c", config, "cleanup", algorithm}
+}
+
+// setupQuotaArgs builds "snapper -c <config> setup-quota"
+func setupQuotaArgs(config string) []string {
+	return []string{"-c", config, "setup-quota"}
+}
+
+// commandLine renders an argv the way it would be typed in a shell
+func commandLine(argv []string) string {
+	quoted := make([]string, 0, len(argv))
+	for _, arg := range argv {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`!*?;&|<>()[]{}#~") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+const (
+	btrfsSuperMagic = 0x9123683e // statfs f_type of btrfs
+	btrfsSubvolIno  = 256        // inode number of every btrfs subvolume root
+)
+
+// configFSTypes are the filesystem types "snapper create-config" accepts
+var configFSTypes = []string{"btrfs", "lvm(xfs)", "lvm(ext4)", "bcachefs"}
+
+// configTemplateDirs are searched for config templates, local ones first
+var configTemplateDirs = []string{"/etc/snapper/config-templates", "/usr/share/snapper/config-templates"}
+
+// configNamePattern is what snapper allows as a config name
+var configNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.+-]*$`)
+
+// configWizardInput holds the values bound to the create and delete config
+// dialogs
+type configWizardInput struct {
+	Name      string
+	Subvolume string
+	FSType    string
+	Template  string
+	Confirmed bool
+}
+
+// options converts the wizard input into snapper's create-config options
+func (in *configWizardInput) options() ConfigOptions {
+	return ConfigOptions{
+		Name:      strings.TrimSpace(in.Name),
+		Subvolume: filepath.Clean(strings.TrimSpace(in.Subvolume)),
+		FSType:    in.FSType,
+		Template:  in.Template,
+	}
+}
+
+// ConfigChangedMsg carries the outcome of "snapper create-config" or
+// "snapper delete-config"
+type ConfigChangedMsg struct {
+	Action string // "create-config" or "delete-config"
+	Config string
+	Output string
+	Err    error
+}
+
+// configTemplates lists the installed config templates; "default" is always
+// offered since snapper falls back to it
+func configTemplates() []string {
+	seen := map[string]bool{"default": true}
+	names := []string{"default"}
+	for _, dir := range configTemplateDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// validateConfigName checks a new config name against snapper's rules and the
+// existing configs
+func validateConfigName(name string, existing []ConfigInfo) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("config name is required")
+	}
+	if !configNamePattern.MatchString(name) {
+		return fmt.Errorf("use letters, digits and _ . + - only")
+	}
+	for _, cfg := range existing {
+		if cfg.Name == name {
+			return fmt.Errorf("config %s already exists", name)
+		}
+	}
+	return nil
+}
+
+// checkSubvolume makes sure a path can get a config: it must be an absolute
+// directory not used by another config, and the root of a subvolume on btrfs
+func checkSubvolume(path, fstype string, existing []ConfigInfo) error {
+	path = strings.TrimSpace(path)
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("enter an absolute path")
+	}
+	path = filepath.Clean(path)
+	for _, cfg := range existing {
+		if cfg.Subvolume == path {
+			return fmt.Errorf("%s already has config %s", path, cfg.Name)
+		}
+	}
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if stat.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	if fstype != "btrfs" {
+		return nil
+	}
+	var statfs syscall.Statfs_t
+	if err := syscall.Statfs(path, &statfs); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if uint32(statfs.Type) != btrfsSuperMagic {
+		return fmt.Errorf("%s is not on a btrfs filesystem", path)
+	}
+	if stat.Ino != btrfsSubvolIno {
+		return fmt.Errorf("%s is a plain directory, not a btrfs subvolume", path)
+	}
+	return nil
+}
+
+// createConfigCmd runs "snapper create-config"
+func createConfigCmd(client SnapperClient, opts ConfigOptions) tea.Cmd {
+	return func() tea.Msg {
+		output, err := client.CreateConfig(opts)
+		return ConfigChangedMsg{Action: "create-config", Config: opts.Name, Output: output, Err: err}
+	}
+}
+
+// deleteConfigCmd runs "snapper delete-config"
+func deleteConfigCmd(client SnapperClient, config string) tea.Cmd {
+	return func() tea.Msg {
+		output, err := client.DeleteConfig(config)
+		return ConfigChangedMsg{Action: "delete-config", Config: config, Output: output, Err: err}
+	}
+}
+
+// openCreateConfigForm walks through name, subvolume, filesystem type and
+// template, then shows the command before running it
+func (m *UIState) openCreateConfigForm() tea.Cmd {
+	existing := m.ConfigsView.Configs
+	client := m.Client
+	input := &configWizardInput{FSType: "btrfs", Template: "default"}
+	m.WizardInput = input
+	m.FormKind = "createconfig"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Config name").
+				Placeholder("home").
+				Validate(func(name string) error {
+					return validateConfigName(name, existing)
+				}).
+				Value(&input.Name),
+			huh.NewSelect[string]().
+				Title("Filesystem type").
+				Options(huh.NewOptions(configFSTypes...)...).
+				Value(&input.FSType),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Subvolume path").
+				DescriptionFunc(func() string {
+					if input.FSType == "btrfs" {
+						return "Mount point of the btrfs subvolume to snapshot"
+					}
+					return "Mount point of the filesystem to snapshot"
+				}, &input.FSType).
+				Placeholder("/home").
+				Validate(func(path string) error {
+					return checkSubvolume(path, input.FSType, existing)
+				}).
+				Value(&input.Subvolume),
+			huh.NewSelect[string]().
+				Title("Template").
+				Description("Initial settings, from "+configTemplateDirs[0]).
+				Options(huh.NewOptions(configTemplates()...)...).
+				Value(&input.Template),
+		),
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("Create the config?").
+				DescriptionFunc(func() string {
+					opts := input.options()
+					return "Runs: " + client.Describe(Operation{Kind: "create-config", Config: opts.Name, NewConfig: opts}).String()
+				}, input).
+				Affirmative("Create").
+				Negative("Cancel").
+				Value(&input.Confirmed),
+		),
+	).WithShowHelp(true).WithWidth(70)
+
+	m.Status = "Create config: enter to continue, esc to cancel"
+	return m.Form.Init()
+}
+
+// openDeleteConfigForm asks before deleting the config under the cursor and
+// all of its snapshots
+func (m *UIState) openDeleteConfigForm() tea.Cmd {
+	cfg := m.ConfigsView.current()
+	if cfg == nil {
+		return nil
+	}
+	count := 0
+	for _, snap := range m.Snapshots {
+		if snap.Config == cfg.Name && snap.Number != 0 {
+			count++
+		}
+	}
+	input := &configWizardInput{Name: cfg.Name, Subvolume: cfg.Subvolume}
+	m.WizardInput = input
+	m.FormKind = "deleteconfig"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("Delete config %s of %s?", cfg.Name, cfg.Subvolume)).
+				Description(fmt.Sprintf("Runs: %s\nIts %d snapshot(s) are deleted too.",
+					m.Client.Describe(Operation{Kind: "delete-config", Config: cfg.Name}), count)).
+				Affirmative("Delete").
+				Negative("Cancel").
+				Value(&input.Confirmed),
+		),
+	).WithShowHelp(true).WithWidth(70)
+
+	m.Status = fmt.Sprintf("Delete config %s: confirm or esc to cancel", cfg.Name)
+	return m.Form.Init()
+}
+
+// handleConfigChanged reloads the configs and snapshots after a config was
+// created or deleted
+func (m UIState) handleConfigChanged(msg ConfigChangedMsg) (tea.Model, tea.Cmd) {
+	m.ActionInProgress = false
+	if msg.Err != nil {
+		m.ActionMessage = fmt.Sprintf("%s failed: %s", msg.Action, nonEmpty(msg.Output, msg.Err.Error()))
+		m.Status = fmt.Sprintf("%s %s failed: %s", msg.Action, msg.Config, firstLine(nonEmpty(msg.Output, msg.Err.Error())))
+		return m, nil
+	}
+	if msg.Action == "create-config" {
+		m.ActionMessage = fmt.Sprintf("Created config %s", msg.Config)
+	} else {
+		m.ActionMessage = fmt.Sprintf("Deleted config %s", msg.Config)
+	}
+	m.Status = m.ActionMessage
+	cmds := []tea.Cmd{refreshSnapshotsCmd(m.Client)}
+	if v := m.ConfigsView; v != nil {
+		v.Loading = true
+		if msg.Action == "create-config" {
+			v.Prefer = msg.Config
+		}
+		cmds = append(cmds, loadConfigsCmd(m.Client))
+	}
+	return m, tea.Batch(cmds...)
+}
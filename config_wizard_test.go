@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestCheckSubvolume(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	writeFile(t, file, "", 0o644)
+	existing := []ConfigInfo{{Name: "root", Subvolume: "/"}, {Name: "data", Subvolume: dir}}
+
+	// sub is a plain directory, never a subvolume root; it is only on btrfs
+	// if the machine's temp dir is
+	notBtrfs := "is not on a btrfs filesystem"
+	var statfs syscall.Statfs_t
+	if err := syscall.Statfs(dir, &statfs); err == nil && uint32(statfs.Type) == btrfsSuperMagic {
+		notBtrfs = "is a plain directory, not a btrfs subvolume"
+	}
+	sub := filepath.Join(dir, "sub")
+	writeFile(t, filepath.Join(sub, "x"), "", 0o644)
+
+	tests := []struct {
+		path   string
+		fstype string
+		err    string // "" when the path is accepted
+	}{
+		{"home", "btrfs", "enter an absolute path"},
+		{"", "btrfs", "enter an absolute path"},
+		{"/", "btrfs", "/ already has config root"},
+		{" " + dir + "/ ", "btrfs", dir + " already has config data"},
+		{sub + "/../", "btrfs", dir + " already has config data"},
+		{filepath.Join(dir, "missing"), "btrfs", "no such file or directory"},
+		{file, "btrfs", file + " is not a directory"},
+		{file, "ext4", file + " is not a directory"},
+		{sub, "btrfs", sub + " " + notBtrfs},
+		{sub, "ext4", ""}, // other filesystems need no subvolume
+	}
+	for _, tt := range tests {
+		err := checkSubvolume(tt.path, tt.fstype, existing)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("checkSubvolume(%q, %s): %v", tt.path, tt.fstype, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("checkSubvolume(%q, %s) = %v, want %q", tt.path, tt.fstype, err, tt.err)
+		}
+	}
+}
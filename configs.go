@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Kinds of config values, deciding how an edit is validated
+const (
+	configReadOnly = "read-only" // fixed when the config is created
+	configBool     = "boolean"   // "yes" or "no"
+	configNumber   = "number"    // non-negative integer
+	configLimit    = "limit"     // number or range "min-max"
+	configFraction = "fraction"  // decimal between 0 and 1
+	configList     = "list"      // space-separated names
+	configText     = "text"
+)
+
+// configKey describes one setting of a snapper config
+type configKey struct {
+	Name string
+	Kind string
+	Help string
+}
+
+// configKeys lists the settings snapper documents, in display order; the
+// ones asked about most come first
+var configKeys = []configKey{
+	{"SUBVOLUME", configReadOnly, "Path of the subvolume the config snapshots"},
+	{"FSTYPE", configReadOnly, "Filesystem type of the subvolume"},
+	{"NUMBER_LIMIT", configLimit, "Snapshots kept by the number cleanup, e.g. 50 or 2-10"},
+	{"NUMBER_LIMIT_IMPORTANT", configLimit, "Important snapshots kept by the number cleanup"},
+	{"TIMELINE_CREATE", configBool, "Create hourly timeline snapshots"},
+	{"TIMELINE_CLEANUP", configBool, "Run the timeline cleanup"},
+	{"TIMELINE_MIN_AGE", configNumber, "Seconds a timeline snapshot is kept at least"},
+	{"TIMELINE_LIMIT_HOURLY", configLimit, "Hourly timeline snapshots kept"},
+	{"TIMELINE_LIMIT_DAILY", configLimit, "Daily timeline snapshots kept"},
+	{"TIMELINE_LIMIT_WEEKLY", configLimit, "Weekly timeline snapshots kept"},
+	{"TIMELINE_LIMIT_MONTHLY", configLimit, "Monthly timeline snapshots kept"},
+	{"TIMELINE_LIMIT_QUARTERLY", configLimit, "Quarterly timeline snapshots kept"},
+	{"TIMELINE_LIMIT_YEARLY", configLimit, "Yearly timeline snapshots kept"},
+	{"ALLOW_USERS", configList, "Users allowed to work with the config, separated by spaces"},
+	{"ALLOW_GROUPS", configList, "Groups allowed to work with the config, separated by spaces"},
+	{"SYNC_ACL", configBool, "Sync ALLOW_USERS and ALLOW_GROUPS to the ACL of .snapshots"},
+	{"NUMBER_CLEANUP", configBool, "Run the number cleanup"},
+	{"NUMBER_MIN_AGE", configNumber, "Seconds a numbered snapshot is kept at least"},
+	{"EMPTY_PRE_POST_CLEANUP", configBool, "Delete pre/post pairs without changes"},
+	{"EMPTY_PRE_POST_MIN_AGE", configNumber, "Seconds an empty pre/post pair is kept at least"},
+	{"SPACE_LIMIT", configFraction, "Fraction of the filesystem snapshots may use, e.g. 0.5"},
+	{"FREE_LIMIT", configFraction, "Fraction of the filesystem to keep free, e.g. 0.2"},
+	{"QGROUP", configText, "Btrfs qgroup used for space-aware cleanup, e.g. 1/0"},
+	{"BACKGROUND_COMPARISON", configBool, "Compare pre and post snapshots in the background"},
+}
+
+// lookupConfigKey returns the description of a setting; unknown settings are
+// free text
+func lookupConfigKey(name string) configKey {
+	for _, key := range configKeys {
+		if key.Name == name {
+			return key
+		}
+	}
+	return configKey{Name: name, Kind: configText}
+}
+
+// validateConfigValue checks a value against the kind of its setting
+func validateConfigValue(name, value string) error {
+	key := lookupConfigKey(name)
+	switch key.Kind {
+	case configReadOnly:
+		return fmt.Errorf("%s is set when the config is created", name)
+	case configBool:
+		if value != "yes" && value != "no" {
+			return fmt.Errorf("%s must be yes or no", name)
+		}
+	case configNumber:
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return fmt.Errorf("%s must be a whole number of seconds", name)
+		}
+	case configLimit:
+		low, high, isRange := strings.Cut(value, "-")
+		lo, err := strconv.ParseUint(low, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%s must be a number or a range like 2-10", name)
+		}
+		if isRange {
+			hi, err := strconv.ParseUint(high, 10, 32)
+			if err != nil || hi < lo {
+				return fmt.Errorf("%s range must be min-max with min <= max", name)
+			}
+		}
+	case configFraction:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 || f > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	case configList:
+		if strings.ContainsAny(value, ",;") {
+			return fmt.Errorf("separate %s entries with spaces", name)
+		}
+	}
+	return nil
+}
+
+// configRows orders the settings of a config: documented ones first, the
+// rest by name
+func configRows(cfg ConfigInfo) []string {
+	var rows []string
+	seen := map[string]bool{}
+	for _, key := range configKeys {
+		if _, ok := cfg.Values[key.Name]; ok {
+			rows = append(rows, key.Name)
+			seen[key.Name] = true
+		}
+	}
+	var rest []string
+	for name := range cfg.Values {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(rows, rest...)
+}
+
+// ConfigsLoadedMsg carries every config with its settings
+type ConfigsLoadedMsg struct {
+	Configs []ConfigInfo
+	Err     error
+}
+
+// ConfigSavedMsg carries the outcome of "snapper set-config" and the config
+// as read back afterwards
+type ConfigSavedMsg struct {
+	Config ConfigInfo
+	Values map[string]string
+	Output string
+	Err    error
+}
+
+// loadConfigsCmd lists the configs and reads the settings of each
+func loadConfigsCmd(client SnapperClient) tea.Cmd {
+	return func() tea.Msg {
+		configs, err := client.ListConfigs()
+		if err != nil {
+			return ConfigsLoadedMsg{Err: err}
+		}
+		for i, cfg := range configs {
+			full, err := client.GetConfig(cfg.Name)
+			if err != nil {
+				return ConfigsLoadedMsg{Configs: configs, Err: err}
+			}
+			if full.Subvolume == "" {
+				full.Subvolume = cfg.Subvolume
+			}
+			configs[i] = full
+		}
+		return ConfigsLoadedMsg{Configs: configs}
+	}
+}
+
+// setConfigCmd runs "snapper set-config" and reads the config back
+func setConfigCmd(client SnapperClient, config string, values map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		msg := ConfigSavedMsg{Config: ConfigInfo{Name: config}, Values: values}
+		msg.Output, msg.Err = client.SetConfig(config, values)
+		if msg.Err != nil {
+			return msg
+		}
+		if cfg, err := client.GetConfig(config); err == nil {
+			msg.Config = cfg
+		}
+		return msg
+	}
+}
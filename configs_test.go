@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		err   string // "" when the value is valid
+	}{
+		{"SUBVOLUME", "/home", "SUBVOLUME is set when the config is created"},
+		{"FSTYPE", "btrfs", "FSTYPE is set when the config is created"},
+
+		{"TIMELINE_CREATE", "yes", ""},
+		{"TIMELINE_CREATE", "no", ""},
+		{"TIMELINE_CREATE", "true", "TIMELINE_CREATE must be yes or no"},
+		{"SYNC_ACL", "", "SYNC_ACL must be yes or no"},
+
+		{"NUMBER_MIN_AGE", "1800", ""},
+		{"NUMBER_MIN_AGE", "-1", "must be a whole number of seconds"},
+		{"NUMBER_MIN_AGE", "1.5", "must be a whole number of seconds"},
+
+		{"NUMBER_LIMIT", "50", ""},
+		{"NUMBER_LIMIT", "2-10", ""},
+		{"NUMBER_LIMIT", "4-4", ""},
+		{"NUMBER_LIMIT", "10-2", "NUMBER_LIMIT range must be min-max with min <= max"},
+		{"NUMBER_LIMIT", "2-", "range must be min-max"},
+		{"NUMBER_LIMIT", "-2", "must be a number or a range like 2-10"},
+		{"TIMELINE_LIMIT_DAILY", "ten", "must be a number or a range like 2-10"},
+
+		{"SPACE_LIMIT", "0.5", ""},
+		{"SPACE_LIMIT", "0", ""},
+		{"FREE_LIMIT", "1", ""},
+		{"FREE_LIMIT", "1.2", "FREE_LIMIT must be between 0 and 1"},
+		{"SPACE_LIMIT", "-0.1", "must be between 0 and 1"},
+		{"SPACE_LIMIT", "half", "must be between 0 and 1"},
+
+		{"ALLOW_USERS", "alice bob", ""},
+		{"ALLOW_USERS", "", ""},
+		{"ALLOW_USERS", "alice,bob", "separate ALLOW_USERS entries with spaces"},
+		{"ALLOW_GROUPS", "wheel;users", "separate ALLOW_GROUPS entries with spaces"},
+
+		// Unknown settings are free text
+		{"QGROUP", "1/0", ""},
+		{"MY_OWN_KEY", "anything, at all", ""},
+	}
+	for _, tt := range tests {
+		err := validateConfigValue(tt.name, tt.value)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s=%q: %v", tt.name, tt.value, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s=%q: error %v, want %q", tt.name, tt.value, err, tt.err)
+		}
+	}
+}
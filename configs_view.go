@@ -0,0 +1,375 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var configKeyStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#7dd3fc"))
+
+// configListWidth is the width of the config list beside the settings
+const configListWidth = 30
+
+// ConfigsView lists the snapper configs beside the settings of the one
+// under the cursor
+type ConfigsView struct {
+	Configs  []ConfigInfo
+	Selected int      // config under the cursor
+	Keys     []string // settings of the selected config, in display order
+	Cursor   int      // setting under the cursor
+	Offset   int
+	Focus    string // "configs" or "settings"
+	Loading  bool
+	Err      error
+	Prefer   string // config to select once loaded
+}
+
+// configFormInput holds the setting being edited
+type configFormInput struct {
+	Config string
+	Key    string
+	Value  string
+	Old    string // value before editing
+}
+
+// openConfigs shows the configs screen and loads every config
+func (m UIState) openConfigs() (tea.Model, tea.Cmd) {
+	v := &ConfigsView{Focus: "configs", Loading: true}
+	if snap := m.currentSnapshot(); snap != nil {
+		v.Prefer = snap.Config
+	}
+	m.ConfigsView = v
+	m.Screen = "configs"
+	m.Status = "Loading snapper configs..."
+	return m, loadConfigsCmd(m.Client)
+}
+
+// handleConfigsLoaded shows the loaded configs, keeping the selection
+func (m UIState) handleConfigsLoaded(msg ConfigsLoadedMsg) (tea.Model, tea.Cmd) {
+	v := m.ConfigsView
+	if v == nil {
+		return m, nil
+	}
+	v.Loading = false
+	v.Err = msg.Err
+	if current := v.current(); current != nil && v.Prefer == "" {
+		v.Prefer = current.Name
+	}
+	v.Configs = msg.Configs
+	v.Selected = 0
+	for i, cfg := range v.Configs {
+		if cfg.Name == v.Prefer {
+			v.Selected = i
+		}
+	}
+	v.Prefer = ""
+	v.selectConfig(v.Selected, m.pageHeight())
+	if msg.Err != nil {
+		m.Status = fmt.Sprintf("Loading configs failed: %v", msg.Err)
+	} else {
+		m.Status = fmt.Sprintf("Loaded %d config(s)", len(v.Configs))
+	}
+	return m, nil
+}
+
+// handleConfigSaved shows the config as read back after "snapper set-config"
+func (m UIState) handleConfigSaved(msg ConfigSavedMsg) (tea.Model, tea.Cmd) {
+	m.ActionInProgress = false
+	settings := strings.Join(formatConfigValues(msg.Values), " ")
+	if msg.Err != nil {
+		m.ActionMessage = fmt.Sprintf("set-config failed: %s", nonEmpty(msg.Output, msg.Err.Error()))
+		m.Status = fmt.Sprintf("Setting %s in %s failed: %s", settings, msg.Config.Name, firstLine(nonEmpty(msg.Output, msg.Err.Error())))
+		return m, nil
+	}
+	m.ActionMessage = fmt.Sprintf("Set %s in %s", settings, msg.Config.Name)
+	m.Status = m.ActionMessage
+	v := m.ConfigsView
+	if v == nil || msg.Config.Values == nil {
+		return m, nil
+	}
+	for i := range v.Configs {
+		if v.Configs[i].Name == msg.Config.Name {
+			if msg.Config.Subvolume == "" {
+				msg.Config.Subvolume = v.Configs[i].Subvolume
+			}
+			v.Configs[i] = msg.Config
+		}
+	}
+	key := v.currentKey()
+	v.selectConfig(v.Selected, m.pageHeight())
+	v.focusKey(key, m.pageHeight())
+	return m, nil
+}
+
+// current is the config under the cursor
+func (v *ConfigsView) current() *ConfigInfo {
+	if v.Selected < len(v.Configs) {
+		return &v.Configs[v.Selected]
+	}
+	return nil
+}
+
+// currentKey is the setting under the cursor
+func (v *ConfigsView) currentKey() string {
+	if v.Cursor < len(v.Keys) {
+		return v.Keys[v.Cursor]
+	}
+	return ""
+}
+
+// selectConfig shows the settings of another config
+func (v *ConfigsView) selectConfig(index, page int) {
+	v.Selected = max(0, min(len(v.Configs)-1, index))
+	v.Keys = nil
+	if cfg := v.current(); cfg != nil {
+		v.Keys = configRows(*cfg)
+	}
+	v.moveCursor(0, page)
+}
+
+// focusKey puts the cursor on a setting, if the config has it
+func (v *ConfigsView) focusKey(key string, page int) {
+	for i, name := range v.Keys {
+		if name == key {
+			v.moveCursor(i-v.Cursor, page)
+		}
+	}
+}
+
+// moveCursor moves the highlighted setting and scrolls it into view
+func (v *ConfigsView) moveCursor(delta, page int) {
+	if len(v.Keys) == 0 {
+		v.Cursor, v.Offset = 0, 0
+		return
+	}
+	v.Cursor = max(0, min(len(v.Keys)-1, v.Cursor+delta))
+	if v.Cursor < v.Offset {
+		v.Offset = v.Cursor
+	}
+	if v.Cursor >= v.Offset+page {
+		v.Offset = v.Cursor - page + 1
+	}
+	v.Offset = max(0, min(v.Offset, len(v.Keys)-page))
+}
+
+// openConfigForm edits the setting under the cursor; booleans are picked,
+// everything else is typed and validated
+func (m *UIState) openConfigForm() tea.Cmd {
+	v := m.ConfigsView
+	cfg, name := v.current(), v.currentKey()
+	if cfg == nil || name == "" {
+		return nil
+	}
+	key := lookupConfigKey(name)
+	if key.Kind == configReadOnly {
+		m.Status = fmt.Sprintf("%s is set when the config is created and cannot be changed", name)
+		return nil
+	}
+
+	// huh copies bound values when a field is built, so prefill first
+	input := &configFormInput{Config: cfg.Name, Key: name, Value: cfg.Values[name], Old: cfg.Values[name]}
+	description := strings.TrimSpace(fmt.Sprintf("%s (%s)", key.Help, key.Kind))
+	var field huh.Field
+	if key.Kind == configBool {
+		if input.Value != "no" {
+			input.Value = "yes"
+		}
+		field = huh.NewSelect[string]().
+			Title(name).
+			Description(description).
+			Options(huh.NewOption("yes", "yes"), huh.NewOption("no", "no")).
+			Value(&input.Value)
+	} else {
+		field = huh.NewInput().
+			Title(name).
+			Description(description).
+			Validate(func(value string) error {
+				return validateConfigValue(name, strings.TrimSpace(value))
+			}).
+			Value(&input.Value)
+	}
+
+	m.ConfigInput = input
+	m.FormKind = "setconfig"
+	m.Form = huh.NewForm(huh.NewGroup(field)).WithShowHelp(true).WithWidth(70)
+	m.Status = fmt.Sprintf("Edit %s of %s: enter to save with snapper set-config, esc to cancel", name, cfg.Name)
+	return m.Form.Init()
+}
+
+// handleConfigsKey drives the configs screen
+func (m UIState) handleConfigsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.ConfigsView
+	page := m.pageHeight()
+	if v.Focus == "configs" {
+		switch msg.String() {
+		case "j", "down":
+			v.selectConfig(v.Selected+1, page)
+			return m, nil
+		case "k", "up":
+			v.selectConfig(v.Selected-1, page)
+			return m, nil
+		case "enter", "l", "right", "tab":
+			v.Focus = "settings"
+			return m, nil
+		}
+	} else {
+		switch msg.String() {
+		case "j", "down":
+			v.moveCursor(1, page)
+			return m, nil
+		case "k", "up":
+			v.moveCursor(-1, page)
+			return m, nil
+		case "pgdn", "pagedown":
+			v.moveCursor(page, page)
+			return m, nil
+		case "pgup", "pageup":
+			v.moveCursor(-page, page)
+			return m, nil
+		case "g", "home":
+			v.moveCursor(-len(v.Keys), page)
+			return m, nil
+		case "G", "end":
+			v.moveCursor(len(v.Keys), page)
+			return m, nil
+		case "enter", "e":
+			return m, m.openConfigForm()
+		case "h", "left", "tab":
+			v.Focus = "configs"
+			return m, nil
+		}
+	}
+
+	switch msg.String() {
+	case "esc", "q":
+		m.Screen = ""
+		m.ConfigsView = nil
+		m.Status = "Closed configs"
+	case "a", "n":
+		if !m.ActionInProgress && !v.Loading {
+			return m, m.openCreateConfigForm()
+		}
+	case "D", "x":
+		if !m.ActionInProgress && !v.Loading {
+			return m, m.openDeleteConfigForm()
+		}
+	case "r":
+		v.Loading = true
+		m.Status = "Reloading snapper configs..."
+		return m, loadConfigsCmd(m.Client)
+	}
+	return m, nil
+}
+
+// handleConfigsMouse selects configs and settings on click; clicking the
+// selected setting edits it
+func (m UIState) handleConfigsMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	v := m.ConfigsView
+	page := m.pageHeight()
+	switch msg.Type {
+	case tea.MouseWheelUp:
+		v.moveCursor(-3, page)
+	case tea.MouseWheelDown:
+		v.moveCursor(3, page)
+	case tea.MouseLeft:
+		row := msg.Y - 3
+		if row < 0 || row >= page {
+			return m, nil
+		}
+		if msg.X < configListWidth {
+			if row < len(v.Configs) {
+				v.Focus = "configs"
+				v.selectConfig(row, page)
+			}
+			return m, nil
+		}
+		pos := v.Offset + row
+		if pos >= len(v.Keys) {
+			return m, nil
+		}
+		v.Focus = "settings"
+		if pos == v.Cursor {
+			return m, m.openConfigForm()
+		}
+		v.moveCursor(pos-v.Cursor, page)
+	}
+	return m, nil
+}
+
+// renderConfigsView draws the config list beside the selected config's
+// settings
+func (m UIState) renderConfigsView(width, height int) string {
+	v := m.ConfigsView
+	page := m.pageHeight()
+	sideWidth := max(20, width-configListWidth-3)
+
+	title := detailHeaderStyle.Render("Snapper configs")
+	info := fmt.Sprintf("%d config(s)", len(v.Configs))
+	if cfg := v.current(); cfg != nil {
+		info = fmt.Sprintf("%s | %s on %s", info, cfg.Name, cfg.Subvolume)
+		if name := v.currentKey(); name != "" && v.Focus == "settings" {
+			if help := lookupConfigKey(name).Help; help != "" {
+				info += " | " + help
+			}
+		}
+	}
+	if v.Loading {
+		info = "Loading configs... | " + info
+	}
+
+	var list []string
+	if v.Err != nil {
+		list = append(list, browserWarnStyle.Render(padOrTruncate(v.Err.Error(), configListWidth)))
+	}
+	for i, cfg := range v.Configs {
+		line := padOrTruncate(fmt.Sprintf("%-12s %s", cfg.Name, cfg.Subvolume), configListWidth)
+		if i == v.Selected {
+			style := pagerCursorStyle
+			if v.Focus == "configs" {
+				style = style.Bold(true)
+			}
+			line = style.Render(line)
+		}
+		list = append(list, line)
+	}
+
+	var side []string
+	if cfg := v.current(); cfg != nil {
+		keyWidth := 26
+		for pos := v.Offset; pos < min(v.Offset+page, len(v.Keys)); pos++ {
+			name := v.Keys[pos]
+			kind := lookupConfigKey(name).Kind
+			value := cfg.Values[name]
+			if value == "" {
+				value = "(empty)"
+			}
+			line := configKeyStyle.Render(padOrTruncate(name, keyWidth)) + " " +
+				padOrTruncate(value, max(1, sideWidth-keyWidth-13)) + " " +
+				pagerHelpStyle.Render(padOrTruncate(kind, 11))
+			if pos == v.Cursor && v.Focus == "settings" {
+				line = pagerCursorStyle.Render(padOrTruncate(name, keyWidth) + " " +
+					padOrTruncate(value, max(1, sideWidth-keyWidth-13)) + " " + padOrTruncate(kind, 11))
+			}
+			side = append(side, line)
+		}
+	}
+
+	body := lipgloss.JoinHorizontal(lipgloss.Top,
+		lipgloss.NewStyle().Width(configListWidth).Height(page).Render(strings.Join(list, "\n")),
+		pagerHelpStyle.Render(strings.TrimSuffix(strings.Repeat(" │ \n", page), "\n")),
+		lipgloss.NewStyle().Width(sideWidth).Height(page).MaxHeight(page).Render(strings.Join(side, "\n")),
+	)
+	footer := pagerHelpStyle.Render("↑↓: Move | tab/←/→: Configs or settings | enter/e/click: Edit setting | a: Add config | D: Delete config | r: Reload | esc/q: Back")
+	ui := lipgloss.JoinVertical(lipgloss.Left,
+		headerStyle.Width(width).Render(m.headerTitle()),
+		title,
+		summaryStyle.Render(padOrTruncate(info, width)),
+		body,
+		footer,
+	)
+	return lipgloss.Place(width, height, lipgloss.Top, lipgloss.Left, ui)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// How a destructive action is confirmed
+const (
+	confirmNone  = "none"  // run straight away
+	confirmYes   = "yes"   // yes/no prompt
+	confirmBatch = "batch" // type a snapshot number for more than one target, yes/no otherwise
+	confirmTyped = "typed" // always type a snapshot number
+)
+
+// ConfirmRules maps a destructive action to its confirmation mode
+type ConfirmRules map[ActionKind]string
+
+// defaultConfirmRules asks before every destructive action and makes
+// rollbacks and batches typed
+var defaultConfirmRules = ConfirmRules{
+	ActionDelete:     confirmBatch,
+	ActionRestore:    confirmTyped,
+	ActionUndoChange: confirmBatch,
+}
+
+// parseConfirmRules reads rules like "delete=typed,rollback=yes" on top of
+// the defaults
+func parseConfirmRules(spec string) (ConfirmRules, error) {
+	rules := ConfirmRules{}
+	for kind, mode := range defaultConfirmRules {
+		rules[kind] = mode
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, mode, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("confirm rule %q is not action=mode", part)
+		}
+		var kind ActionKind
+		for candidate := range defaultConfirmRules {
+			if candidate.String() == strings.TrimSpace(name) {
+				kind = candidate
+			}
+		}
+		if kind == ActionUnknown {
+			return nil, fmt.Errorf("unknown action %q in confirm rules; use delete, rollback or undochange", name)
+		}
+		switch mode = strings.TrimSpace(mode); mode {
+		case confirmNone, confirmYes, confirmBatch, confirmTyped:
+			rules[kind] = mode
+		default:
+			return nil, fmt.Errorf("unknown confirm mode %q; use none, yes, batch or typed", mode)
+		}
+	}
+	return rules, nil
+}
+
+// mode returns the confirmation mode of an action
+func (r ConfirmRules) mode(kind ActionKind) string {
+	if mode, ok := r[kind]; ok {
+		return mode
+	}
+	return defaultConfirmRules[kind]
+}
+
+// expectedText returns the snapshot number the user has to type to confirm
+// an action on count targets, or "" when a yes/no answer is enough. Callers
+// pick the number: the snapshot itself, the highest one of a batch, or the
+// snapshot files are undone from.
+func (r ConfirmRules) expectedText(kind ActionKind, number, count int) string {
+	switch mode := r.mode(kind); {
+	case mode == confirmTyped, mode == confirmBatch && count > 1:
+		return strconv.Itoa(number)
+	}
+	return ""
+}
+
+// highestNumber is the snapshot number a batch is confirmed with; it has to
+// be read off the list, unlike a count that can be typed blind
+func highestNumber(snaps []Snapshot) int {
+	highest := snaps[0].Number
+	for _, snap := range snaps[1:] {
+		highest = max(highest, snap.Number)
+	}
+	return highest
+}
+
+// confirmFormInput holds a rollback or delete awaiting confirmation
+type confirmFormInput struct {
+	Kind      ActionKind
+	Snap      Snapshot
+	Targets   []Snapshot
+	Expected  string // text to type; "" for a yes/no prompt
+	Typed     string
+	Confirmed bool
+}
+
+// actionTargets are the snapshots an action applies to: the selection, or
+// the snapshot under the cursor
+func (m UIState) actionTargets() []Snapshot {
+	var targets []Snapshot
+	for _, snap := range m.Visible {
+		if m.SelectedSnapshots[snap.Key()] {
+			targets = append(targets, snap)
+		}
+	}
+	if len(targets) == 0 {
+		if snap := m.currentSnapshot(); snap != nil {
+			targets = []Snapshot{*snap}
+		}
+	}
+	return targets
+}
+
+// describeSnapshots lists snapshots one per line with config, number,
+// description and size
+func describeSnapshots(snaps []Snapshot) string {
+	sorted := append([]Snapshot(nil), snaps...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Config != sorted[j].Config {
+			return sorted[i].Config < sorted[j].Config
+		}
+		return sorted[i].Number < sorted[j].Number
+	})
+	lines := make([]string, 0, len(sorted))
+	for _, snap := range sorted {
+		lines = append(lines, fmt.Sprintf("%s #%d  %s  (%s)",
+			snap.Config, snap.Number, nonEmpty(snap.Description, "<no description>"), humanReadableBytes(snapshotBytes(snap))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// typedConfirmation is the input that only accepts the expected text
+func typedConfirmation(expected, what string, value *string) *huh.Input {
+	return huh.NewInput().
+		Title(fmt.Sprintf("Type %s to confirm", expected)).
+		Description(what).
+		Validate(func(typed string) error {
+			if strings.TrimSpace(typed) != expected {
+				return fmt.Errorf("type %s, or esc to cancel", expected)
+			}
+			return nil
+		}).
+		Value(value)
+}
+
+// confirmAction asks before a rollback or delete as the rules say, or runs
+// it straight away when they say none
+func (m *UIState) confirmAction(kind ActionKind) tea.Cmd {
+	snap := m.currentSnapshot()
+	if m.ActionInProgress || snap == nil {
+		return nil
+	}
+	targets := m.actionTargets()
+	if kind == ActionRestore && len(targets) > 1 {
+		m.ActionMessage = "Please select only one snapshot."
+		m.Status = "Cannot roll back to several snapshots"
+		return nil
+	}
+	if m.ConfirmRules.mode(kind) == confirmNone {
+		return m.runAction(kind, *snap, targets)
+	}
+
+	input := &confirmFormInput{Kind: kind, Snap: *snap, Targets: targets}
+	input.Expected = m.ConfirmRules.expectedText(kind, highestNumber(targets), len(targets))
+	title := fmt.Sprintf("Delete %d snapshot(s)?", len(targets))
+	what := "the highest snapshot number listed above"
+	if kind == ActionRestore {
+		title = fmt.Sprintf("Roll %s back to snapshot %d?", targets[0].Config, targets[0].Number)
+		what = "the snapshot number to roll back to"
+	} else if len(targets) == 1 {
+		what = "the snapshot number to delete"
+	}
+
+	fields := []huh.Field{
+		huh.NewNote().Title(title).Description(noteEscaper.Replace(describeSnapshots(targets))),
+	}
+	if input.Expected != "" {
+		fields = append(fields, typedConfirmation(input.Expected, what, &input.Typed))
+	} else {
+		fields = append(fields, huh.NewConfirm().
+			Title("Continue?").
+			Affirmative(strings.ToUpper(kind.String()[:1])+kind.String()[1:]).
+			Negative("Cancel").
+			Value(&input.Confirmed))
+	}
+
+	m.ConfirmInput = input
+	m.FormKind = "confirm"
+	m.Form = huh.NewForm(huh.NewGroup(fields...)).WithShowHelp(true).WithWidth(80)
+	m.Status = fmt.Sprintf("Confirm %s: esc to cancel", kind)
+	return m.Form.Init()
+}
+
+// confirmed reports whether the dialog was answered with yes or the right text
+func (in *confirmFormInput) confirmed() bool {
+	if in.Expected != "" {
+		return strings.TrimSpace(in.Typed) == in.Expected
+	}
+	return in.Confirmed
+}
+
+// runAction starts a rollback or delete on exactly the given targets, so a
+// confirmed action runs on the snapshots its dialog listed even if the
+// selection or filter changed behind it
+func (m *UIState) runAction(kind ActionKind, snap Snapshot, targets []Snapshot) tea.Cmd {
+	m.ActionInProgress = true
+	m.ActionMessage = "⏳ Executing delete..."
+	if kind == ActionRestore {
+		m.ActionMessage = "⏳ Executing apply..."
+	}
+	selected := make(map[SnapshotKey]bool, len(targets))
+	for _, target := range targets {
+		selected[target.Key()] = true
+	}
+	return executeActionCmd(m.Client, kind, snap, selected, targets)
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestExpectedText(t *testing.T) {
+	tests := []struct {
+		mode   string
+		number int
+		count  int
+		want   string
+	}{
+		{confirmNone, 7, 1, ""},
+		{confirmNone, 7, 3, ""},
+		{confirmYes, 7, 3, ""},
+		{confirmBatch, 7, 1, ""},
+		{confirmBatch, 7, 3, "7"},
+		{confirmTyped, 7, 1, "7"},
+		{confirmTyped, 7, 3, "7"},
+	}
+	for _, tt := range tests {
+		rules := ConfirmRules{ActionDelete: tt.mode}
+		if got := rules.expectedText(ActionDelete, tt.number, tt.count); got != tt.want {
+			t.Errorf("%s with %d target(s): %q, want %q", tt.mode, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestParseConfirmRules(t *testing.T) {
+	rules, err := parseConfirmRules("delete=typed, undochange=none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.mode(ActionDelete) != confirmTyped || rules.mode(ActionUndoChange) != confirmNone || rules.mode(ActionRestore) != confirmTyped {
+		t.Errorf("rules = %v", rules)
+	}
+	for _, spec := range []string{"delete", "remove=yes", "delete=maybe"} {
+		if _, err := parseConfirmRules(spec); err == nil {
+			t.Errorf("%q parsed", spec)
+		}
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"syscall"
 )
 
-// listSnapshots executes snapper list command and returns parsed snapshots
-func listSnapshots() ([]Snapshot, error) {
+// listSnapshots executes snapper list command and returns parsed snapshots;
+// an empty config lists every config
+func listSnapshots(binary, config string) ([]Snapshot, error) {
 	columns := []string{
 		"config",
 		"subvolume",
@@ -28,7 +30,11 @@ func listSnapshots() ([]Snapshot, error) {
 		"active",
 	}
 
-	cmd := exec.Command("snapper", "--jsonout", "list", "--columns", strings.Join(columns, ","))
+	args := []string{"--jsonout", "list", "--columns", strings.Join(columns, ",")}
+	if config != "" {
+		args = append([]string{"-c", config}, args...)
+	}
+	cmd := exec.Command(binary, args...)
 	cmd.Env = os.Environ()
 
 	output, err := cmd.Output()
@@ -54,6 +60,63 @@ func listSnapshots() ([]Snapshot, error) {
 	return snaps, nil
 }
 
+// listConfigs runs "snapper --jsonout list-configs" and returns the configs
+// sorted by name
+func listConfigs(binary string) ([]ConfigInfo, error) {
+	output, err := runSnapperJSON(binary, "list-configs", "--jsonout", "list-configs")
+	if err != nil {
+		return nil, err
+	}
+	var payload map[string][]map[string]interface{}
+	if err := json.Unmarshal(output, &payload); err != nil {
+		return nil, fmt.Errorf("unable to decode snapper JSON: %w", err)
+	}
+
+	var configs []ConfigInfo
+	for _, entry := range payload["configs"] {
+		configs = append(configs, ConfigInfo{
+			Name:      toString(entry["config"], ""),
+			Subvolume: toString(entry["subvolume"], ""),
+		})
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	return configs, nil
+}
+
+// getConfig runs "snapper --jsonout -c <config> get-config"
+func getConfig(binary, config string) (ConfigInfo, error) {
+	output, err := runSnapperJSON(binary, "get-config", "--jsonout", "-c", config, "get-config")
+	if err != nil {
+		return ConfigInfo{}, err
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(output, &payload); err != nil {
+		return ConfigInfo{}, fmt.Errorf("unable to decode snapper JSON: %w", err)
+	}
+
+	info := ConfigInfo{Name: config, Values: map[string]string{}}
+	for key, value := range payload {
+		info.Values[key] = toString(value, "")
+	}
+	info.Subvolume = info.Values["SUBVOLUME"]
+	return info, nil
+}
+
+// runSnapperJSON runs a snapper query and returns its stdout, with stderr as
+// the error message when it fails
+func runSnapperJSON(binary, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(binary, args...)
+	cmd.Env = os.Environ()
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("snapper %s failed: %s", name, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("snapper %s failed: %w", name, err)
+	}
+	return output, nil
+}
+
 // snapshotFromRaw converts a raw JSON map to a Snapshot struct
 func snapshotFromRaw(config string, data map[string]interface{}) Snapshot {
 	number, _ := toInt(data["number"])
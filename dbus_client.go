@@ -0,0 +1,589 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	snapperBusName   = "org.opensuse.Snapper"
+	snapperInterface = "org.opensuse.Snapper"
+	snapperPath      = dbus.ObjectPath("/org/opensuse/Snapper")
+)
+
+// snapper file status bits as reported by GetFiles
+const (
+	fileCreated     = 1 << 0
+	fileDeleted     = 1 << 1
+	fileType        = 1 << 2
+	fileContent     = 1 << 3
+	filePermissions = 1 << 4
+	fileOwner       = 1 << 5
+	fileGroup       = 1 << 6
+	fileXattrs      = 1 << 7
+	fileACL         = 1 << 8
+)
+
+// dbusConfig mirrors the (ssa{ss}) config tuple returned by snapperd
+type dbusConfig struct {
+	Name      string
+	Subvolume string
+	Data      map[string]string
+}
+
+// dbusSnapshot mirrors the (uquxussa{ss}) snapshot tuple returned by snapperd
+type dbusSnapshot struct {
+	Number      uint32
+	Type        uint16
+	PreNumber   uint32
+	Date        int64
+	UID         uint32
+	Description string
+	Cleanup     string
+	Userdata    map[string]string
+}
+
+// dbusFile mirrors the (su) file tuple returned by GetFiles
+type dbusFile struct {
+	Name   string
+	Status uint32
+}
+
+// DBusClient implements SnapperClient against the snapperd D-Bus service
+type DBusClient struct {
+	conn *dbus.Conn
+	obj  dbus.BusObject
+	// fallback runs operations snapperd does not expose (rollback, cleanup)
+	fallback *ExecClient
+}
+
+// newDBusClient connects to snapperd on the system bus, or on the session bus
+// when testing against a stand-in service
+func newDBusClient(sessionBus bool) (*DBusClient, error) {
+	connect := dbus.ConnectSystemBus
+	if sessionBus {
+		connect = dbus.ConnectSessionBus
+	}
+	conn, err := connect()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to D-Bus: %w", err)
+	}
+	return &DBusClient{
+		conn:     conn,
+		obj:      conn.Object(snapperBusName, snapperPath),
+		fallback: newExecClient(),
+	}, nil
+}
+
+// List queries every config and its snapshots from snapperd
+func (c *DBusClient) List() ([]Snapshot, error) {
+	configs, err := c.listConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	// snapperd enforces ALLOW_USERS per config; skip the configs this user
+	// may not read and only fail when none of them is readable
+	var snaps []Snapshot
+	var firstErr error
+	readable := 0
+	for _, cfg := range configs {
+		configSnaps, err := c.listConfigSnapshots(cfg)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("config %s: %w", cfg.Name, err)
+			}
+			continue
+		}
+		readable++
+		snaps = append(snaps, configSnaps...)
+	}
+	if readable == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return snaps, nil
+}
+
+// ListConfig returns the snapshots of a single config
+func (c *DBusClient) ListConfig(config string) ([]Snapshot, error) {
+	cfg, err := c.getConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return c.listConfigSnapshots(cfg)
+}
+
+// Get calls GetSnapshot for one snapshot of a config
+func (c *DBusClient) Get(config string, number int) (Snapshot, error) {
+	cfg, err := c.getConfig(config)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var r dbusSnapshot
+	if err := c.call("GetSnapshot", config, uint32(number)).Store(&r); err != nil {
+		return Snapshot{}, dbusError(err)
+	}
+
+	snap := snapshotFromDBus(cfg, r)
+	snap.UsedSpace = c.usedSpaces(config, []dbusSnapshot{r})[0]
+	if defaultNum, ok := c.optionalNumber("GetDefaultSnapshot", config); ok {
+		snap.Default = snap.Number == defaultNum
+	}
+	if activeNum, ok := c.optionalNumber("GetActiveSnapshot", config); ok {
+		snap.Active = snap.Number == activeNum
+	}
+	// GetSnapshot does not report the post partner of a pre snapshot
+	if snap.SnapshotType == "pre" {
+		if siblings, err := c.listConfigSnapshots(cfg); err == nil {
+			if found, err := findSnapshot(siblings, config, number); err == nil {
+				snap.PostNumber = found.PostNumber
+			}
+		}
+	}
+	return snap, nil
+}
+
+// Watch subscribes to snapperd's change signals on the client's bus
+func (c *DBusClient) Watch() (<-chan SnapperEvent, error) {
+	return subscribeSnapperSignals(c.conn)
+}
+
+// QgroupSizes reads the snapshots' qgroups through btrfs ioctls and sysfs;
+// snapperd does not report them
+func (c *DBusClient) QgroupSizes(snaps []Snapshot) ([]Snapshot, []string) {
+	return withQgroupSizes(snaps)
+}
+
+// ConfigExclusive reads each config's QGROUP from its settings and sysfs
+func (c *DBusClient) ConfigExclusive(snaps []Snapshot) map[string]int64 {
+	return configExclusive(c, snaps)
+}
+
+// Delete calls DeleteSnapshots for the given numbers of one config
+func (c *DBusClient) Delete(config string, numbers []int) (string, error) {
+	return c.invoke(c.Describe(Operation{Kind: "delete", Config: config, Numbers: numbers}))
+}
+
+// Create calls CreateSingleSnapshot, CreatePreSnapshot or CreatePostSnapshot
+func (c *DBusClient) Create(opts CreateOptions) (int, error) {
+	inv := c.Describe(Operation{Kind: "create", Config: opts.Config, Create: opts})
+	if inv.Method == "" {
+		return 0, fmt.Errorf("unknown snapshot type %q", opts.Type)
+	}
+	var number uint32
+	if err := c.call(inv.Method, inv.Args...).Store(&number); err != nil {
+		return 0, dbusError(err)
+	}
+	return int(number), nil
+}
+
+// Modify calls SetSnapshot, which replaces all three fields at once
+func (c *DBusClient) Modify(config string, number int, opts ModifyOptions) (string, error) {
+	return c.invoke(c.Describe(Operation{Kind: "modify", Config: config, Numbers: []int{number}, Modify: opts}))
+}
+
+// Rollback is not part of the snapperd interface, so it goes through the CLI
+func (c *DBusClient) Rollback(config string, number int) (string, error) {
+	return c.fallback.Rollback(config, number)
+}
+
+// Cleanup goes through the CLI; the cleanup rules live in the snapper tool,
+// not in snapperd
+func (c *DBusClient) Cleanup(config, algorithm string) (string, error) {
+	return c.fallback.Cleanup(config, algorithm)
+}
+
+// Diff goes through the CLI; snapperd only compares file metadata
+func (c *DBusClient) Diff(config string, from, to int, path string) (string, error) {
+	return c.fallback.Diff(config, from, to, path)
+}
+
+// UndoChange goes through the CLI, which copies the files out of the snapshot
+func (c *DBusClient) UndoChange(config string, from, to int, paths []string) (string, error) {
+	return c.fallback.UndoChange(config, from, to, paths)
+}
+
+// Status builds a comparison in snapperd and renders it like "snapper status"
+func (c *DBusClient) Status(config string, from, to int) (string, error) {
+	cfg, err := c.getConfig(config)
+	if err != nil {
+		return err.Error(), err
+	}
+
+	var count uint32
+	if err := c.call("CreateComparison", config, uint32(from), uint32(to)).Store(&count); err != nil {
+		err = dbusError(err)
+		return err.Error(), err
+	}
+	defer c.call("DeleteComparison", config, uint32(from), uint32(to))
+
+	var files []dbusFile
+	if err := c.call("GetFiles", config, uint32(from), uint32(to)).Store(&files); err != nil {
+		err = dbusError(err)
+		return err.Error(), err
+	}
+
+	lines := make([]string, 0, len(files))
+	for _, f := range files {
+		change := fileChangeFromStatus(path.Join(cfg.Subvolume, f.Name), f.Status)
+		lines = append(lines, fmt.Sprintf("%s %s", change.Flags(), change.Path))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// ListConfigs returns every config known to snapperd with its settings
+func (c *DBusClient) ListConfigs() ([]ConfigInfo, error) {
+	configs, err := c.listConfigs()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]ConfigInfo, 0, len(configs))
+	for _, cfg := range configs {
+		infos = append(infos, ConfigInfo{Name: cfg.Name, Subvolume: cfg.Subvolume, Values: cfg.Data})
+	}
+	return infos, nil
+}
+
+// GetConfig returns the settings of one config
+func (c *DBusClient) GetConfig(config string) (ConfigInfo, error) {
+	cfg, err := c.getConfig(config)
+	if err != nil {
+		return ConfigInfo{}, err
+	}
+	return ConfigInfo{Name: cfg.Name, Subvolume: cfg.Subvolume, Values: cfg.Data}, nil
+}
+
+// SetConfig changes settings of a config through snapperd
+func (c *DBusClient) SetConfig(config string, values map[string]string) (string, error) {
+	return c.invoke(c.Describe(Operation{Kind: "set-config", Config: config, Values: values}))
+}
+
+// CreateConfig sets up a new config through snapperd
+func (c *DBusClient) CreateConfig(opts ConfigOptions) (string, error) {
+	return c.invoke(c.Describe(Operation{Kind: "create-config", Config: opts.Name, NewConfig: opts}))
+}
+
+// DeleteConfig removes a config and its snapshots through snapperd
+func (c *DBusClient) DeleteConfig(config string) (string, error) {
+	return c.invoke(c.Describe(Operation{Kind: "delete-config", Config: config}))
+}
+
+// SetupQuota enables quota groups for a config through snapperd
+func (c *DBusClient) SetupQuota(config string) (string, error) {
+	return c.invoke(c.Describe(Operation{Kind: "setup-quota", Config: config}))
+}
+
+// Describe returns the snapperd method an operation calls with its
+// arguments, or the snapper command line of the operations that go through
+// the CLI
+func (c *DBusClient) Describe(op Operation) Invocation {
+	switch op.Kind {
+	case "delete":
+		nums := make([]uint32, 0, len(op.Numbers))
+		for _, n := range op.Numbers {
+			nums = append(nums, uint32(n))
+		}
+		return dbusInvocation("DeleteSnapshots", op.Config, nums)
+	case "status":
+		return dbusInvocation("CreateComparison", op.Config, uint32(op.Numbers[0]), uint32(op.Numbers[1]))
+	case "create":
+		opts := op.Create
+		userdata := opts.Userdata
+		if userdata == nil {
+			userdata = map[string]string{}
+		}
+		switch opts.Type {
+		case "single":
+			return dbusInvocation("CreateSingleSnapshot", opts.Config, opts.Description, opts.Cleanup, userdata)
+		case "pre":
+			return dbusInvocation("CreatePreSnapshot", opts.Config, opts.Description, opts.Cleanup, userdata)
+		case "post":
+			return dbusInvocation("CreatePostSnapshot", opts.Config, uint32(opts.PreNumber), opts.Description, opts.Cleanup, userdata)
+		}
+		return Invocation{}
+	case "modify":
+		userdata := op.Modify.Userdata
+		if userdata == nil {
+			userdata = map[string]string{}
+		}
+		return dbusInvocation("SetSnapshot", op.Config, uint32(op.Numbers[0]), op.Modify.Description, op.Modify.Cleanup, userdata)
+	case "set-config":
+		return dbusInvocation("SetConfig", op.Config, op.Values)
+	case "create-config":
+		cfg := op.NewConfig
+		return dbusInvocation("CreateConfig", cfg.Name, cfg.Subvolume, cfg.FSType, cfg.Template)
+	case "delete-config":
+		return dbusInvocation("DeleteConfig", op.Config)
+	case "setup-quota":
+		return dbusInvocation("SetupQuota", op.Config)
+	}
+	return c.fallback.Describe(op)
+}
+
+// dbusInvocation describes a call of a snapperd method
+func dbusInvocation(method string, args ...interface{}) Invocation {
+	return Invocation{Method: method, Args: args}
+}
+
+// invoke runs a described operation: a snapperd method without return
+// values, or a snapper command line
+func (c *DBusClient) invoke(inv Invocation) (string, error) {
+	if inv.Method == "" {
+		return c.fallback.run(inv.Argv[1:]...)
+	}
+	if err := c.call(inv.Method, inv.Args...).Err; err != nil {
+		err = dbusError(err)
+		return err.Error(), err
+	}
+	return "", nil
+}
+
+// getConfig returns the name, subvolume and settings of one config
+func (c *DBusClient) getConfig(config string) (dbusConfig, error) {
+	var cfg dbusConfig
+	if err := c.call("GetConfig", config).Store(&cfg); err != nil {
+		return dbusConfig{}, dbusError(err)
+	}
+	return cfg, nil
+}
+
+// listConfigs returns the configs known to snapperd sorted by name
+func (c *DBusClient) listConfigs() ([]dbusConfig, error) {
+	var configs []dbusConfig
+	if err := c.call("ListConfigs").Store(&configs); err != nil {
+		return nil, dbusError(err)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	return configs, nil
+}
+
+// listConfigSnapshots converts the typed ListSnapshots result of one config
+func (c *DBusClient) listConfigSnapshots(cfg dbusConfig) ([]Snapshot, error) {
+	var raw []dbusSnapshot
+	if err := c.call("ListSnapshots", cfg.Name).Store(&raw); err != nil {
+		return nil, dbusError(err)
+	}
+
+	defaultNum, hasDefault := c.optionalNumber("GetDefaultSnapshot", cfg.Name)
+	activeNum, hasActive := c.optionalNumber("GetActiveSnapshot", cfg.Name)
+
+	sizes := c.usedSpaces(cfg.Name, raw)
+	snaps := make([]Snapshot, 0, len(raw))
+	postOf := map[int]int{}
+	for i, r := range raw {
+		snap := snapshotFromDBus(cfg, r)
+		snap.UsedSpace = sizes[i]
+		snap.Default = hasDefault && snap.Number == defaultNum
+		snap.Active = hasActive && snap.Number == activeNum
+		if snap.SnapshotType == "post" && snap.PreNumber != nil {
+			postOf[*snap.PreNumber] = snap.Number
+		}
+		snaps = append(snaps, snap)
+	}
+	for i := range snaps {
+		if post, ok := postOf[snaps[i].Number]; ok && snaps[i].SnapshotType == "pre" {
+			snaps[i].PostNumber = toOptionalInt(post)
+		}
+	}
+	return snaps, nil
+}
+
+// usedSpaces asks snapperd for the used space of every listed snapshot at
+// once: the calls are all sent before any reply is read, so a config costs
+// one round trip instead of one per snapshot. Older daemons lack the call
+// and leave the sizes nil
+func (c *DBusClient) usedSpaces(config string, raw []dbusSnapshot) []*int64 {
+	done := make(chan *dbus.Call, len(raw))
+	calls := make([]*dbus.Call, len(raw))
+	for i, r := range raw {
+		calls[i] = c.obj.Go(snapperInterface+".GetUsedSpace", 0, done, config, r.Number)
+	}
+	for range calls {
+		<-done
+	}
+	sizes := make([]*int64, len(raw))
+	for i, call := range calls {
+		var used uint64
+		if call.Store(&used) == nil {
+			sizes[i] = ptrInt64(int64(used))
+		}
+	}
+	return sizes
+}
+
+// optionalNumber calls a (bu) returning method such as GetDefaultSnapshot,
+// treating missing methods or invalid results as absent
+func (c *DBusClient) optionalNumber(method, config string) (int, bool) {
+	var valid bool
+	var number uint32
+	if err := c.call(method, config).Store(&valid, &number); err != nil || !valid {
+		return 0, false
+	}
+	return int(number), true
+}
+
+// call invokes a method on the snapperd object
+func (c *DBusClient) call(method string, args ...interface{}) *dbus.Call {
+	return c.obj.Call(snapperInterface+"."+method, 0, args...)
+}
+
+// subscribeSnapperSignals listens for snapperd's change signals on conn and
+// translates them into SnapperEvents until the connection closes
+func subscribeSnapperSignals(conn *dbus.Conn) (<-chan SnapperEvent, error) {
+	err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(snapperPath),
+		dbus.WithMatchInterface(snapperInterface),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to subscribe to snapperd signals: %w", err)
+	}
+
+	signals := make(chan *dbus.Signal, 32)
+	conn.Signal(signals)
+
+	events := make(chan SnapperEvent, 32)
+	go func() {
+		defer close(events)
+		for sig := range signals {
+			if ev, ok := snapperEventFromSignal(sig); ok {
+				events <- ev
+			}
+		}
+	}()
+	return events, nil
+}
+
+// snapperEventFromSignal decodes one snapperd signal; unrelated signals are skipped
+func snapperEventFromSignal(sig *dbus.Signal) (SnapperEvent, bool) {
+	if sig == nil || len(sig.Body) == 0 {
+		return SnapperEvent{}, false
+	}
+	config, ok := sig.Body[0].(string)
+	if !ok {
+		return SnapperEvent{}, false
+	}
+
+	ev := SnapperEvent{Config: config}
+	switch strings.TrimPrefix(sig.Name, snapperInterface+".") {
+	case "SnapshotCreated":
+		ev.Kind = EventSnapshotCreated
+	case "SnapshotModified":
+		ev.Kind = EventSnapshotModified
+	case "SnapshotsDeleted":
+		ev.Kind = EventSnapshotsDeleted
+	case "ConfigCreated":
+		return SnapperEvent{Kind: EventConfigCreated, Config: config}, true
+	case "ConfigDeleted":
+		return SnapperEvent{Kind: EventConfigDeleted, Config: config}, true
+	default:
+		return SnapperEvent{}, false
+	}
+
+	if len(sig.Body) < 2 {
+		return SnapperEvent{}, false
+	}
+	switch v := sig.Body[1].(type) {
+	case uint32:
+		ev.Numbers = []int{int(v)}
+	case []uint32:
+		for _, n := range v {
+			ev.Numbers = append(ev.Numbers, int(n))
+		}
+	default:
+		return SnapperEvent{}, false
+	}
+	return ev, true
+}
+
+// snapshotFromDBus converts a typed snapperd snapshot into a Snapshot
+func snapshotFromDBus(cfg dbusConfig, r dbusSnapshot) Snapshot {
+	snap := Snapshot{
+		Config:       cfg.Name,
+		Subvolume:    cfg.Subvolume,
+		Number:       int(r.Number),
+		SnapshotType: snapshotTypeName(r.Type),
+		User:         userName(r.UID),
+		Cleanup:      r.Cleanup,
+		Description:  r.Description,
+		Userdata:     r.Userdata,
+	}
+	if len(snap.Userdata) == 0 {
+		snap.Userdata = nil
+	}
+	// Snapshot 0 ("current") has no creation date
+	if r.Date > 0 {
+		snap.Date = time.Unix(r.Date, 0).Format("2006-01-02 15:04:05")
+	}
+	if snap.SnapshotType == "post" {
+		snap.PreNumber = toOptionalInt(int(r.PreNumber))
+	}
+	return snap
+}
+
+// snapshotTypeName maps snapperd's numeric snapshot type to the CLI name
+func snapshotTypeName(t uint16) string {
+	switch t {
+	case 0:
+		return "single"
+	case 1:
+		return "pre"
+	case 2:
+		return "post"
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// userName resolves a uid to a login name, falling back to the number
+func userName(uid uint32) string {
+	if u, err := user.LookupId(fmt.Sprint(uid)); err == nil {
+		return u.Username
+	}
+	return fmt.Sprint(uid)
+}
+
+// fileChangeFromStatus converts GetFiles status bits into a FileChange
+func fileChangeFromStatus(path string, status uint32) FileChange {
+	return FileChange{
+		Path:        path,
+		Created:     status&fileCreated != 0,
+		Deleted:     status&fileDeleted != 0,
+		TypeChanged: status&fileType != 0,
+		Content:     status&fileContent != 0,
+		Permissions: status&filePermissions != 0,
+		User:        status&fileOwner != 0,
+		Group:       status&fileGroup != 0,
+		Xattr:       status&fileXattrs != 0,
+		ACL:         status&fileACL != 0,
+	}
+}
+
+// dbusError turns snapperd error names into readable messages
+func dbusError(err error) error {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return err
+	}
+	switch dbusErr.Name {
+	case "error.no_permissions":
+		return errors.New("snapperd denied access: user is not in ALLOW_USERS/ALLOW_GROUPS of this config")
+	case "error.unknown_config":
+		return errors.New("snapperd: unknown config")
+	case "error.snapshot_not_found":
+		return errors.New("snapperd: snapshot not found")
+	case "error.illegal_snapshot":
+		return errors.New("snapperd: illegal snapshot")
+	}
+	if strings.HasPrefix(dbusErr.Name, "error.") {
+		return fmt.Errorf("snapperd: %s", strings.ReplaceAll(strings.TrimPrefix(dbusErr.Name, "error."), "_", " "))
+	}
+	return err
+}
@@ -0,0 +1,558 @@
+package main
+
+import (
+	"bufio"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeSnapperd is a stand-in org.opensuse.Snapper service: just enough of
+// snapperd for DBusClient, with ALLOW_USERS checked against caller
+type fakeSnapperd struct {
+	mu        sync.Mutex
+	conn      *dbus.Conn
+	configs   []dbusConfig
+	snapshots map[string][]dbusSnapshot
+	caller    string // user the calls are checked as; root may read everything
+	usedDelay time.Duration
+	files     []dbusFile // what every comparison finds
+	compared  int        // comparisons created and not yet deleted
+	inFlight  int
+	peak      int // most GetUsedSpace calls handled at the same time
+}
+
+var errNoPermissions = &dbus.Error{Name: "error.no_permissions"}
+
+// startFakeSnapperd runs a private session bus with the stand-in service on
+// it and points the session bus address at it
+func startFakeSnapperd(t *testing.T) *fakeSnapperd {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+	config := t.TempDir() + "/bus.conf"
+	err = os.WriteFile(config, []byte(`<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:dir=`+t.TempDir()+`</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(daemon, "--config-file="+config, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading the bus address: %v", err)
+	}
+	address = strings.TrimSpace(address)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", address)
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	f := &fakeSnapperd{
+		conn:   conn,
+		caller: "root",
+		configs: []dbusConfig{
+			{Name: "root", Subvolume: "/", Data: map[string]string{"SUBVOLUME": "/", "ALLOW_USERS": ""}},
+			{Name: "home", Subvolume: "/home", Data: map[string]string{"SUBVOLUME": "/home", "ALLOW_USERS": "alice"}},
+		},
+		snapshots: map[string][]dbusSnapshot{
+			"root": {
+				{Number: 0, Description: "current"},
+				{Number: 1, Type: 1, Date: 1731916800, Description: "zypp(zypper)", Cleanup: "number", Userdata: map[string]string{"important": "yes"}},
+				{Number: 2, Type: 2, PreNumber: 1, Date: 1731917000, Description: "zypp(zypper)", Cleanup: "number"},
+			},
+			"home": {
+				{Number: 0, Description: "current"},
+				{Number: 5, Date: 1731920000, Description: "hourly", Cleanup: "timeline"},
+			},
+		},
+	}
+	if err := conn.Export(f, snapperPath, snapperInterface); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := conn.RequestName(snapperBusName, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("claiming %s: %v (%v)", snapperBusName, err, reply)
+	}
+	return f
+}
+
+// allowed reports whether the caller may use a config; callers hold the lock
+func (f *fakeSnapperd) allowed(config string) (dbusConfig, *dbus.Error) {
+	for _, cfg := range f.configs {
+		if cfg.Name != config {
+			continue
+		}
+		if f.caller != "root" && !slices.Contains(strings.Fields(cfg.Data["ALLOW_USERS"]), f.caller) {
+			return dbusConfig{}, errNoPermissions
+		}
+		return cfg, nil
+	}
+	return dbusConfig{}, &dbus.Error{Name: "error.unknown_config"}
+}
+
+// change edits the service's state between calls
+func (f *fakeSnapperd) change(edit func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	edit()
+}
+
+// The methods answer with copies: replies are encoded after the lock is
+// released
+
+func (f *fakeSnapperd) ListConfigs() ([]dbusConfig, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	configs := slices.Clone(f.configs)
+	for i := range configs {
+		configs[i].Data = maps.Clone(configs[i].Data)
+	}
+	return configs, nil
+}
+
+func (f *fakeSnapperd) GetConfig(config string) (dbusConfig, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cfg, err := f.allowed(config)
+	cfg.Data = maps.Clone(cfg.Data)
+	return cfg, err
+}
+
+func (f *fakeSnapperd) ListSnapshots(config string) ([]dbusSnapshot, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.allowed(config); err != nil {
+		return nil, err
+	}
+	return slices.Clone(f.snapshots[config]), nil
+}
+
+func (f *fakeSnapperd) GetSnapshot(config string, number uint32) (dbusSnapshot, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.allowed(config); err != nil {
+		return dbusSnapshot{}, err
+	}
+	for _, s := range f.snapshots[config] {
+		if s.Number == number {
+			s.Userdata = maps.Clone(s.Userdata)
+			return s, nil
+		}
+	}
+	return dbusSnapshot{}, &dbus.Error{Name: "error.snapshot_not_found"}
+}
+
+func (f *fakeSnapperd) GetDefaultSnapshot(config string) (bool, uint32, *dbus.Error) {
+	return false, 0, nil
+}
+
+func (f *fakeSnapperd) GetActiveSnapshot(config string) (bool, uint32, *dbus.Error) {
+	return false, 0, nil
+}
+
+func (f *fakeSnapperd) GetUsedSpace(config string, number uint32) (uint64, *dbus.Error) {
+	f.mu.Lock()
+	f.inFlight++
+	f.peak = max(f.peak, f.inFlight)
+	delay := f.usedDelay
+	f.mu.Unlock()
+
+	time.Sleep(delay)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight--
+	return uint64(number) * 1024, nil
+}
+
+func (f *fakeSnapperd) CreateComparison(config string, from, to uint32) (uint32, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.allowed(config); err != nil {
+		return 0, err
+	}
+	f.compared++
+	return uint32(len(f.files)), nil
+}
+
+func (f *fakeSnapperd) GetFiles(config string, from, to uint32) ([]dbusFile, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.compared == 0 {
+		return nil, &dbus.Error{Name: "error.no_comparison"}
+	}
+	return slices.Clone(f.files), nil
+}
+
+func (f *fakeSnapperd) DeleteComparison(config string, from, to uint32) *dbus.Error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.compared--
+	return nil
+}
+
+func (f *fakeSnapperd) CreateSingleSnapshot(config, description, cleanup string, userdata map[string]string) (uint32, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.allowed(config); err != nil {
+		return 0, err
+	}
+	next := uint32(1)
+	for _, snap := range f.snapshots[config] {
+		if snap.Number >= next {
+			next = snap.Number + 1
+		}
+	}
+	f.snapshots[config] = append(f.snapshots[config], dbusSnapshot{
+		Number: next, Date: time.Now().Unix(), Description: description, Cleanup: cleanup, Userdata: userdata,
+	})
+	f.conn.Emit(snapperPath, snapperInterface+".SnapshotCreated", config, next)
+	return next, nil
+}
+
+func (f *fakeSnapperd) DeleteSnapshots(config string, numbers []uint32) *dbus.Error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.allowed(config); err != nil {
+		return err
+	}
+	for _, n := range numbers {
+		if !slices.ContainsFunc(f.snapshots[config], func(s dbusSnapshot) bool { return s.Number == n }) {
+			return &dbus.Error{Name: "error.snapshot_not_found"}
+		}
+	}
+	f.snapshots[config] = slices.DeleteFunc(f.snapshots[config], func(s dbusSnapshot) bool {
+		return slices.Contains(numbers, s.Number)
+	})
+	f.conn.Emit(snapperPath, snapperInterface+".SnapshotsDeleted", config, numbers)
+	return nil
+}
+
+func newTestDBusClient(t *testing.T) (*DBusClient, *fakeSnapperd) {
+	t.Helper()
+	f := startFakeSnapperd(t)
+	client, err := newDBusClient(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.conn.Close() })
+	return client, f
+}
+
+func TestDBusList(t *testing.T) {
+	client, _ := newTestDBusClient(t)
+	snaps, err := client.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := keys(snaps), "home#0 home#5 root#0 root#1 root#2"; got != want {
+		t.Fatalf("snapshots = %s, want %s", got, want)
+	}
+	pre := snaps[3]
+	if pre.SnapshotType != "pre" || intValue(pre.PostNumber) != 2 || pre.Userdata["important"] != "yes" || pre.Subvolume != "/" {
+		t.Errorf("pre snapshot = %+v", pre)
+	}
+	if post := snaps[4]; post.SnapshotType != "post" || intValue(post.PreNumber) != 1 {
+		t.Errorf("post snapshot = %+v", post)
+	}
+	if used := snaps[1].UsedSpace; used == nil || *used != 5*1024 {
+		t.Errorf("used space of home #5 = %v", used)
+	}
+	if snaps[0].Date != "" {
+		t.Errorf("current has date %q", snaps[0].Date)
+	}
+}
+
+func TestDBusListSendsUsedSpaceCallsTogether(t *testing.T) {
+	client, f := newTestDBusClient(t)
+	f.change(func() {
+		f.usedDelay = 20 * time.Millisecond
+		for n := uint32(10); n < 30; n++ {
+			f.snapshots["root"] = append(f.snapshots["root"], dbusSnapshot{Number: n, Date: 1731930000})
+		}
+	})
+	snaps, err := client.ListConfig("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 23 {
+		t.Fatalf("%d snapshots", len(snaps))
+	}
+	for _, snap := range snaps {
+		if snap.UsedSpace == nil || *snap.UsedSpace != int64(snap.Number)*1024 {
+			t.Errorf("used space of #%d = %v", snap.Number, snap.UsedSpace)
+		}
+	}
+	f.change(func() {
+		if f.peak < 2 {
+			t.Errorf("GetUsedSpace calls were made one at a time")
+		}
+	})
+}
+
+func TestDBusCreateAndDelete(t *testing.T) {
+	client, f := newTestDBusClient(t)
+	events, err := client.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	number, err := client.Create(CreateOptions{Config: "root", Type: "single", Description: "manual", Cleanup: "number", Userdata: map[string]string{"by": "test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if number != 3 {
+		t.Errorf("created #%d, want #3", number)
+	}
+	select {
+	case ev := <-events:
+		if ev.Kind != EventSnapshotCreated || ev.Config != "root" || len(ev.Numbers) != 1 || ev.Numbers[0] != 3 {
+			t.Errorf("event = %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("no SnapshotCreated signal")
+	}
+	snap, err := client.Get("root", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Description != "manual" || snap.Userdata["by"] != "test" {
+		t.Errorf("created snapshot = %+v", snap)
+	}
+
+	if _, err := client.Delete("root", []int{1, 3}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Kind != EventSnapshotsDeleted || len(ev.Numbers) != 2 {
+			t.Errorf("event = %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("no SnapshotsDeleted signal")
+	}
+	f.change(func() {
+		if got := len(f.snapshots["root"]); got != 2 {
+			t.Errorf("%d root snapshots left, want 2", got)
+		}
+	})
+
+	_, err = client.Delete("root", []int{42})
+	if err == nil || err.Error() != "snapperd: snapshot not found" {
+		t.Errorf("deleting a missing snapshot: %v", err)
+	}
+}
+
+func TestDBusGetConfigAndStatus(t *testing.T) {
+	client, f := newTestDBusClient(t)
+	cfg, err := client.GetConfig("home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "home" || cfg.Subvolume != "/home" || cfg.Values["ALLOW_USERS"] != "alice" {
+		t.Errorf("config = %+v", cfg)
+	}
+	if _, err := client.GetConfig("nothing"); err == nil {
+		t.Error("read a config that does not exist")
+	}
+
+	f.change(func() {
+		f.files = []dbusFile{
+			{Name: "/alice/notes", Status: fileContent | fileOwner},
+			{Name: "/alice/new", Status: fileCreated},
+		}
+	})
+	out, err := client.Status("home", 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "c.u... /home/alice/notes\n+..... /home/alice/new"; out != want {
+		t.Errorf("status = %q, want %q", out, want)
+	}
+	f.change(func() {
+		if f.compared != 0 {
+			t.Errorf("%d comparison(s) left behind", f.compared)
+		}
+	})
+}
+
+func TestDBusSignalsPatchTable(t *testing.T) {
+	client, f := newTestDBusClient(t)
+	m := initialModel(client)
+	if m.Events == nil {
+		t.Fatal("the model does not watch snapperd")
+	}
+	m = update(t, m, tea.WindowSizeMsg{Width: 160, Height: 40})
+	m = drive(t, m, refreshSnapshotsCmd(m.Client))
+	if len(m.Snapshots) != 5 {
+		t.Fatalf("snapshots = %s", keys(m.Snapshots))
+	}
+
+	// nextPatch feeds the next signal the model receives through Update
+	nextPatch := func() SnapshotPatchMsg {
+		t.Helper()
+		done := make(chan tea.Msg, 1)
+		go func() { done <- watchEventsCmd(m.Client, m.Events)() }()
+		select {
+		case msg := <-done:
+			patch, ok := msg.(SnapshotPatchMsg)
+			if !ok {
+				t.Fatalf("message = %#v", msg)
+			}
+			model, _ := m.Update(patch)
+			m = model.(UIState)
+			return patch
+		case <-time.After(2 * time.Second):
+			t.Fatal("no signal")
+			return SnapshotPatchMsg{}
+		}
+	}
+
+	f.change(func() {
+		f.snapshots["home"] = append(f.snapshots["home"], dbusSnapshot{Number: 6, Date: 1731930000, Description: "by hand", Cleanup: "number"})
+		f.conn.Emit(snapperPath, snapperInterface+".SnapshotCreated", "home", uint32(6))
+	})
+	if patch := nextPatch(); patch.Err != nil {
+		t.Fatalf("resolving the created snapshot: %v", patch.Err)
+	}
+	snap, err := findSnapshot(m.Snapshots, "home", 6)
+	if err != nil || snap.Description != "by hand" || snap.Subvolume != "/home" || snap.UsedSpace == nil {
+		t.Errorf("created row = %+v, %v", snap, err)
+	}
+
+	f.change(func() {
+		f.snapshots["root"][1].Description = "renamed"
+		f.conn.Emit(snapperPath, snapperInterface+".SnapshotModified", "root", uint32(1))
+	})
+	if patch := nextPatch(); patch.Err != nil {
+		t.Fatalf("resolving the modified snapshot: %v", patch.Err)
+	}
+	snap, err = findSnapshot(m.Snapshots, "root", 1)
+	if err != nil || snap.Description != "renamed" || intValue(snap.PostNumber) != 2 {
+		t.Errorf("modified row = %+v, %v", snap, err)
+	}
+	if len(m.Snapshots) != 6 || !strings.Contains(m.Status, "root: snapshot 1 modified") {
+		t.Errorf("%d rows, status %q", len(m.Snapshots), m.Status)
+	}
+}
+
+func TestDBusAllowUsers(t *testing.T) {
+	client, f := newTestDBusClient(t)
+	// Configs the user may not read are skipped as long as one is readable
+	f.change(func() {
+		f.caller = "bob"
+		f.configs[0].Data["ALLOW_USERS"] = "bob"
+	})
+	snaps, err := client.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(snaps); got != "root#0 root#1 root#2" {
+		t.Errorf("snapshots = %s", got)
+	}
+
+	const denied = "snapperd denied access: user is not in ALLOW_USERS/ALLOW_GROUPS of this config"
+	if _, err := client.ListConfig("home"); err == nil || err.Error() != denied {
+		t.Errorf("listing home: %v", err)
+	}
+	if _, err := client.Create(CreateOptions{Config: "home", Type: "single"}); err == nil || err.Error() != denied {
+		t.Errorf("creating in home: %v", err)
+	}
+	if out, err := client.Delete("home", []int{5}); err == nil || out != denied {
+		t.Errorf("deleting in home: %q, %v", out, err)
+	}
+
+	// With nothing readable, List reports why
+	f.change(func() { f.configs[0].Data["ALLOW_USERS"] = "" })
+	if _, err := client.List(); err == nil || !strings.Contains(err.Error(), denied) {
+		t.Errorf("listing with no readable config: %v", err)
+	}
+}
+
+func TestDBusRecorderLogsMethodCalls(t *testing.T) {
+	client, f := newTestDBusClient(t)
+	recorder := newRecorderClient(client)
+	audit, err := openAuditLog(t.TempDir() + "/audit.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Audit = audit
+
+	recorder.SetDryRun(true)
+	if _, err := recorder.Delete("root", []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.Rollback("root", 1); err != nil {
+		t.Fatal(err)
+	}
+	f.change(func() {
+		if got := len(f.snapshots["root"]); got != 3 {
+			t.Errorf("dry run deleted snapshots: %d left", got)
+		}
+	})
+	recorder.SetDryRun(false)
+	if _, err := recorder.Delete("home", []int{5}); err != nil {
+		t.Fatal(err)
+	}
+
+	// What is previewed is what runs: snapperd methods, and the CLI only for
+	// the rollback snapperd lacks
+	want := []string{
+		`snapperd DeleteSnapshots("root", [1, 2])`,
+		"snapper -c root rollback 1",
+		`snapperd DeleteSnapshots("home", [5])`,
+	}
+	entries, err := readAuditLog(audit.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("%d entries logged, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if got := entry.command(); got != want[i] {
+			t.Errorf("entry %d = %q, want %q", i, got, want[i])
+		}
+		if preview := recorder.Log()[i].command(); preview != want[i] {
+			t.Errorf("recorded %d = %q, want %q", i, preview, want[i])
+		}
+	}
+	if got := client.Describe(Operation{Kind: "delete", Config: "home", Numbers: []int{5}}).String(); got != want[2] {
+		t.Errorf("preview = %q", got)
+	}
+}
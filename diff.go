@@ -0,0 +1,299 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DiffLine is one line of a unified diff with its line numbers on each side
+type DiffLine struct {
+	Kind byte   // 'h' file header, '@' hunk header, ' ' context, '-' removed, '+' added, '\\' note
+	Text string // line without its marker, tabs expanded
+	Old  int    // line number in the old file, 0 if the line is not there
+	New  int    // line number in the new file, 0 if the line is not there
+	Pair int    // index of the line a removal/addition replaces, -1 if none
+}
+
+// FileDigest describes one side of a binary file comparison
+type FileDigest struct {
+	Path   string
+	Exists bool
+	Size   int64
+	SHA256 string
+	Err    error
+}
+
+// BinaryComparison compares a file between two snapshots by size and hash
+type BinaryComparison struct {
+	Old, New FileDigest
+}
+
+// maxWordDiffTokens bounds the word-level LCS; longer lines are marked whole
+const maxWordDiffTokens = 400
+
+// parseUnifiedDiff splits "snapper diff" output into numbered lines and pairs
+// each run of removals with the additions that follow it
+func parseUnifiedDiff(output string) []DiffLine {
+	if strings.TrimSpace(output) == "" {
+		return nil
+	}
+	var lines []DiffLine
+	inHunk := false
+	oldLine, newLine := 0, 0
+	for _, raw := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		raw = strings.ReplaceAll(raw, "\t", "    ")
+		if strings.HasPrefix(raw, "@@") {
+			oldLine, newLine = parseHunkHeader(raw)
+			inHunk = true
+			lines = append(lines, DiffLine{Kind: '@', Text: raw, Pair: -1})
+			continue
+		}
+		if !inHunk {
+			lines = append(lines, DiffLine{Kind: 'h', Text: raw, Pair: -1})
+			continue
+		}
+		if raw == "" {
+			// An empty context line whose leading space was trimmed
+			raw = " "
+		}
+
+		line := DiffLine{Kind: raw[0], Text: raw[1:], Pair: -1}
+		switch line.Kind {
+		case ' ':
+			line.Old, line.New = oldLine, newLine
+			oldLine++
+			newLine++
+		case '-':
+			line.Old = oldLine
+			oldLine++
+		case '+':
+			line.New = newLine
+			newLine++
+		case '\\':
+			line.Text = raw
+		default:
+			// Start of the next file's headers
+			line = DiffLine{Kind: 'h', Text: raw, Pair: -1}
+			inHunk = false
+		}
+		lines = append(lines, line)
+	}
+	pairDiffLines(lines)
+	return lines
+}
+
+// parseHunkHeader reads the starting line numbers of "@@ -a,b +c,d @@"
+func parseHunkHeader(header string) (int, int) {
+	fields := strings.Fields(header)
+	start := func(field string) int {
+		if len(field) < 2 {
+			return 0
+		}
+		number, _, _ := strings.Cut(field[1:], ",")
+		n, _ := strconv.Atoi(number)
+		return n
+	}
+	if len(fields) < 3 {
+		return 0, 0
+	}
+	return start(fields[1]), start(fields[2])
+}
+
+// pairDiffLines links the i-th removal of a run with the i-th addition after it
+func pairDiffLines(lines []DiffLine) {
+	for i := 0; i < len(lines); {
+		if lines[i].Kind != '-' {
+			i++
+			continue
+		}
+		delStart := i
+		for i < len(lines) && lines[i].Kind == '-' {
+			i++
+		}
+		addStart := i
+		for i < len(lines) && lines[i].Kind == '+' {
+			i++
+		}
+		for k := 0; delStart+k < addStart && addStart+k < i; k++ {
+			lines[delStart+k].Pair = addStart + k
+			lines[addStart+k].Pair = delStart + k
+		}
+	}
+}
+
+// isBinaryDiff reports whether snapper could not produce a textual diff
+func isBinaryDiff(output string) bool {
+	if strings.ContainsRune(output, 0) || !utf8.ValidString(output) {
+		return true
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ") {
+			return true
+		}
+	}
+	return false
+}
+
+// compareFiles digests a file in two snapshots of the config; snapshot 0 is
+// the live system
+func compareFiles(snap Snapshot, from, to int, path string) *BinaryComparison {
+	digest := func(number int) FileDigest {
+		if number == 0 {
+			return digestFile(path)
+		}
+		snap.Number = number
+		rel, err := snapshotRelPath(snap, path)
+		if err != nil {
+			return FileDigest{Path: path, Err: err}
+		}
+		return digestFile(filepath.Join(snapshotRoot(snap), rel))
+	}
+	return &BinaryComparison{Old: digest(from), New: digest(to)}
+}
+
+// digestFile stats and hashes a file; a missing file is not an error
+func digestFile(path string) FileDigest {
+	digest := FileDigest{Path: path}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return digest
+	}
+	if err != nil {
+		digest.Err = err
+		return digest
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		digest.Err = err
+		return digest
+	}
+	digest.Exists = true
+	digest.Size = info.Size()
+	if !info.Mode().IsRegular() {
+		digest.Err = fmt.Errorf("not a regular file (%s)", info.Mode().Type())
+		return digest
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		digest.Err = err
+		return digest
+	}
+	digest.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return digest
+}
+
+// Verdict summarises a binary comparison in a few words
+func (c BinaryComparison) Verdict() string {
+	switch {
+	case c.Old.Err != nil || c.New.Err != nil:
+		return "could not compare both sides"
+	case !c.Old.Exists && !c.New.Exists:
+		return "file is in neither snapshot"
+	case !c.Old.Exists:
+		return "file was created"
+	case !c.New.Exists:
+		return "file was deleted"
+	case c.Old.SHA256 == c.New.SHA256:
+		return "contents are identical"
+	case c.Old.Size != c.New.Size:
+		return fmt.Sprintf("contents differ (%+d bytes)", c.New.Size-c.Old.Size)
+	}
+	return "contents differ (same size)"
+}
+
+// diffTokens splits a line into words, runs of spaces and single symbols
+func diffTokens(text string) []string {
+	var tokens []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i + 1
+		switch {
+		case isWordRune(runes[i]):
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+		case unicode.IsSpace(runes[i]):
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+		}
+		tokens = append(tokens, string(runes[i:j]))
+		i = j
+	}
+	return tokens
+}
+
+// isWordRune reports whether r continues a word token
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordDiffMasks marks, per rune, which parts of a removed line (a) and its
+// replacement (b) are not shared between them
+func wordDiffMasks(a, b string) ([]bool, []bool) {
+	ta, tb := diffTokens(a), diffTokens(b)
+	if len(ta) > maxWordDiffTokens || len(tb) > maxWordDiffTokens {
+		return changedMask(a), changedMask(b)
+	}
+
+	// lcs[i][j] is the longest common subsequence of ta[i:] and tb[j:]
+	lcs := make([][]int, len(ta)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(tb)+1)
+	}
+	for i := len(ta) - 1; i >= 0; i-- {
+		for j := len(tb) - 1; j >= 0; j-- {
+			if ta[i] == tb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	keepA := make([]bool, len(ta))
+	keepB := make([]bool, len(tb))
+	for i, j := 0, 0; i < len(ta) && j < len(tb); {
+		switch {
+		case ta[i] == tb[j]:
+			keepA[i], keepB[j] = true, true
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return tokenMask(ta, keepA), tokenMask(tb, keepB)
+}
+
+// tokenMask expands per-token keep flags into per-rune changed flags
+func tokenMask(tokens []string, keep []bool) []bool {
+	var mask []bool
+	for i, token := range tokens {
+		for range []rune(token) {
+			mask = append(mask, !keep[i])
+		}
+	}
+	return mask
+}
+
+// changedMask marks every rune of text as changed
+func changedMask(text string) []bool {
+	mask := make([]bool, utf8.RuneCountInString(text))
+	for i := range mask {
+		mask[i] = true
+	}
+	return mask
+}
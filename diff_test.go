@@ -0,0 +1,291 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// diffSummary renders lines as "kind|old|new|pair|text", leaving absent line
+// numbers blank
+func diffSummary(lines []DiffLine) []string {
+	var out []string
+	for _, l := range lines {
+		out = append(out, strings.Join([]string{
+			string(l.Kind), strings.TrimSpace(lineNumber(l.Old)), strings.TrimSpace(lineNumber(l.New)), strconv.Itoa(l.Pair), l.Text,
+		}, "|"))
+	}
+	return out
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"blank", "\n\n", nil},
+		{
+			"numbered hunk",
+			"--- a/etc/fstab\n+++ b/etc/fstab\n@@ -3,4 +3,5 @@\n keep\n-old\n+new\n+more\n same\n",
+			[]string{
+				"h|||-1|--- a/etc/fstab",
+				"h|||-1|+++ b/etc/fstab",
+				"@|||-1|@@ -3,4 +3,5 @@",
+				" |3|3|-1|keep",
+				"-|4||5|old",
+				"+||4|4|new",
+				"+||5|-1|more",
+				" |5|6|-1|same",
+			},
+		},
+		{
+			"second hunk restarts numbering",
+			"@@ -1 +1 @@\n-a\n+b\n@@ -10,2 +10,2 @@\n x\n-c\n",
+			[]string{
+				"@|||-1|@@ -1 +1 @@",
+				"-|1||2|a",
+				"+||1|1|b",
+				"@|||-1|@@ -10,2 +10,2 @@",
+				" |10|10|-1|x",
+				"-|11||-1|c",
+			},
+		},
+		{
+			"no newline notes",
+			"@@ -1 +1 @@\n-old\n\\ No newline at end of file\n+new\n\\ No newline at end of file\n",
+			[]string{
+				"@|||-1|@@ -1 +1 @@",
+				"-|1||-1|old",
+				"\\|||-1|\\ No newline at end of file",
+				"+||1|-1|new",
+				"\\|||-1|\\ No newline at end of file",
+			},
+		},
+		{
+			"trimmed empty context line and tabs",
+			"@@ -1,3 +1,3 @@\n a\n\n+\tb\n",
+			[]string{
+				"@|||-1|@@ -1,3 +1,3 @@",
+				" |1|1|-1|a",
+				" |2|2|-1|",
+				"+||3|-1|    b",
+			},
+		},
+		{
+			"multi-file headers",
+			"--- a/one\n+++ b/one\n@@ -1 +1 @@\n-x\n+y\ndiff a/two b/two\n--- a/two\n+++ b/two\n@@ -7 +7 @@\n-p\n+q\n",
+			[]string{
+				"h|||-1|--- a/one",
+				"h|||-1|+++ b/one",
+				"@|||-1|@@ -1 +1 @@",
+				"-|1||4|x",
+				"+||1|3|y",
+				"h|||-1|diff a/two b/two",
+				"h|||-1|--- a/two",
+				"h|||-1|+++ b/two",
+				"@|||-1|@@ -7 +7 @@",
+				"-|7||10|p",
+				"+||7|9|q",
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := diffSummary(parseUnifiedDiff(tt.output))
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestParseHunkHeader(t *testing.T) {
+	tests := []struct {
+		header   string
+		old, new int
+	}{
+		{"@@ -3,4 +5,6 @@", 3, 5},
+		{"@@ -1 +1 @@", 1, 1},
+		{"@@ -0,0 +1,12 @@ func main() {", 0, 1},
+		{"@@ -17,2 +0,0 @@", 17, 0},
+		{"@@", 0, 0},
+		{"@@ garbage", 0, 0},
+	}
+	for _, tt := range tests {
+		old, new := parseHunkHeader(tt.header)
+		if old != tt.old || new != tt.new {
+			t.Errorf("parseHunkHeader(%q) = %d, %d; want %d, %d", tt.header, old, new, tt.old, tt.new)
+		}
+	}
+}
+
+func TestPairDiffLines(t *testing.T) {
+	tests := []struct {
+		kinds string
+		want  []int
+	}{
+		{"--++", []int{2, 3, 0, 1}},
+		{"---+", []int{3, -1, -1, 0}},
+		{"-+++", []int{1, 0, -1, -1}},
+		{"- +", []int{-1, -1, -1}},
+		{"++--", []int{-1, -1, -1, -1}},
+		{"-+@-+", []int{1, 0, -1, 4, 3}},
+	}
+	for _, tt := range tests {
+		lines := make([]DiffLine, len(tt.kinds))
+		for i := range lines {
+			lines[i] = DiffLine{Kind: tt.kinds[i], Pair: -1}
+		}
+		pairDiffLines(lines)
+		for i, line := range lines {
+			if line.Pair != tt.want[i] {
+				t.Errorf("%q: line %d paired with %d, want %d", tt.kinds, i, line.Pair, tt.want[i])
+			}
+		}
+	}
+}
+
+// maskString renders a mask as "^" for changed runes and "." for shared ones
+func maskString(mask []bool) string {
+	var b strings.Builder
+	for _, changed := range mask {
+		if changed {
+			b.WriteByte('^')
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
+
+func TestWordDiffMasks(t *testing.T) {
+	long := strings.Repeat("a ", maxWordDiffTokens/2-1)
+	tests := []struct {
+		name, a, b   string
+		wantA, wantB string
+	}{
+		{"one word", "size = 10", "size = 20", ".......^^", ".......^^"},
+		{"insertion", "a b", "a x b", "...", "..^^."},
+		{"identical", "same", "same", "....", "...."},
+		{"empty side", "", "new", "", "^^^"},
+		// Exactly at the limit the LCS still runs
+		{"at limit", long + "c ", long + "b ", strings.Repeat(".", len(long)) + "^.", strings.Repeat(".", len(long)) + "^."},
+		// One token above it both lines are marked whole
+		{"above limit", long + "c d", long + "b ", strings.Repeat("^", len(long)+3), strings.Repeat("^", len(long)+2)},
+	}
+	for _, tt := range tests {
+		a, b := wordDiffMasks(tt.a, tt.b)
+		if got := maskString(a); got != tt.wantA {
+			t.Errorf("%s: old mask %q, want %q", tt.name, got, tt.wantA)
+		}
+		if got := maskString(b); got != tt.wantB {
+			t.Errorf("%s: new mask %q, want %q", tt.name, got, tt.wantB)
+		}
+	}
+}
+
+func TestIsBinaryDiff(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"@@ -1 +1 @@\n-a\n+b\n", false},
+		{"Binary files /a/x and /b/x differ\n", true},
+		{"--- a\n+++ b\n@@ -1 +1 @@\n-\x00\n", true},
+		{"\xff\xfe", true},
+		{"-Binary files are fun\n", false},
+	}
+	for _, tt := range tests {
+		if got := isBinaryDiff(tt.output); got != tt.want {
+			t.Errorf("isBinaryDiff(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestSyntaxForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/home/alice/main.go", "go"},
+		{"/usr/include/stdio.h", "c"},
+		{"/srv/app/SETUP.PY", "python"},
+		{"/etc/snapper/configs/root", "config"},
+		{"/etc/fstab", "config"},
+		{"/home/alice/.bashrc", "shell"},
+		{"/etc/containers/policy.json", "json"},
+		{"/etc/netplan/01.yaml", "yaml"},
+		{"/usr/lib/systemd/system/sshd.service", "config"},
+		{"/usr/bin/ls", ""},
+		{"/home/alice/notes.txt", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if lang := syntaxForPath(tt.path); lang != nil {
+			got = lang.Name
+		}
+		if got != tt.want {
+			t.Errorf("syntaxForPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// classString renders syntax classes one letter per rune: k keyword,
+// s string, c comment, n number, . plain
+func classString(classes []syntaxClass) string {
+	letters := map[syntaxClass]byte{synPlain: '.', synKeyword: 'k', synString: 's', synComment: 'c', synNumber: 'n'}
+	var b strings.Builder
+	for _, class := range classes {
+		b.WriteByte(letters[class])
+	}
+	return b.String()
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		lang     *syntaxLang
+		text     string
+		inBlock  bool
+		want     string
+		endBlock bool
+	}{
+		{langGo, `return "a\"b" // done`, false, `kkkkkk.ssssss.ccccccc`, false},
+		{langGo, `x := 42 /* open`, false, `.....nn.ccccccc`, true},
+		{langGo, `still */ y`, true, `cccccccc..`, false},
+		{langGo, `a /* b */ if`, false, `..ccccccc.kk`, false},
+		{langGo, "`raw\\`", false, "ssssss", false},
+		{langGo, `iffy 3.14`, false, `.....nnnn`, false},
+		{langC, `#include <x.h>`, false, `kkkkkkkk......`, false},
+		{langShell, `echo $# # count`, false, `........ccccccc`, false},
+		{langPython, `def f(): # x`, false, `kkk......ccc`, false},
+		{langConf, `SUBVOLUME="/" ; note`, false, `kkkkkkkkk.sss.cccccc`, false},
+		{langConf, `  key = 1`, false, `..kkk...n`, false},
+		{langConf, `a;b`, false, `...`, false},
+		{langYAML, `- name: true`, false, `..kkkk..kkkk`, false},
+		{langJSON, `{"n": 12, "ok": null}`, false, `.sss..nn..ssss..kkkk.`, false},
+	}
+	for _, tt := range tests {
+		got, endBlock := tt.lang.classify(tt.text, tt.inBlock)
+		if s := classString(got); s != tt.want || endBlock != tt.endBlock {
+			t.Errorf("%s %q = %q, %v; want %q, %v", tt.lang.Name, tt.text, s, endBlock, tt.want, tt.endBlock)
+		}
+	}
+}
+
+func TestHighlightDiffFollowsBothSides(t *testing.T) {
+	// The old side opens a block comment the new side does not have
+	lines := parseUnifiedDiff("@@ -1,3 +1,3 @@\n-/* start\n+x := 1\n if\n@@ -9 +9 @@\n if\n")
+	if len(lines) != 6 {
+		t.Fatalf("%d lines", len(lines))
+	}
+	classes := highlightDiff(langGo, lines)
+	want := []string{"", "cccccccc", ".....n", "kk", "", "kk"}
+	for i := range lines {
+		if got := classString(classes[i]); got != want[i] {
+			t.Errorf("line %d %q = %q, want %q", i, lines[i].Text, got, want[i])
+		}
+	}
+	if highlightDiff(nil, lines) != nil {
+		t.Error("classes without a language")
+	}
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	diffHeaderStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#e2e8f0"))
+	diffHunkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#7dd3fc"))
+	diffAddStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#10b981"))
+	diffDelStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#f87171"))
+	diffAddWordStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffffff")).Background(lipgloss.Color("#065f46"))
+	diffDelWordStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffffff")).Background(lipgloss.Color("#7f1d1d"))
+	diffGutterStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#64748b"))
+
+	// syntaxColors tint code over the +/- colouring
+	syntaxColors = map[syntaxClass]lipgloss.Color{
+		synKeyword: lipgloss.Color("#c084fc"),
+		synString:  lipgloss.Color("#fbbf24"),
+		synComment: lipgloss.Color("#64748b"),
+		synNumber:  lipgloss.Color("#f472b6"),
+	}
+)
+
+// DiffView is the full-screen pane showing "snapper diff" for one file of a
+// status range, as a unified or side-by-side diff
+type DiffView struct {
+	Snap       Snapshot
+	From, To   int
+	Path       string
+	Lines      []DiffLine
+	Lang       *syntaxLang       // nil when the file type is not recognised
+	Syntax     [][]syntaxClass   // per line of Lines, nil without a language
+	Binary     *BinaryComparison // set instead of Lines for files without a textual diff
+	SideBySide bool
+	WordMode   bool
+	Rows       []DiffRow
+	Hunks      []int // indexes into Rows where hunks start
+	Offset     int
+	Back       string // screen to return to
+}
+
+// DiffRow is one screen row: an index into Lines for each side, -1 for a
+// blank side. Unified rows only use Left.
+type DiffRow struct {
+	Left, Right int
+}
+
+// DiffResultMsg carries the diff of one file
+type DiffResultMsg struct {
+	Snap     Snapshot
+	From, To int
+	Path     string
+	Output   string
+	Binary   *BinaryComparison
+	Err      error
+}
+
+// diffFileCmd fetches the diff of a file, comparing sizes and hashes when
+// snapper reports it as binary
+func diffFileCmd(client SnapperClient, snap Snapshot, from, to int, path string) tea.Cmd {
+	return func() tea.Msg {
+		output, err := client.Diff(snap.Config, from, to, path)
+		msg := DiffResultMsg{Snap: snap, From: from, To: to, Path: path, Output: output, Err: err}
+		if err == nil && isBinaryDiff(output) {
+			msg.Binary = compareFiles(snap, from, to, path)
+		}
+		return msg
+	}
+}
+
+// newDiffView parses diff output into a pane
+func newDiffView(msg DiffResultMsg) *DiffView {
+	v := &DiffView{Snap: msg.Snap, From: msg.From, To: msg.To, Path: msg.Path, Binary: msg.Binary}
+	if msg.Binary == nil {
+		v.Lines = parseUnifiedDiff(msg.Output)
+		v.Lang = syntaxForPath(msg.Path)
+		v.Syntax = highlightDiff(v.Lang, v.Lines)
+	}
+	v.layout()
+	return v
+}
+
+// openDiff starts loading the diff for the status line under the cursor
+func (m UIState) openDiff() (tea.Model, tea.Cmd) {
+	v := m.StatusView
+	line := -1
+	switch {
+	case v.TreeMode && v.Cursor < len(v.TreeRows):
+		line = v.TreeRows[v.Cursor].Node.Line
+	case !v.TreeMode && v.Cursor < len(v.Rows):
+		line = v.Rows[v.Cursor]
+	}
+	if line < 0 || v.Changes[line] == nil {
+		m.Status = "No file under the cursor"
+		return m, nil
+	}
+	path := v.Changes[line].Path
+	m.Status = fmt.Sprintf("Loading diff of %s...", path)
+	return m, diffFileCmd(m.Client, v.Snap, v.From, v.To, path)
+}
+
+// handleDiffResult opens the diff pane over the status viewer
+func (m UIState) handleDiffResult(msg DiffResultMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		m.Status = fmt.Sprintf("Diff failed: %s", firstLine(msg.Output))
+		return m, nil
+	}
+	m.DiffView = newDiffView(msg)
+	m.DiffView.Back = m.Screen
+	m.Screen = "diff"
+	m.Status = fmt.Sprintf("Diff of %s", msg.Path)
+	return m, nil
+}
+
+// layout builds the screen rows for the current mode and finds the hunks
+func (v *DiffView) layout() {
+	v.Rows = v.Rows[:0]
+	v.Hunks = v.Hunks[:0]
+	for i := 0; i < len(v.Lines); i++ {
+		line := v.Lines[i]
+		if line.Kind == '@' {
+			v.Hunks = append(v.Hunks, len(v.Rows))
+		}
+		if !v.SideBySide {
+			v.Rows = append(v.Rows, DiffRow{Left: i, Right: -1})
+			continue
+		}
+
+		switch line.Kind {
+		case '-', '+':
+			// Lay a run of removals beside the additions that follow it
+			start := i
+			for i < len(v.Lines) && v.Lines[i].Kind == '-' {
+				i++
+			}
+			dels := i - start
+			addStart := i
+			for i < len(v.Lines) && v.Lines[i].Kind == '+' {
+				i++
+			}
+			adds := i - addStart
+			for k := 0; k < max(dels, adds); k++ {
+				row := DiffRow{Left: -1, Right: -1}
+				if k < dels {
+					row.Left = start + k
+				}
+				if k < adds {
+					row.Right = addStart + k
+				}
+				v.Rows = append(v.Rows, row)
+			}
+			i--
+		default:
+			v.Rows = append(v.Rows, DiffRow{Left: i, Right: i})
+		}
+	}
+}
+
+// scroll moves the page by delta rows, clamped to the diff
+func (v *DiffView) scroll(delta, page int) {
+	v.Offset = max(0, min(v.Offset+delta, len(v.Rows)-page))
+}
+
+// currentHunk returns the index into Hunks of the hunk at the top of the page
+func (v *DiffView) currentHunk() int {
+	current := -1
+	for i, row := range v.Hunks {
+		if row <= v.Offset {
+			current = i
+		}
+	}
+	return current
+}
+
+// jumpHunk scrolls to the next (1) or previous (-1) hunk
+func (v *DiffView) jumpHunk(dir, page int) string {
+	if len(v.Hunks) == 0 {
+		return "No hunks"
+	}
+	target := -1
+	if dir > 0 {
+		for i, row := range v.Hunks {
+			if row > v.Offset {
+				target = i
+				break
+			}
+		}
+	} else {
+		for i := len(v.Hunks) - 1; i >= 0; i-- {
+			if v.Hunks[i] < v.Offset {
+				target = i
+				break
+			}
+		}
+	}
+	if target < 0 {
+		return "No more hunks"
+	}
+	v.scroll(v.Hunks[target]-v.Offset, page)
+	return fmt.Sprintf("Hunk %d of %d", target+1, len(v.Hunks))
+}
+
+// handleDiffKey drives the diff pane: scrolling, hunks and display modes
+func (m UIState) handleDiffKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.DiffView
+	page := m.pageHeight()
+	switch msg.String() {
+	case "esc", "q":
+		m.Screen = v.Back
+		m.DiffView = nil
+		m.Status = "Closed diff"
+	case "j", "down":
+		v.scroll(1, page)
+	case "k", "up":
+		v.scroll(-1, page)
+	case "pgdn", "pagedown", " ":
+		v.scroll(page, page)
+	case "pgup", "pageup":
+		v.scroll(-page, page)
+	case "g", "home":
+		v.scroll(-len(v.Rows), page)
+	case "G", "end":
+		v.scroll(len(v.Rows), page)
+	case "n", "]":
+		m.Status = v.jumpHunk(1, page)
+	case "N", "[":
+		m.Status = v.jumpHunk(-1, page)
+	case "s":
+		hunk := v.currentHunk()
+		v.SideBySide = !v.SideBySide
+		v.layout()
+		v.Offset = 0
+		if hunk >= 0 {
+			v.scroll(v.Hunks[hunk], page)
+		}
+		m.Status = "Unified diff"
+		if v.SideBySide {
+			m.Status = "Side-by-side diff"
+		}
+	case "w":
+		v.WordMode = !v.WordMode
+		m.Status = "Word highlighting off"
+		if v.WordMode {
+			m.Status = "Word highlighting on"
+		}
+	}
+	return m, nil
+}
+
+// handleDiffMouse scrolls the diff pane with the wheel
+func (m UIState) handleDiffMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.MouseWheelUp:
+		m.DiffView.scroll(-3, m.pageHeight())
+	case tea.MouseWheelDown:
+		m.DiffView.scroll(3, m.pageHeight())
+	}
+	return m, nil
+}
+
+// renderDiffView draws the diff pane over the whole screen
+func (m UIState) renderDiffView(width, height int) string {
+	v := m.DiffView
+	page := m.pageHeight()
+
+	title := detailHeaderStyle.Render(fmt.Sprintf("Diff %s %d..%d %s", v.Snap.Config, v.From, v.To, v.Path))
+	mode := "unified"
+	if v.SideBySide {
+		mode = "side-by-side"
+	}
+	if v.WordMode {
+		mode += ", word highlighting"
+	}
+	if v.Lang != nil {
+		mode += ", " + v.Lang.Name
+	}
+	info := fmt.Sprintf("%s | %d hunks", mode, len(v.Hunks))
+	if hunk := v.currentHunk(); hunk >= 0 {
+		info = fmt.Sprintf("%s | hunk %d of %d", mode, hunk+1, len(v.Hunks))
+	}
+	if len(v.Rows) > 0 {
+		info += fmt.Sprintf(" | rows %d-%d of %d", v.Offset+1, min(v.Offset+page, len(v.Rows)), len(v.Rows))
+	}
+
+	var body []string
+	switch {
+	case v.Binary != nil:
+		body = renderBinaryComparison(*v.Binary, width)
+		info = "binary file"
+	case len(v.Lines) == 0:
+		body = append(body, "No textual differences.")
+	default:
+		for _, row := range v.Rows[v.Offset:min(v.Offset+page, len(v.Rows))] {
+			if v.SideBySide {
+				body = append(body, v.renderSideBySideRow(row, width))
+			} else {
+				body = append(body, v.renderUnifiedLine(row.Left, width))
+			}
+		}
+	}
+
+	footer := pagerHelpStyle.Render("↑↓/PgUp/PgDn/g/G: Scroll | n/N or ]/[: Next/Prev hunk | s: Side-by-side | w: Word highlighting | esc/q: Back")
+	ui := lipgloss.JoinVertical(lipgloss.Left,
+		headerStyle.Width(width).Render(m.headerTitle()),
+		title,
+		summaryStyle.Render(info),
+		lipgloss.NewStyle().Height(page).Render(strings.Join(body, "\n")),
+		footer,
+	)
+	return lipgloss.Place(width, height, lipgloss.Top, lipgloss.Left, ui)
+}
+
+// renderUnifiedLine draws a diff line with old/new line numbers in the gutter
+func (v *DiffView) renderUnifiedLine(idx, width int) string {
+	line := v.Lines[idx]
+	switch line.Kind {
+	case 'h':
+		return diffHeaderStyle.Render(padOrTruncate(line.Text, width))
+	case '@', '\\':
+		return diffHunkStyle.Render(padOrTruncate(line.Text, width))
+	}
+	gutter := diffGutterStyle.Render(lineNumber(line.Old) + " " + lineNumber(line.New) + " ")
+	return gutter + v.renderCode(idx, max(0, width-12))
+}
+
+// renderSideBySideRow draws the old side on the left and the new on the right
+func (v *DiffView) renderSideBySideRow(row DiffRow, width int) string {
+	if row.Left >= 0 && row.Left == row.Right {
+		line := v.Lines[row.Left]
+		switch line.Kind {
+		case 'h':
+			return diffHeaderStyle.Render(padOrTruncate(line.Text, width))
+		case '@', '\\':
+			return diffHunkStyle.Render(padOrTruncate(line.Text, width))
+		}
+	}
+
+	half := max(8, (width-3)/2)
+	side := func(idx int, number func(DiffLine) int) string {
+		if idx < 0 {
+			return strings.Repeat(" ", half)
+		}
+		return diffGutterStyle.Render(lineNumber(number(v.Lines[idx]))+" ") + v.renderCode(idx, half-6)
+	}
+	left := side(row.Left, func(l DiffLine) int { return l.Old })
+	right := side(row.Right, func(l DiffLine) int { return l.New })
+	return left + diffGutterStyle.Render(" │ ") + right
+}
+
+// renderCode draws the marker and text of a line, highlighting the words it
+// does not share with its paired line in word mode
+func (v *DiffView) renderCode(idx, width int) string {
+	line := v.Lines[idx]
+	base, strong := lipgloss.NewStyle(), lipgloss.NewStyle()
+	switch line.Kind {
+	case '+':
+		base, strong = diffAddStyle, diffAddWordStyle
+	case '-':
+		base, strong = diffDelStyle, diffDelWordStyle
+	}
+
+	var mask []bool
+	if v.WordMode && line.Pair >= 0 {
+		other := v.Lines[line.Pair].Text
+		if line.Kind == '-' {
+			mask, _ = wordDiffMasks(line.Text, other)
+		} else {
+			_, mask = wordDiffMasks(other, line.Text)
+		}
+	}
+	var classes []syntaxClass
+	if v.Syntax != nil {
+		classes = v.Syntax[idx]
+	}
+	return base.Render(string(line.Kind)) + renderMasked(line.Text, mask, classes, max(0, width-1), base, strong)
+}
+
+// renderMasked pads or truncates text to width, drawing runes whose mask is
+// set in strong and the rest in base, each tinted by its syntax class
+func renderMasked(text string, mask []bool, classes []syntaxClass, width int, base, strong lipgloss.Style) string {
+	runes := []rune(padOrTruncate(text, width))
+	changedAt := func(i int) bool { return i < len(mask) && mask[i] }
+	classAt := func(i int) syntaxClass {
+		if i < len(classes) {
+			return classes[i]
+		}
+		return synPlain
+	}
+	var b strings.Builder
+	for start := 0; start < len(runes); {
+		changed, class := changedAt(start), classAt(start)
+		end := start + 1
+		for end < len(runes) && changedAt(end) == changed && classAt(end) == class {
+			end++
+		}
+		style := base
+		if changed {
+			style = strong
+		}
+		if color, ok := syntaxColors[class]; ok {
+			style = style.Foreground(color)
+		}
+		b.WriteString(style.Render(string(runes[start:end])))
+		start = end
+	}
+	return b.String()
+}
+
+// renderBinaryComparison lists size and hash of both sides of a binary file
+func renderBinaryComparison(c BinaryComparison, width int) []string {
+	fit := func(text string) string {
+		return strings.TrimRight(padOrTruncate(text, width), " ")
+	}
+	side := func(label string, d FileDigest) []string {
+		lines := []string{diffHeaderStyle.Render(fit(label + ": " + d.Path))}
+		switch {
+		case d.Err != nil && !d.Exists:
+			lines = append(lines, fit("  error: "+d.Err.Error()))
+		case !d.Exists:
+			lines = append(lines, "  (does not exist)")
+		default:
+			lines = append(lines, fit(fmt.Sprintf("  size:   %s (%d bytes)", humanReadableBytes(&d.Size), d.Size)))
+			if d.Err != nil {
+				lines = append(lines, fit("  sha256: "+d.Err.Error()))
+			} else {
+				lines = append(lines, fit("  sha256: "+d.SHA256))
+			}
+		}
+		return lines
+	}
+
+	lines := []string{fit("Binary file; comparing size and SHA-256 instead of a textual diff."), ""}
+	lines = append(lines, side("Old", c.Old)...)
+	lines = append(lines, "")
+	lines = append(lines, side("New", c.New)...)
+	lines = append(lines, "", diffHunkStyle.Render(fit("Result: "+c.Verdict())))
+	return lines
+}
+
+// lineNumber right-aligns a diff line number, blank for 0
+func lineNumber(n int) string {
+	if n == 0 {
+		return strings.Repeat(" ", 5)
+	}
+	return fmt.Sprintf("%5d", n)
+}
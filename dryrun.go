@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxDryRunListed caps how many recorded commands the action panel lists
+// under its heading
+const maxDryRunListed = 3
+
+// RecorderClient wraps a SnapperClient and records every action that
+// changes something, in memory and in the audit log. In dry-run mode those
+// actions are only recorded; listing and comparing snapshots still go to the
+// wrapped client.
+type RecorderClient struct {
+	Inner    SnapperClient
+	Audit    *AuditLog // nil when audit logging is off
+	User     string
+	SudoUser string
+
+	mu       sync.Mutex
+	dryRun   bool
+	log      []AuditEntry
+	pending  []AuditEntry // dry-run entries not yet reported to the UI
+	auditErr error        // last failure to append to the audit log
+}
+
+// newRecorderClient wraps a client; dry-run mode starts off
+func newRecorderClient(inner SnapperClient) *RecorderClient {
+	name, sudoUser := invokingUser()
+	return &RecorderClient{Inner: inner, User: name, SudoUser: sudoUser}
+}
+
+// SetDryRun switches dry-run mode on or off
+func (r *RecorderClient) SetDryRun(on bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dryRun = on
+}
+
+// DryRun reports whether actions are only recorded
+func (r *RecorderClient) DryRun() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dryRun
+}
+
+// Log returns a copy of every entry recorded this session, oldest first
+func (r *RecorderClient) Log() []AuditEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]AuditEntry(nil), r.log...)
+}
+
+// AuditErr returns the last failure to write the audit log
+func (r *RecorderClient) AuditErr() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.auditErr
+}
+
+// takeDryRun returns the dry-run entries recorded since the last call
+func (r *RecorderClient) takeDryRun() []AuditEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pending := r.pending
+	r.pending = nil
+	return pending
+}
+
+// record runs an action through the wrapped client unless in dry-run mode,
+// and logs it with its outcome
+func (r *RecorderClient) record(entry AuditEntry, run func() (string, error)) (string, error) {
+	r.mu.Lock()
+	dryRun := r.dryRun
+	r.mu.Unlock()
+
+	entry.Time = time.Now()
+	entry.User = r.User
+	entry.SudoUser = r.SudoUser
+	entry.DryRun = dryRun
+	var output string
+	var err error
+	if !dryRun {
+		output, err = run()
+		entry.finish(entry.Time, output, err)
+	}
+
+	var auditErr error
+	if r.Audit != nil {
+		auditErr = r.Audit.Append(entry)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = append(r.log, entry)
+	if dryRun {
+		r.pending = append(r.pending, entry)
+	}
+	if auditErr != nil {
+		r.auditErr = auditErr
+	}
+	return output, err
+}
+
+// Watch passes through the wrapped client's snapshot events
+func (r *RecorderClient) Watch() (<-chan SnapperEvent, error) {
+	if watcher, ok := r.Inner.(SnapshotWatcher); ok {
+		return watcher.Watch()
+	}
+	return nil, fmt.Errorf("backend cannot watch for snapshot changes")
+}
+
+// QgroupSizes passes through to a wrapped client that can read qgroups
+func (r *RecorderClient) QgroupSizes(snaps []Snapshot) ([]Snapshot, []string) {
+	return readQgroupSizes(r.Inner, snaps)
+}
+
+// ConfigExclusive passes through to a wrapped client that can read qgroups
+func (r *RecorderClient) ConfigExclusive(snaps []Snapshot) map[string]int64 {
+	return readConfigExclusive(r.Inner, snaps)
+}
+
+// List passes through
+func (r *RecorderClient) List() ([]Snapshot, error) {
+	return r.Inner.List()
+}
+
+// ListConfig passes through
+func (r *RecorderClient) ListConfig(config string) ([]Snapshot, error) {
+	return r.Inner.ListConfig(config)
+}
+
+// Get passes through
+func (r *RecorderClient) Get(config string, number int) (Snapshot, error) {
+	return r.Inner.Get(config, number)
+}
+
+// Status passes through; it changes nothing
+func (r *RecorderClient) Status(config string, from, to int) (string, error) {
+	return r.Inner.Status(config, from, to)
+}
+
+// Diff passes through; it changes nothing
+func (r *RecorderClient) Diff(config string, from, to int, path string) (string, error) {
+	return r.Inner.Diff(config, from, to, path)
+}
+
+// ListConfigs passes through
+func (r *RecorderClient) ListConfigs() ([]ConfigInfo, error) {
+	return r.Inner.ListConfigs()
+}
+
+// GetConfig passes through
+func (r *RecorderClient) GetConfig(config string) (ConfigInfo, error) {
+	return r.Inner.GetConfig(config)
+}
+
+// Describe passes through; the wrapped backend knows what it runs
+func (r *RecorderClient) Describe(op Operation) Invocation {
+	return r.Inner.Describe(op)
+}
+
+// entry is the audit entry of an operation, described by the wrapped backend
+func (r *RecorderClient) entry(op Operation) AuditEntry {
+	return operationEntry(op, r.Inner.Describe(op))
+}
+
+// Delete records the delete
+func (r *RecorderClient) Delete(config string, numbers []int) (string, error) {
+	return r.record(r.entry(Operation{Kind: "delete", Config: config, Numbers: numbers}), func() (string, error) {
+		return r.Inner.Delete(config, numbers)
+	})
+}
+
+// Rollback records the rollback
+func (r *RecorderClient) Rollback(config string, number int) (string, error) {
+	return r.record(r.entry(Operation{Kind: "rollback", Config: config, Numbers: []int{number}}), func() (string, error) {
+		return r.Inner.Rollback(config, number)
+	})
+}
+
+// UndoChange records the undochange
+func (r *RecorderClient) UndoChange(config string, from, to int, paths []string) (string, error) {
+	op := Operation{Kind: "undochange", Config: config, Numbers: []int{from, to}, Paths: paths}
+	return r.record(r.entry(op), func() (string, error) {
+		return r.Inner.UndoChange(config, from, to, paths)
+	})
+}
+
+// Create records the create; a dry run creates nothing and returns 0
+func (r *RecorderClient) Create(opts CreateOptions) (int, error) {
+	var number int
+	_, err := r.record(r.entry(Operation{Kind: "create", Config: opts.Config, Create: opts}), func() (string, error) {
+		var err error
+		number, err = r.Inner.Create(opts)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(number), nil
+	})
+	return number, err
+}
+
+// Modify records the modify, with the userdata keys the CLI would clear
+func (r *RecorderClient) Modify(config string, number int, opts ModifyOptions) (string, error) {
+	op := Operation{Kind: "modify", Config: config, Numbers: []int{number}, Modify: opts}
+	return r.record(r.entry(op), func() (string, error) {
+		return r.Inner.Modify(config, number, opts)
+	})
+}
+
+// SetConfig records the set-config
+func (r *RecorderClient) SetConfig(config string, values map[string]string) (string, error) {
+	return r.record(r.entry(Operation{Kind: "set-config", Config: config, Values: values}), func() (string, error) {
+		return r.Inner.SetConfig(config, values)
+	})
+}
+
+// CreateConfig records the create-config
+func (r *RecorderClient) CreateConfig(opts ConfigOptions) (string, error) {
+	return r.record(r.entry(Operation{Kind: "create-config", Config: opts.Name, NewConfig: opts}), func() (string, error) {
+		return r.Inner.CreateConfig(opts)
+	})
+}
+
+// DeleteConfig records the delete-config
+func (r *RecorderClient) DeleteConfig(config string) (string, error) {
+	return r.record(r.entry(Operation{Kind: "delete-config", Config: config}), func() (string, error) {
+		return r.Inner.DeleteConfig(config)
+	})
+}
+
+// Cleanup records the cleanup
+func (r *RecorderClient) Cleanup(config, algorithm string) (string, error) {
+	return r.record(r.entry(Operation{Kind: "cleanup", Config: config, Algorithm: algorithm}), func() (string, error) {
+		return r.Inner.Cleanup(config, algorithm)
+	})
+}
+
+// SetupQuota records the setup-quota
+func (r *RecorderClient) SetupQuota(config string) (string, error) {
+	return r.record(r.entry(Operation{Kind: "setup-quota", Config: config}), func() (string, error) {
+		return r.Inner.SetupQuota(config)
+	})
+}
+
+// handleDryRunResult reports the commands an action recorded instead of
+// running, in place of its usual outcome
+func (m UIState) handleDryRunResult(commands []AuditEntry) (tea.Model, tea.Cmd) {
+	m.ActionInProgress = false
+	lines := []string{"Dry run, nothing was changed. Would run:"}
+	listed := commands
+	if len(commands) > maxDryRunListed {
+		listed = commands[:maxDryRunListed-1]
+	}
+	for _, command := range listed {
+		lines = append(lines, command.command())
+	}
+	if len(listed) < len(commands) {
+		lines = append(lines, fmt.Sprintf("… and %d more (L: audit history)", len(commands)-len(listed)))
+	}
+	m.ActionMessage = strings.Join(lines, "\n")
+	m.Status = fmt.Sprintf("Dry run: %s", commands[0].command())
+	if len(commands) > 1 {
+		m.Status = fmt.Sprintf("Dry run: %d commands recorded", len(commands))
+	}
+	return m, nil
+}
+
+// headerTitle is the app header, flagging dry-run mode
+func (m UIState) headerTitle() string {
+	if m.Recorder != nil && m.Recorder.DryRun() {
+		return "Snapper TUI — DRY RUN (nothing is changed)"
+	}
+	return "Snapper TUI"
+}
+
+// toggleDryRun switches dry-run mode from the table
+func (m *UIState) toggleDryRun() {
+	on := !m.Recorder.DryRun()
+	m.Recorder.SetDryRun(on)
+	if on {
+		m.Status = "Dry run on: actions are recorded, not run (n: off)"
+	} else {
+		m.Status = "Dry run off: actions run snapper"
+	}
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeClient is an in-memory SnapperClient for running the UI without root or
+// btrfs. It is no QgroupReader, so snapshots keep the qgroup sizes they were
+// seeded with.
+type FakeClient struct {
+	mu sync.Mutex
+
+	// Snapshots is the backing snapshot list, mutated by Delete
+	Snapshots []Snapshot
+	// Failures makes an operation ("list", "delete", "rollback", "status",
+	// "set-config", ...) return the error; "undochange:<path>" fails undoing a
+	// single file and "delete:<config>" deleting from a single config
+	Failures map[string]error
+	// Latencies delays an operation before it returns
+	Latencies map[string]time.Duration
+	// StatusOutputs holds canned status output keyed by "config:from..to"
+	StatusOutputs map[string]string
+	// DiffOutputs holds canned diff output keyed by "config:from..to:path"
+	DiffOutputs map[string]string
+	// Configs holds the settings of each config, seeded with snapper's defaults
+	Configs map[string]ConfigInfo
+	// Calls records every invocation as a snapper-like command line
+	Calls []string
+
+	events chan SnapperEvent
+}
+
+// newFakeClient creates a fake backend seeded with a copy of the given snapshots
+func newFakeClient(snaps []Snapshot) *FakeClient {
+	f := &FakeClient{
+		Snapshots:     append([]Snapshot(nil), snaps...),
+		Failures:      map[string]error{},
+		Latencies:     map[string]time.Duration{},
+		StatusOutputs: map[string]string{},
+		DiffOutputs:   map[string]string{},
+		Configs:       map[string]ConfigInfo{},
+	}
+	for _, snap := range snaps {
+		if _, ok := f.Configs[snap.Config]; !ok {
+			f.Configs[snap.Config] = defaultConfigInfo(snap.Config, snap.Subvolume)
+		}
+	}
+	return f
+}
+
+// defaultConfigInfo is a config as created from snapper's default template
+func defaultConfigInfo(name, subvolume string) ConfigInfo {
+	return ConfigInfo{Name: name, Subvolume: subvolume, Values: map[string]string{
+		"SUBVOLUME":                subvolume,
+		"FSTYPE":                   "btrfs",
+		"QGROUP":                   "",
+		"SPACE_LIMIT":              "0.5",
+		"FREE_LIMIT":               "0.2",
+		"ALLOW_USERS":              "",
+		"ALLOW_GROUPS":             "",
+		"SYNC_ACL":                 "no",
+		"BACKGROUND_COMPARISON":    "yes",
+		"NUMBER_CLEANUP":           "yes",
+		"NUMBER_MIN_AGE":           "1800",
+		"NUMBER_LIMIT":             "50",
+		"NUMBER_LIMIT_IMPORTANT":   "10",
+		"TIMELINE_CREATE":          "yes",
+		"TIMELINE_CLEANUP":         "yes",
+		"TIMELINE_MIN_AGE":         "1800",
+		"TIMELINE_LIMIT_HOURLY":    "10",
+		"TIMELINE_LIMIT_DAILY":     "10",
+		"TIMELINE_LIMIT_WEEKLY":    "0",
+		"TIMELINE_LIMIT_MONTHLY":   "10",
+		"TIMELINE_LIMIT_QUARTERLY": "0",
+		"TIMELINE_LIMIT_YEARLY":    "10",
+		"EMPTY_PRE_POST_CLEANUP":   "yes",
+		"EMPTY_PRE_POST_MIN_AGE":   "1800",
+	}}
+}
+
+// List returns a copy of the in-memory snapshots
+func (f *FakeClient) List() ([]Snapshot, error) {
+	if err := f.begin("list", "list"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Snapshot(nil), f.Snapshots...), nil
+}
+
+// ListConfig returns a copy of the in-memory snapshots of one config
+func (f *FakeClient) ListConfig(config string) ([]Snapshot, error) {
+	if err := f.begin("list", fmt.Sprintf("-c %s list", config)); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var snaps []Snapshot
+	for _, snap := range f.Snapshots {
+		if snap.Config == config {
+			snaps = append(snaps, snap)
+		}
+	}
+	return snaps, nil
+}
+
+// Get returns one in-memory snapshot
+func (f *FakeClient) Get(config string, number int) (Snapshot, error) {
+	if err := f.begin("get", fmt.Sprintf("-c %s list %d", config, number)); err != nil {
+		return Snapshot{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return findSnapshot(f.Snapshots, config, number)
+}
+
+// Watch returns the channel on which Emit and mutating calls publish events
+func (f *FakeClient) Watch() (<-chan SnapperEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.events == nil {
+		f.events = make(chan SnapperEvent, 64)
+	}
+	return f.events, nil
+}
+
+// Emit publishes an event as snapperd would, e.g. for a background timeline snapshot
+func (f *FakeClient) Emit(ev SnapperEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.emitLocked(ev)
+}
+
+// Delete removes the numbered snapshots of a config, failing if any is unknown
+func (f *FakeClient) Delete(config string, numbers []int) (string, error) {
+	parts := make([]string, 0, len(numbers))
+	for _, n := range numbers {
+		parts = append(parts, fmt.Sprint(n))
+	}
+	if err := f.begin("delete", fmt.Sprintf("-c %s delete %s", config, strings.Join(parts, " "))); err != nil {
+		return err.Error(), err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.Failures["delete:"+config]; err != nil {
+		return err.Error(), err
+	}
+	doomed := map[int]bool{}
+	for _, n := range numbers {
+		if f.indexOf(config, n) < 0 {
+			err := fmt.Errorf("snapshot '%d' not found", n)
+			return err.Error(), err
+		}
+		doomed[n] = true
+	}
+	kept := f.Snapshots[:0]
+	for _, snap := range f.Snapshots {
+		if snap.Config == config && doomed[snap.Number] {
+			continue
+		}
+		kept = append(kept, snap)
+	}
+	f.Snapshots = kept
+	f.emitLocked(SnapperEvent{Kind: EventSnapshotsDeleted, Config: config, Numbers: numbers})
+	return "", nil
+}
+
+// Rollback marks the snapshot as the default one of its config
+func (f *FakeClient) Rollback(config string, number int) (string, error) {
+	if err := f.begin("rollback", fmt.Sprintf("-c %s rollback %d", config, number)); err != nil {
+		return err.Error(), err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.indexOf(config, number) < 0 {
+		err := fmt.Errorf("snapshot '%d' not found", number)
+		return err.Error(), err
+	}
+	for i := range f.Snapshots {
+		if f.Snapshots[i].Config == config {
+			f.Snapshots[i].Default = f.Snapshots[i].Number == number
+		}
+	}
+	return fmt.Sprintf("Setting default subvolume to snapshot %d.", number), nil
+}
+
+// Status returns the canned output for the range, or an empty comparison
+func (f *FakeClient) Status(config string, from, to int) (string, error) {
+	if err := f.begin("status", fmt.Sprintf("-c %s status %d..%d", config, from, to)); err != nil {
+		return err.Error(), err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.StatusOutputs[fmt.Sprintf("%s:%d..%d", config, from, to)], nil
+}
+
+// Diff returns the canned output for the file and range, or no differences
+func (f *FakeClient) Diff(config string, from, to int, path string) (string, error) {
+	if err := f.begin("diff", fmt.Sprintf("-c %s diff %d..%d %s", config, from, to, path)); err != nil {
+		return err.Error(), err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.DiffOutputs[fmt.Sprintf("%s:%d..%d:%s", config, from, to, path)], nil
+}
+
+// UndoChange records the call and answers like "snapper --verbose
+// undochange": a line per file, "failed to modify <path>" for the paths that
+// have a failure set, and an error if any did
+func (f *FakeClient) UndoChange(config string, from, to int, paths []string) (string, error) {
+	call := fmt.Sprintf("-c %s undochange %d..%d %s", config, from, to, strings.Join(paths, " "))
+	if err := f.begin("undochange", call); err != nil {
+		return err.Error(), err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var lines []string
+	failed := 0
+	for _, path := range paths {
+		if f.Failures["undochange:"+path] != nil {
+			lines = append(lines, "failed to modify "+path)
+			failed++
+		} else {
+			lines = append(lines, "modifying "+path)
+		}
+	}
+	if failed > 0 {
+		return strings.Join(lines, "\n"), fmt.Errorf("undochange failed for %d file(s)", failed)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Create appends a snapshot numbered after the highest one of its config
+func (f *FakeClient) Create(opts CreateOptions) (int, error) {
+	if err := f.begin("create", fmt.Sprintf("-c %s create --type %s", opts.Config, opts.Type)); err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	next := 1
+	subvolume := "/"
+	for _, snap := range f.Snapshots {
+		if snap.Config == opts.Config {
+			next = max(next, snap.Number+1)
+			subvolume = snap.Subvolume
+		}
+	}
+
+	snap := Snapshot{
+		Config:       opts.Config,
+		Subvolume:    subvolume,
+		Number:       next,
+		SnapshotType: opts.Type,
+		Date:         time.Now().Format("2006-01-02 15:04:05"),
+		User:         "root",
+		Cleanup:      opts.Cleanup,
+		Description:  opts.Description,
+		Userdata:     opts.Userdata,
+	}
+	if opts.Type == "post" {
+		i := f.indexOf(opts.Config, opts.PreNumber)
+		if i < 0 || f.Snapshots[i].SnapshotType != "pre" {
+			return 0, fmt.Errorf("pre snapshot '%d' not found", opts.PreNumber)
+		}
+		snap.PreNumber = toOptionalInt(opts.PreNumber)
+		f.Snapshots[i].PostNumber = toOptionalInt(next)
+	}
+	f.Snapshots = append(f.Snapshots, snap)
+	f.emitLocked(SnapperEvent{Kind: EventSnapshotCreated, Config: opts.Config, Numbers: []int{next}})
+	return next, nil
+}
+
+// Modify replaces the metadata of an in-memory snapshot
+func (f *FakeClient) Modify(config string, number int, opts ModifyOptions) (string, error) {
+	if err := f.begin("modify", fmt.Sprintf("-c %s modify %d", config, number)); err != nil {
+		return err.Error(), err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.indexOf(config, number)
+	if i < 0 {
+		err := fmt.Errorf("snapshot '%d' not found", number)
+		return err.Error(), err
+	}
+	f.Snapshots[i].Description = opts.Description
+	f.Snapshots[i].Cleanup = opts.Cleanup
+	f.Snapshots[i].Userdata = opts.Userdata
+	f.emitLocked(SnapperEvent{Kind: EventSnapshotModified, Config: config, Numbers: []int{number}})
+	return "", nil
+}
+
+// ListConfigs returns the in-memory configs sorted by name
+func (f *FakeClient) ListConfigs() ([]ConfigInfo, error) {
+	if err := f.begin("list-configs", "list-configs"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	configs := make([]ConfigInfo, 0, len(f.Configs))
+	for _, cfg := range f.Configs {
+		configs = append(configs, ConfigInfo{Name: cfg.Name, Subvolume: cfg.Subvolume})
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	return configs, nil
+}
+
+// GetConfig returns a copy of one in-memory config
+func (f *FakeClient) GetConfig(config string) (ConfigInfo, error) {
+	if err := f.begin("get-config", fmt.Sprintf("-c %s get-config", config)); err != nil {
+		return ConfigInfo{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cfg, ok := f.Configs[config]
+	if !ok {
+		return ConfigInfo{}, fmt.Errorf("unknown config '%s'", config)
+	}
+	values := make(map[string]string, len(cfg.Values))
+	for key, val := range cfg.Values {
+		values[key] = val
+	}
+	cfg.Values = values
+	return cfg, nil
+}
+
+// SetConfig updates settings of an in-memory config
+func (f *FakeClient) SetConfig(config string, values map[string]string) (string, error) {
+	call := fmt.Sprintf("-c %s set-config %s", config, strings.Join(formatConfigValues(values), " "))
+	if err := f.begin("set-config", call); err != nil {
+		return err.Error(), err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cfg, ok := f.Configs[config]
+	if !ok {
+		err := fmt.Errorf("unknown config '%s'", config)
+		return err.Error(), err
+	}
+	for key, val := range values {
+		cfg.Values[key] = val
+	}
+	return "", nil
+}
+
+// CreateConfig adds an in-memory config with the default settings
+func (f *FakeClient) CreateConfig(opts ConfigOptions) (string, error) {
+	if err := f.begin("create-config", strings.Join(createConfigArgs(opts), " ")); err != nil {
+		return err.Error(), err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.Configs[opts.Name]; ok {
+		err := fmt.Errorf("config '%s' already exists", opts.Name)
+		return err.Error(), err
+	}
+	cfg := defaultConfigInfo(opts.Name, opts.Subvolume)
+	cfg.Values["FSTYPE"] = opts.FSType
+	f.Configs[opts.Name] = cfg
+	return "", nil
+}
+
+// DeleteConfig removes an in-memory config and its snapshots
+func (f *FakeClient) DeleteConfig(config string) (string, error) {
+	if err := f.begin("delete-config", fmt.Sprintf("-c %s delete-config", config)); err != nil {
+		return err.Error(), err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.Configs[config]; !ok {
+		err := fmt.Errorf("unknown config '%s'", config)
+		return err.Error(), err
+	}
+	delete(f.Configs, config)
+	kept := f.Snapshots[:0]
+	var removed []int
+	for _, snap := range f.Snapshots {
+		if snap.Config == config {
+			removed = append(removed, snap.Number)
+			continue
+		}
+		kept = append(kept, snap)
+	}
+	f.Snapshots = kept
+	if len(removed) > 0 {
+		f.emitLocked(SnapperEvent{Kind: EventSnapshotsDeleted, Config: config, Numbers: removed})
+	}
+	return "", nil
+}
+
+// Cleanup deletes what the cleanup rules select from the in-memory
+// snapshots; pre/post pairs count as empty unless a status output is canned
+func (f *FakeClient) Cleanup(config, algorithm string) (string, error) {
+	if err := f.begin("cleanup", fmt.Sprintf("-c %s cleanup %s", config, algorithm)); err != nil {
+		return err.Error(), err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cfg, ok := f.Configs[config]
+	if !ok {
+		err := fmt.Errorf("unknown config '%s'", config)
+		return err.Error(), err
+	}
+	empty := map[int]bool{}
+	for pre, post := range emptyPrePostPairs(f.Snapshots, config) {
+		empty[pre] = strings.TrimSpace(f.StatusOutputs[fmt.Sprintf("%s:%d..%d", config, pre, post)]) == ""
+	}
+	plan := simulateCleanup(f.Snapshots, cfg, algorithm, empty, time.Now())
+	if len(plan.Doomed) == 0 {
+		return "", nil
+	}
+	var numbers []int
+	kept := f.Snapshots[:0]
+	for _, snap := range f.Snapshots {
+		if plan.Dooms(snap.Key()) {
+			numbers = append(numbers, snap.Number)
+			continue
+		}
+		kept = append(kept, snap)
+	}
+	f.Snapshots = kept
+	f.emitLocked(SnapperEvent{Kind: EventSnapshotsDeleted, Config: config, Numbers: numbers})
+	return "", nil
+}
+
+// SetupQuota sets the QGROUP of an in-memory config the way snapper does
+func (f *FakeClient) SetupQuota(config string) (string, error) {
+	if err := f.begin("setup-quota", fmt.Sprintf("-c %s setup-quota", config)); err != nil {
+		return err.Error(), err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cfg, ok := f.Configs[config]
+	if !ok {
+		err := fmt.Errorf("unknown config '%s'", config)
+		return err.Error(), err
+	}
+	if cfg.Values["QGROUP"] != "" {
+		err := fmt.Errorf("quota already set up")
+		return err.Error(), err
+	}
+	cfg.Values["QGROUP"] = "1/0"
+	return "", nil
+}
+
+// Describe returns the snapper command line of an operation, as the CLI
+// backend the fake stands in for would run it
+func (f *FakeClient) Describe(op Operation) Invocation {
+	return newExecClient().Describe(op)
+}
+
+// begin records the call, applies the configured latency and returns any injected failure
+func (f *FakeClient) begin(op, call string) error {
+	f.mu.Lock()
+	f.Calls = append(f.Calls, call)
+	delay := f.Latencies[op]
+	err := f.Failures[op]
+	f.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return err
+}
+
+// emitLocked publishes an event if someone is watching, dropping it when the
+// buffer is full; callers must hold the lock
+func (f *FakeClient) emitLocked(ev SnapperEvent) {
+	if f.events == nil {
+		return
+	}
+	select {
+	case f.events <- ev:
+	default:
+	}
+}
+
+// indexOf finds a snapshot by config and number; callers must hold the lock
+func (f *FakeClient) indexOf(config string, number int) int {
+	for i, snap := range f.Snapshots {
+		if snap.Config == config && snap.Number == number {
+			return i
+		}
+	}
+	return -1
+}
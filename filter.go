@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SnapshotFilter is a parsed filter query; a snapshot is shown when it
+// matches every term
+type SnapshotFilter struct {
+	Query string
+	terms []func(Snapshot) bool
+}
+
+// filterOperators in the order they are tried, two-character ones first
+var filterOperators = []string{">=", "<=", "!=", ">", "<", "=", ":", "~"}
+
+// filterFieldPattern is what may precede an operator to make a term a field
+// predicate rather than free text
+var filterFieldPattern = regexp.MustCompile(`^[a-z#]+(\.[^\s"]+)?$`)
+
+// filterSizePattern is a size such as 512, 1G, 1.5GiB or 300mb
+var filterSizePattern = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)\s*([kmgtp]?)(i?b)?$`)
+
+// filterDateLayouts are the date forms accepted by date comparisons
+var filterDateLayouts = []string{snapperDateLayout, "2006-01-02 15:04", "2006-01-02", "2006-01", "2006"}
+
+// Matches reports whether a snapshot passes the filter; a nil filter passes
+// everything
+func (f *SnapshotFilter) Matches(snap Snapshot) bool {
+	if f == nil {
+		return true
+	}
+	for _, term := range f.terms {
+		if !term(snap) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the snapshots passing the filter, in order
+func (f *SnapshotFilter) apply(snaps []Snapshot) []Snapshot {
+	if f == nil {
+		return snaps
+	}
+	visible := make([]Snapshot, 0, len(snaps))
+	for _, snap := range snaps {
+		if f.Matches(snap) {
+			visible = append(visible, snap)
+		}
+	}
+	return visible
+}
+
+// parseFilter parses a query such as
+//
+//	type:pre cleanup:number user:root size>1G date>2025-01-01 desc~"zypp" data.important=yes
+//
+// Words without an operator match any column as case-insensitive text; a
+// leading "-" negates a term. An empty query gives a nil filter.
+func parseFilter(query string) (*SnapshotFilter, error) {
+	words, err := splitFilterQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, nil
+	}
+	f := &SnapshotFilter{Query: strings.TrimSpace(query)}
+	for _, word := range words {
+		negate := strings.HasPrefix(word, "-") && len(word) > 1
+		if negate {
+			word = word[1:]
+		}
+		term, err := parseFilterTerm(word)
+		if err != nil {
+			return nil, err
+		}
+		if negate {
+			positive := term
+			term = func(snap Snapshot) bool { return !positive(snap) }
+		}
+		f.terms = append(f.terms, term)
+	}
+	return f, nil
+}
+
+// splitFilterQuery splits a query at spaces outside double quotes, keeping
+// the quotes for parseFilterTerm
+func splitFilterQuery(query string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			word.WriteRune(r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// parseFilterTerm turns one word into a predicate: "field<op>value" when the
+// word starts with a field name and an operator, free text otherwise
+func parseFilterTerm(word string) (func(Snapshot) bool, error) {
+	at := strings.IndexAny(word, ":=!<>~")
+	if at > 0 && filterFieldPattern.MatchString(word[:at]) {
+		field := word[:at]
+		op := word[at : at+1]
+		for _, candidate := range filterOperators {
+			if strings.HasPrefix(word[at:], candidate) {
+				op = candidate
+				break
+			}
+		}
+		return fieldPredicate(field, op, strings.ReplaceAll(word[at+len(op):], `"`, ""))
+	}
+	text := strings.ToLower(strings.ReplaceAll(word, `"`, ""))
+	return func(snap Snapshot) bool {
+		return strings.Contains(strings.ToLower(snapshotText(snap)), text)
+	}, nil
+}
+
+// snapshotText is every column of a snapshot, for free-text matching
+func snapshotText(snap Snapshot) string {
+	return strings.Join([]string{
+		snap.Config, strconv.Itoa(snap.Number), snap.SnapshotType, snap.Date,
+		snap.User, snap.Cleanup, snap.Description, flattenUserData(snap.Userdata),
+	}, "\x00")
+}
+
+// fieldPredicate builds the predicate of one "field<op>value" term
+func fieldPredicate(field, op, value string) (func(Snapshot) bool, error) {
+	if key, ok := strings.CutPrefix(field, "data."); ok {
+		return textPredicate(field, op, value, false, func(s Snapshot) string { return s.Userdata[key] })
+	}
+	switch field {
+	case "#", "num", "number":
+		return intPredicate(field, op, value, func(s Snapshot) *int { return &s.Number })
+	case "pre":
+		return intPredicate(field, op, value, func(s Snapshot) *int { return s.PreNumber })
+	case "post":
+		return intPredicate(field, op, value, func(s Snapshot) *int { return s.PostNumber })
+	case "type":
+		return textPredicate(field, op, value, false, func(s Snapshot) string { return s.SnapshotType })
+	case "user":
+		return textPredicate(field, op, value, false, func(s Snapshot) string { return s.User })
+	case "cleanup":
+		return textPredicate(field, op, value, false, func(s Snapshot) string { return s.Cleanup })
+	case "config":
+		return textPredicate(field, op, value, false, func(s Snapshot) string { return s.Config })
+	case "desc", "description":
+		return textPredicate(field, op, value, true, func(s Snapshot) string { return s.Description })
+	case "size":
+		return sizePredicate(op, value)
+	case "date":
+		return datePredicate(op, value)
+	}
+	return nil, fmt.Errorf("unknown filter field %q; use type, user, cleanup, config, desc, number, pre, post, size, date or data.<key>", field)
+}
+
+// textPredicate compares a text field: ":" and "=" match it exactly (":"
+// matches a substring when contains is set), "!=" excludes it and "~" is a
+// regular expression; all ignore case
+func textPredicate(field, op, value string, contains bool, get func(Snapshot) string) (func(Snapshot) bool, error) {
+	switch op {
+	case ":", "=", "!=":
+		if op == ":" && contains {
+			value = strings.ToLower(value)
+			return func(s Snapshot) bool { return strings.Contains(strings.ToLower(get(s)), value) }, nil
+		}
+		return func(s Snapshot) bool { return strings.EqualFold(get(s), value) == (op != "!=") }, nil
+	case "~":
+		re, err := regexp.Compile("(?i)" + value)
+		if err != nil {
+			return nil, fmt.Errorf("%s~%s: %v", field, value, err)
+		}
+		return func(s Snapshot) bool { return re.MatchString(get(s)) }, nil
+	}
+	return nil, fmt.Errorf("%s cannot be compared with %s; use :, =, != or ~", field, op)
+}
+
+// intPredicate compares a number field; unset numbers never match
+func intPredicate(field, op, value string, get func(Snapshot) *int) (func(Snapshot) bool, error) {
+	want, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s%s%s: not a number", field, op, value)
+	}
+	if op == "~" {
+		return nil, fmt.Errorf("%s cannot be matched with ~", field)
+	}
+	return func(s Snapshot) bool {
+		n := get(s)
+		return n != nil && compareWith(op, compareInt64(int64(*n), int64(want)))
+	}, nil
+}
+
+// sizePredicate compares the space a snapshot holds; unknown sizes never match
+func sizePredicate(op, value string) (func(Snapshot) bool, error) {
+	want, err := parseFilterSize(value)
+	if err != nil {
+		return nil, err
+	}
+	if op == "~" {
+		return nil, fmt.Errorf("size cannot be matched with ~")
+	}
+	return func(s Snapshot) bool {
+		size := snapshotBytes(s)
+		return size != nil && compareWith(op, compareInt64(*size, want))
+	}, nil
+}
+
+// parseFilterSize reads a size such as 512, 100K, 1G or 1.5GiB; units are
+// powers of 1024 like the Size column
+func parseFilterSize(value string) (int64, error) {
+	match := filterSizePattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, fmt.Errorf("size %q: use a number with an optional K, M, G, T or P unit", value)
+	}
+	n, _ := strconv.ParseFloat(match[1], 64)
+	for _, unit := range "kmgtp" {
+		if match[2] == "" {
+			break
+		}
+		n *= 1024
+		if strings.EqualFold(match[2], string(unit)) {
+			break
+		}
+	}
+	return int64(n), nil
+}
+
+// datePredicate matches dates: ":" and "=" match a prefix such as 2025-11 or
+// 2025-11-19, "!=" excludes it and the comparisons take a date or date and
+// time
+func datePredicate(op, value string) (func(Snapshot) bool, error) {
+	switch op {
+	case ":", "=", "!=":
+		return func(s Snapshot) bool { return strings.HasPrefix(s.Date, value) == (op != "!=") }, nil
+	case "~":
+		return nil, fmt.Errorf("date cannot be matched with ~; use date:%s for a prefix", value)
+	}
+	var want time.Time
+	var err error
+	for _, layout := range filterDateLayouts {
+		if want, err = time.ParseInLocation(layout, value, time.Local); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("date %q: use YYYY-MM-DD, optionally with HH:MM[:SS]", value)
+	}
+	return func(s Snapshot) bool {
+		t, ok := snapshotTime(s)
+		return ok && compareWith(op, t.Compare(want))
+	}, nil
+}
+
+// compareInt64 returns -1, 0 or 1 like strings.Compare
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareWith applies a comparison operator to the result of a comparison
+func compareWith(op string, cmp int) bool {
+	switch op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	}
+	return cmp == 0
+}
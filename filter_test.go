@@ -0,0 +1,179 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// filterSnapshots covers every field the filter language knows
+var filterSnapshots = []Snapshot{
+	{Config: "root", Number: 1, SnapshotType: "single", Date: "2024-12-31 23:00:00", User: "root", Cleanup: "number", Description: "before upgrade", Exclusive: ptrInt64(2 << 30)},
+	{Config: "root", Number: 2, SnapshotType: "pre", PostNumber: ptrInt(3), Date: "2025-01-10 09:00:00", User: "root", Cleanup: "number", Description: "zypp(zypper)", Userdata: map[string]string{"important": "yes"}, Exclusive: ptrInt64(1536 << 20)},
+	{Config: "root", Number: 3, SnapshotType: "post", PreNumber: ptrInt(2), Date: "2025-01-10 09:05:00", User: "root", Cleanup: "number", Description: "", Userdata: map[string]string{"important": "no"}, Exclusive: ptrInt64(100 << 20)},
+	{Config: "home", Number: 4, SnapshotType: "single", Date: "2025-02-01 00:00:00", User: "alice", Cleanup: "timeline", Description: "timeline", UsedSpace: ptrInt64(3 << 30)},
+	{Config: "home", Number: 5, SnapshotType: "single", Date: "2025-02-02 12:00:00", User: "alice", Cleanup: "", Description: "Manual \"quoted\" note"},
+}
+
+// filterNumbers lists the numbers of the snapshots a query keeps
+func filterNumbers(t *testing.T, query string) string {
+	t.Helper()
+	f, err := parseFilter(query)
+	if err != nil {
+		t.Fatalf("parseFilter(%q): %v", query, err)
+	}
+	var numbers []string
+	for _, snap := range f.apply(filterSnapshots) {
+		numbers = append(numbers, strconv.Itoa(snap.Number))
+	}
+	return strings.Join(numbers, " ")
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "1 2 3 4 5"},
+		{"   ", "1 2 3 4 5"},
+		// The example from the request: one pre snapshot passes every term
+		{`type:pre cleanup:number user:root size>1G date>2025-01-01 desc~"zypp" data.important=yes`, "2"},
+
+		// Free text searches every column, ignoring case
+		{"ALICE", "4 5"},
+		{"upgrade", "1"},
+		{`"manual "`, "5"},
+		{"important", "2 3"}, // userdata keys count as text
+		{"yes", "2"},
+
+		// Negation
+		{"-type:single", "2 3"},
+		{"-alice", "1 2 3"},
+		{"-data.important=yes", "1 3 4 5"},
+		{"-", "1 2 3 4 5"}, // a lone dash is free text every row contains in its date
+
+		// Text fields
+		{"config=HOME", "4 5"},
+		{"config!=home", "1 2 3"},
+		{"desc:ZYPP", "2"},
+		{"desc=zypp", ""},
+		{`desc:"before upgrade"`, "1"},
+		{"desc~^$", "3"},
+		{"cleanup:", "5"},
+		{"user~^a", "4 5"},
+
+		// Userdata
+		{"data.important=yes", "2"},
+		{"data.important!=yes", "1 3 4 5"},
+		{"data.important~.", "2 3"},
+
+		// Numbers
+		{"#>3", "4 5"},
+		{"number<=2", "1 2"},
+		{"num!=1", "2 3 4 5"},
+		{"pre=2", "3"},
+		{"post>0", "2"},
+
+		// Sizes are powers of 1024; the exclusive size wins over used space
+		{"size>1G", "1 2 4"},
+		{"size>1.5GiB", "1 4"},
+		{"size>=1.5GiB", "1 2 4"},
+		{"size<200mb", "3"},
+		{"size>=3221225472", "4"},
+
+		// Date comparisons take a start of period, prefixes a period
+		{"date>2025-01", "2 3 4 5"},
+		{"date:2025-01", "2 3"},
+		{"date=2025-02-01", "4"},
+		{"date!=2025", "1"},
+		{`date<"2025-01-10 09:05"`, "1 2"},
+		{`date>="2025-01-10 09:05:00"`, "3 4 5"},
+		{"date>=2025-01-10 09:05:00", "3"}, // unquoted, the time is a second, free-text term
+	}
+	for _, tt := range tests {
+		if got := filterNumbers(t, tt.query); got != tt.want {
+			t.Errorf("%q kept %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{`desc:"open`, "unterminated quote"},
+		{`a "b c`, "unterminated quote"},
+		{"colour:red", `unknown filter field "colour"`},
+		{"desc~(", "desc~(:"},
+		{"data.key~[", "data.key~[:"},
+		{"type>pre", "type cannot be compared with >"},
+		{"number:x", "number:x: not a number"},
+		{"pre~1", "pre cannot be matched with ~"},
+		{"size>lots", `size "lots"`},
+		{"size>1X", `size "1X"`},
+		{"size~1G", "size cannot be matched with ~"},
+		{"date~2025", "date cannot be matched with ~"},
+		{"date>yesterday", `date "yesterday"`},
+		{"date>2025-13", `date "2025-13"`},
+	}
+	for _, tt := range tests {
+		_, err := parseFilter(tt.query)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseFilter(%q) error = %v, want %q", tt.query, err, tt.err)
+		}
+	}
+}
+
+func TestSplitFilterQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"  a \t b  ", []string{"a", "b"}},
+		{`desc~"two words" user:root`, []string{`desc~"two words"`, "user:root"}},
+		{`"a b"c d`, []string{`"a b"c`, "d"}},
+	}
+	for _, tt := range tests {
+		got, err := splitFilterQuery(tt.query)
+		if err != nil || strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("splitFilterQuery(%q) = %q, %v; want %q", tt.query, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseFilterSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"512", 512},
+		{"100K", 100 << 10},
+		{"1m", 1 << 20},
+		{"1G", 1 << 30},
+		{"1.5GiB", 3 << 29},
+		{"300mb", 300 << 20},
+		{"2 T", 2 << 40},
+		{"1P", 1 << 50},
+		{"7b", 7},
+	}
+	for _, tt := range tests {
+		got, err := parseFilterSize(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseFilterSize(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "G", "1.5.2G", "-1G", "1GG"} {
+		if _, err := parseFilterSize(bad); err == nil {
+			t.Errorf("parseFilterSize(%q) accepted", bad)
+		}
+	}
+}
+
+func TestNilFilterMatchesEverything(t *testing.T) {
+	var f *SnapshotFilter
+	if !f.Matches(filterSnapshots[0]) || len(f.apply(filterSnapshots)) != len(filterSnapshots) {
+		t.Error("a nil filter hides snapshots")
+	}
+}
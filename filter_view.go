@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// focusOrder is the Tab cycle of the main screen
+var focusOrder = []string{"filter", "table", "restore", "delete", "status"}
+
+// newFilterInput creates the one-line input of the filter bar
+func newFilterInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = ""
+	input.Placeholder = "/ to filter: text, type:pre, user:root, size>1G, date>2025-01-01, desc~zypp, data.key=value"
+	input.CharLimit = 256
+	return input
+}
+
+// applyFilter derives the visible rows from the snapshot list and keeps the
+// cursor within them
+func (m *UIState) applyFilter() {
+	m.Visible = m.Filter.apply(m.Snapshots)
+	if m.Cursor >= len(m.Visible) {
+		m.Cursor = max(0, len(m.Visible)-1)
+	}
+	m.ensureCursorVisible()
+}
+
+// setFilter switches to a new filter, keeping the cursor on the same snapshot
+// when it is still shown
+func (m *UIState) setFilter(filter *SnapshotFilter) {
+	var cursorKey *SnapshotKey
+	if snap := m.currentSnapshot(); snap != nil {
+		key := snap.Key()
+		cursorKey = &key
+	}
+	m.Filter = filter
+	m.applyFilter()
+	m.restoreCursor(cursorKey)
+	m.setActionPreview()
+}
+
+// clearFilter drops the filter and empties the filter bar
+func (m *UIState) clearFilter() {
+	m.FilterInput.SetValue("")
+	m.FilterErr = nil
+	m.setFilter(nil)
+	m.Status = "Filter cleared"
+}
+
+// visibleSelection is the part of the selection the filter shows; actions
+// never touch rows that are filtered out
+func (m UIState) visibleSelection() map[SnapshotKey]bool {
+	selected := make(map[SnapshotKey]bool)
+	for _, snap := range m.Visible {
+		if m.SelectedSnapshots[snap.Key()] {
+			selected[snap.Key()] = true
+		}
+	}
+	return selected
+}
+
+// focusElement moves the keyboard focus, starting or stopping the filter
+// bar's cursor as it enters or leaves
+func (m *UIState) focusElement(element string) tea.Cmd {
+	m.FocusedElement = element
+	if element == "filter" {
+		return m.FilterInput.Focus()
+	}
+	m.FilterInput.Blur()
+	return nil
+}
+
+// cycleFocus moves the focus forward or backward through focusOrder
+func (m *UIState) cycleFocus(step int) tea.Cmd {
+	for i, e := range focusOrder {
+		if e == m.FocusedElement {
+			return m.focusElement(focusOrder[(i+step+len(focusOrder))%len(focusOrder)])
+		}
+	}
+	return m.focusElement("table")
+}
+
+// handleFilterKey edits the filter bar, refiltering the table as the query
+// changes; a query that does not parse keeps the previous filter
+func (m UIState) handleFilterKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "tab":
+		return m, m.cycleFocus(1)
+	case "shift+tab":
+		return m, m.cycleFocus(-1)
+	case "enter":
+		return m, m.focusElement("table")
+	case "esc":
+		m.clearFilter()
+		return m, m.focusElement("table")
+	}
+
+	var cmd tea.Cmd
+	m.FilterInput, cmd = m.FilterInput.Update(msg)
+	filter, err := parseFilter(m.FilterInput.Value())
+	m.FilterErr = err
+	if err == nil {
+		m.setFilter(filter)
+	}
+	return m, cmd
+}
+
+// renderFilterBar draws the filter input with the number of rows it shows,
+// or why the query does not parse
+func (m UIState) renderFilterBar(width int) string {
+	label := "Filter: "
+	if m.FocusedElement == "filter" {
+		label = "Filter (enter: table, esc: clear): "
+	}
+	if m.ViewName != "" {
+		modified := ""
+		if m.viewModified() {
+			modified = "*"
+		}
+		label = fmt.Sprintf("[%s%s] %s", m.ViewName, modified, label)
+	}
+	info := ""
+	switch {
+	case m.FilterErr != nil:
+		info = "  ⚠ " + m.FilterErr.Error()
+	case m.Filter != nil:
+		info = fmt.Sprintf("  (%d of %d)", len(m.Visible), len(m.Snapshots))
+	}
+	m.FilterInput.Width = max(10, width-len(label)-len(info)-2)
+	if m.FilterErr != nil {
+		info = browserWarnStyle.Render(info)
+	}
+	return lipgloss.NewStyle().MaxWidth(width).Render(label + m.FilterInput.View() + info)
+}
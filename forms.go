@@ -0,0 +1,604 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// cleanupAlgorithms lists the cleanup algorithms snapper accepts; "" means none
+var cleanupAlgorithms = []string{"", "number", "timeline", "empty-pre-post"}
+
+// createFormInput holds the values bound to the create snapshot dialog
+type createFormInput struct {
+	Config      string
+	Type        string
+	PreNumber   string
+	Description string
+	Cleanup     string
+	Userdata    string
+}
+
+// keepValue is the select value meaning "leave the field as it is" in batch edits
+const keepValue = "\x00keep"
+
+// modifyFormInput holds the values bound to the modify dialog and its targets
+type modifyFormInput struct {
+	Description string
+	Cleanup     string
+	Userdata    string
+	Targets     []Snapshot
+}
+
+// routeToForm feeds a message to the open dialog. Input always goes to the
+// dialog; the app's own messages are left to the main Update, and so are
+// resizes, which the main Update hands on to the dialog too
+func (m UIState) routeToForm(msg tea.Msg) (bool, tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "esc" {
+			if m.FormKind != "summary" {
+				m.Status = "Cancelled"
+			}
+			m.closeForm()
+			m.setActionPreview()
+			return true, m, nil
+		}
+	case tea.MouseMsg:
+		return true, m, nil
+	case tea.WindowSizeMsg, TickMsg, RefreshTriggerMsg, RefreshResultMsg, ActionResultMsg, SnapshotPatchMsg,
+		DiffResultMsg, BrowserDirMsg, BrowserPreviewMsg, HistoryResultMsg, SearchProgressMsg, ConfigsLoadedMsg,
+		ConfigSavedMsg, ConfigChangedMsg, CleanupPreviewMsg, AuditLoadedMsg, ViewsSavedMsg, StatusSavedMsg:
+		return false, m, nil
+	}
+
+	model, cmd := m.Form.Update(msg)
+	if form, ok := model.(*huh.Form); ok {
+		m.Form = form
+	}
+
+	switch m.Form.State {
+	case huh.StateAborted:
+		m.closeForm()
+		m.Status = "Cancelled"
+		m.setActionPreview()
+		return true, m, nil
+	case huh.StateCompleted:
+		return m.submitForm()
+	}
+	if m.FormKind == "modify" {
+		m.ActionMessage = m.ModifyInput.preview()
+	}
+	return true, m, cmd
+}
+
+// resizeForm passes a resize on to the open dialog once the layout behind it
+// has taken it
+func (m *UIState) resizeForm(msg tea.WindowSizeMsg) tea.Cmd {
+	model, cmd := m.Form.Update(msg)
+	if form, ok := model.(*huh.Form); ok {
+		m.Form = form
+	}
+	return cmd
+}
+
+// submitForm turns a completed dialog into the matching action
+func (m UIState) submitForm() (bool, tea.Model, tea.Cmd) {
+	kind := m.FormKind
+	createInput := m.CreateInput
+	modifyInput := m.ModifyInput
+	undoInput := m.UndoInput
+	restoreInput := m.RestoreInput
+	historyInput := m.HistoryInput
+	searchInput := m.SearchInput
+	configInput := m.ConfigInput
+	wizardInput := m.WizardInput
+	cleanupInput := m.CleanupInput
+	quotaInput := m.QuotaInput
+	confirmInput := m.ConfirmInput
+	viewInput := m.ViewInput
+	plan := m.CleanupPlan
+	m.closeForm()
+
+	switch kind {
+	case "modify":
+		if _, err := modifyInput.options(modifyInput.Targets[0]); err != nil {
+			m.ActionMessage = fmt.Sprintf("Modify failed: %v", err)
+			m.Status = "Modify failed"
+			return true, m, nil
+		}
+		m.ActionInProgress = true
+		m.ActionMessage = fmt.Sprintf("⏳ Modifying %d snapshot(s)...", len(modifyInput.Targets))
+		return true, m, modifySnapshotsCmd(m.Client, modifyInput)
+	case "create":
+		opts, err := createInput.options()
+		if err != nil {
+			m.ActionMessage = fmt.Sprintf("Create failed: %v", err)
+			m.Status = "Create failed"
+			return true, m, nil
+		}
+		m.ActionInProgress = true
+		m.ActionMessage = fmt.Sprintf("⏳ Creating %s snapshot in %s...", opts.Type, opts.Config)
+		return true, m, createSnapshotCmd(m.Client, opts)
+	case "undochange":
+		if !undoInput.confirmed() {
+			m.Status = "Cancelled"
+			return true, m, nil
+		}
+		m.ActionInProgress = true
+		m.Status = fmt.Sprintf("Undoing changes to %d file(s)...", len(undoInput.Changes))
+		return true, m, undoChangeCmd(m.Client, undoInput)
+	case "restore":
+		if !restoreInput.Confirmed {
+			m.Status = "Cancelled"
+			return true, m, nil
+		}
+		m.ActionInProgress = true
+		m.Status = fmt.Sprintf("Restoring %s from snapshot %d...", restoreInput.Live, restoreInput.Snap.Number)
+		return true, m, restoreCmd(m.Recorder, restoreInput)
+	case "history":
+		model, cmd := m.openHistory(historyInput.Snap, filepath.Clean(strings.TrimSpace(historyInput.Path)))
+		return true, model, cmd
+	case "search":
+		model, cmd := m.startSearchView(searchInput)
+		return true, model, cmd
+	case "setconfig":
+		value := strings.TrimSpace(configInput.Value)
+		if value == configInput.Old {
+			m.Status = fmt.Sprintf("%s unchanged", configInput.Key)
+			return true, m, nil
+		}
+		m.ActionInProgress = true
+		m.Status = fmt.Sprintf("Setting %s=%s in %s...", configInput.Key, value, configInput.Config)
+		return true, m, setConfigCmd(m.Client, configInput.Config, map[string]string{configInput.Key: value})
+	case "createconfig", "deleteconfig":
+		if !wizardInput.Confirmed {
+			m.Status = "Cancelled"
+			return true, m, nil
+		}
+		m.ActionInProgress = true
+		if kind == "deleteconfig" {
+			m.Status = fmt.Sprintf("Deleting config %s...", wizardInput.Name)
+			return true, m, deleteConfigCmd(m.Client, wizardInput.Name)
+		}
+		opts := wizardInput.options()
+		m.Status = fmt.Sprintf("Creating config %s for %s...", opts.Name, opts.Subvolume)
+		return true, m, createConfigCmd(m.Client, opts)
+	case "cleanup":
+		m.ActionInProgress = true
+		m.Status = fmt.Sprintf("Simulating the %s cleanup of %s...", cleanupInput.Algorithm, cleanupInput.Config)
+		return true, m, cleanupPreviewCmd(m.Client, cleanupInput.Config, cleanupInput.Algorithm, m.Snapshots)
+	case "setupquota":
+		if !quotaInput.Confirmed {
+			m.Status = "Cancelled"
+			return true, m, nil
+		}
+		m.ActionInProgress = true
+		m.ActionMessage = fmt.Sprintf("⏳ Setting up quota groups for %s...", quotaInput.Config)
+		return true, m, setupQuotaCmd(m.Client, quotaInput.Config)
+	case "confirm":
+		if !confirmInput.confirmed() {
+			m.Status = "Cancelled"
+			return true, m, nil
+		}
+		return true, m, m.runAction(confirmInput.Kind, confirmInput.Snap, confirmInput.Targets)
+	case "views":
+		model, cmd := m.submitViewPicker(viewInput)
+		return true, model, cmd
+	case "runcleanup":
+		if !cleanupInput.Confirmed || plan == nil {
+			m.Status = "Cleanup not run; esc discards the preview"
+			return true, m, nil
+		}
+		m.ActionInProgress = true
+		m.ActionMessage = fmt.Sprintf("⏳ Running the %s cleanup of %s...", plan.Algorithm, plan.Config)
+		return true, m, cleanupCmd(m.Client, *plan)
+	}
+	return true, m, nil
+}
+
+// closeForm discards the open dialog and its bound values
+func (m *UIState) closeForm() {
+	m.Form = nil
+	m.FormKind = ""
+	m.CreateInput = nil
+	m.ModifyInput = nil
+	m.UndoInput = nil
+	m.RestoreInput = nil
+	m.HistoryInput = nil
+	m.SearchInput = nil
+	m.ConfigInput = nil
+	m.WizardInput = nil
+	m.CleanupInput = nil
+	m.QuotaInput = nil
+	m.ConfirmInput = nil
+	m.ViewInput = nil
+}
+
+// openCreateForm opens the create snapshot dialog for the config under the cursor
+func (m *UIState) openCreateForm() tea.Cmd {
+	configs := m.configNames()
+	input := &createFormInput{Type: "single", Cleanup: "number"}
+	if snap := m.currentSnapshot(); snap != nil {
+		input.Config = snap.Config
+	} else if len(configs) > 0 {
+		input.Config = configs[0]
+	}
+
+	var configField huh.Field
+	if len(configs) > 0 {
+		configField = huh.NewSelect[string]().
+			Title("Config").
+			Options(huh.NewOptions(configs...)...).
+			Value(&input.Config)
+	} else {
+		configField = huh.NewInput().
+			Title("Config").
+			Value(&input.Config).
+			Validate(requireText("config"))
+	}
+
+	snaps := m.Snapshots
+	m.CreateInput = input
+	m.FormKind = "create"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			configField,
+			huh.NewSelect[string]().
+				Title("Type").
+				Options(huh.NewOptions("single", "pre", "post")...).
+				Value(&input.Type),
+		),
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Pre snapshot").
+				DescriptionFunc(func() string {
+					if len(openPreOptions(snaps, input.Config)) == 0 {
+						return "No open pre snapshot in this config; go back and pick another type"
+					}
+					return "Open pre snapshots without a post partner"
+				}, &input.Config).
+				OptionsFunc(func() []huh.Option[string] {
+					return openPreOptions(snaps, input.Config)
+				}, &input.Config).
+				Value(&input.PreNumber).
+				Validate(requireText("pre snapshot")),
+		).WithHideFunc(func() bool { return input.Type != "post" }),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Description").
+				Value(&input.Description),
+			huh.NewSelect[string]().
+				Title("Cleanup algorithm").
+				Options(cleanupOptions()...).
+				Value(&input.Cleanup),
+			huh.NewInput().
+				Title("Userdata").
+				Placeholder("key=value, important=yes").
+				Value(&input.Userdata).
+				Validate(func(s string) error {
+					_, err := parseUserdata(s)
+					return err
+				}),
+		),
+	).WithShowHelp(true).WithWidth(60)
+
+	m.Status = "Create snapshot: enter to continue, esc to cancel"
+	return m.Form.Init()
+}
+
+// openModifyForm opens the modify dialog for the selected snapshots, or the
+// one under the cursor when nothing is selected
+func (m *UIState) openModifyForm() tea.Cmd {
+	var targets []Snapshot
+	for _, snap := range m.Visible {
+		if m.SelectedSnapshots[snap.Key()] {
+			targets = append(targets, snap)
+		}
+	}
+	if len(targets) == 0 {
+		if snap := m.currentSnapshot(); snap != nil {
+			targets = []Snapshot{*snap}
+		}
+	}
+	// Snapshot 0 is the live filesystem and has no metadata to edit
+	editable := targets[:0]
+	for _, snap := range targets {
+		if snap.Number != 0 {
+			editable = append(editable, snap)
+		}
+	}
+	if len(editable) == 0 {
+		m.Status = "Snapshot 0 (current) cannot be modified"
+		return nil
+	}
+
+	// huh copies bound values when a field is built, so prefill first
+	input := &modifyFormInput{Targets: editable, Cleanup: keepValue}
+	if len(editable) == 1 {
+		input.Description = editable[0].Description
+		input.Cleanup = editable[0].Cleanup
+		input.Userdata = formatUserdataInput(editable[0].Userdata)
+	}
+
+	descField := huh.NewInput().Title("Description").Value(&input.Description)
+	cleanupField := huh.NewSelect[string]().Title("Cleanup algorithm")
+	userdataField := huh.NewInput().Title("Userdata").Value(&input.Userdata).
+		Validate(func(s string) error {
+			_, err := parseUserdata(s)
+			return err
+		})
+
+	if len(editable) == 1 {
+		cleanupField.Options(cleanupOptions()...)
+		userdataField.Placeholder("key=value, important=yes")
+	} else {
+		descField.Placeholder("leave empty to keep each description")
+		cleanupField.Options(append([]huh.Option[string]{huh.NewOption("keep current", keepValue)}, cleanupOptions()...)...)
+		userdataField.Description("Merged into each snapshot; key= removes a key").Placeholder("important=yes")
+	}
+	cleanupField.Value(&input.Cleanup)
+
+	m.ModifyInput = input
+	m.FormKind = "modify"
+	m.Form = huh.NewForm(huh.NewGroup(descField, cleanupField, userdataField)).
+		WithShowHelp(true).
+		WithWidth(60)
+
+	m.ActionMessage = input.preview()
+	m.Status = fmt.Sprintf("Modify %d snapshot(s): enter to continue, esc to cancel", len(editable))
+	return m.Form.Init()
+}
+
+// options computes the metadata a target should end up with
+func (in *modifyFormInput) options(snap Snapshot) (ModifyOptions, error) {
+	edits, err := parseUserdata(in.Userdata)
+	if err != nil {
+		return ModifyOptions{}, err
+	}
+
+	// A single snapshot is edited as a whole; a batch only changes what was filled in
+	if len(in.Targets) == 1 {
+		return ModifyOptions{
+			Description: strings.TrimSpace(in.Description),
+			Cleanup:     in.Cleanup,
+			Userdata:    edits,
+			Current:     snap.Userdata,
+		}, nil
+	}
+
+	opts := ModifyOptions{Description: snap.Description, Cleanup: snap.Cleanup, Current: snap.Userdata}
+	if desc := strings.TrimSpace(in.Description); desc != "" {
+		opts.Description = desc
+	}
+	if in.Cleanup != keepValue {
+		opts.Cleanup = in.Cleanup
+	}
+	userdata := map[string]string{}
+	for key, val := range snap.Userdata {
+		userdata[key] = val
+	}
+	for key, val := range edits {
+		if val == "" {
+			delete(userdata, key)
+		} else {
+			userdata[key] = val
+		}
+	}
+	if len(userdata) > 0 {
+		opts.Userdata = userdata
+	}
+	return opts, nil
+}
+
+// preview renders the before/after of the pending modification for the action panel
+func (in *modifyFormInput) preview() string {
+	lines := []string{fmt.Sprintf("Modify %s:", describeTargets(in.Targets))}
+	var descs, cleanups, datas []string
+	for _, snap := range in.Targets {
+		after, err := in.options(snap)
+		if err != nil {
+			return fmt.Sprintf("Modify %s: %v", describeTargets(in.Targets), err)
+		}
+		descs = append(descs, changeText(nonEmpty(snap.Description, "<none>"), nonEmpty(after.Description, "<none>")))
+		cleanups = append(cleanups, changeText(nonEmpty(snap.Cleanup, "none"), nonEmpty(after.Cleanup, "none")))
+		datas = append(datas, changeText(nonEmpty(flattenUserData(snap.Userdata), "<none>"), nonEmpty(flattenUserData(after.Userdata), "<none>")))
+	}
+	lines = append(lines,
+		"Description: "+summarizeChanges(descs),
+		"Cleanup: "+summarizeChanges(cleanups),
+		"Userdata: "+summarizeChanges(datas),
+	)
+	return strings.Join(lines, "\n")
+}
+
+// changeText renders one field transition, or the value when it does not change
+func changeText(before, after string) string {
+	if before == after {
+		return before + " (unchanged)"
+	}
+	return before + " → " + after
+}
+
+// summarizeChanges collapses per-snapshot transitions, listing distinct ones
+func summarizeChanges(changes []string) string {
+	seen := map[string]bool{}
+	var distinct []string
+	for _, c := range changes {
+		if !seen[c] {
+			seen[c] = true
+			distinct = append(distinct, c)
+		}
+	}
+	if len(distinct) > 2 {
+		return fmt.Sprintf("%s; %s; … (%d variants)", distinct[0], distinct[1], len(distinct))
+	}
+	return strings.Join(distinct, "; ")
+}
+
+// describeTargets names a target set compactly, e.g. "root #17" or "3 snapshots (root, home)"
+func describeTargets(targets []Snapshot) string {
+	if len(targets) == 1 {
+		return fmt.Sprintf("%s #%d", targets[0].Config, targets[0].Number)
+	}
+	var configs []string
+	for _, group := range groupByConfig(targets) {
+		configs = append(configs, group[0].Config)
+	}
+	return fmt.Sprintf("%d snapshots (%s)", len(targets), strings.Join(configs, ", "))
+}
+
+// options validates the dialog values and converts them to CreateOptions
+func (in *createFormInput) options() (CreateOptions, error) {
+	userdata, err := parseUserdata(in.Userdata)
+	if err != nil {
+		return CreateOptions{}, err
+	}
+	opts := CreateOptions{
+		Config:      strings.TrimSpace(in.Config),
+		Type:        in.Type,
+		Description: strings.TrimSpace(in.Description),
+		Cleanup:     in.Cleanup,
+		Userdata:    userdata,
+	}
+	if opts.Config == "" {
+		return CreateOptions{}, fmt.Errorf("no config selected")
+	}
+	if opts.Type == "post" {
+		pre, err := strconv.Atoi(in.PreNumber)
+		if err != nil {
+			return CreateOptions{}, fmt.Errorf("no pre snapshot selected")
+		}
+		opts.PreNumber = pre
+	}
+	return opts, nil
+}
+
+// renderForm draws the open dialog in place of the table
+func (m UIState) renderForm(width, height int) string {
+	title := "Create snapshot"
+	switch m.FormKind {
+	case "modify":
+		title = "Modify snapshot metadata"
+	case "undochange":
+		title = "Undo changes"
+	case "restore":
+		title = "Restore from snapshot"
+	case "history":
+		title = "File history"
+	case "search":
+		title = "Search snapshot contents"
+	case "setconfig":
+		title = "Edit config setting"
+	case "createconfig":
+		title = "Create config"
+	case "deleteconfig":
+		title = "Delete config"
+	case "cleanup":
+		title = "Preview cleanup"
+	case "runcleanup":
+		title = "Run cleanup"
+	case "setupquota":
+		title = "Set up quota"
+	case "confirm":
+		title = "Confirm " + m.ConfirmInput.Kind.String()
+	case "views":
+		title = "Saved views"
+	case "summary":
+		title = "Summary"
+	}
+	body := detailHeaderStyle.Render(title) + "\n\n" + m.Form.View()
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Top, panelStyle.Render(body))
+}
+
+// configNames returns the distinct configs present in the snapshot list
+func (m UIState) configNames() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, snap := range m.Snapshots {
+		if snap.Config != "" && !seen[snap.Config] {
+			seen[snap.Config] = true
+			names = append(names, snap.Config)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openPreOptions lists the pre snapshots of a config that have no post partner yet
+func openPreOptions(snaps []Snapshot, config string) []huh.Option[string] {
+	var options []huh.Option[string]
+	for _, snap := range snaps {
+		if snap.Config != config || snap.SnapshotType != "pre" || snap.PostNumber != nil {
+			continue
+		}
+		label := fmt.Sprintf("%d  %s", snap.Number, nonEmpty(snap.Description, "<none>"))
+		options = append(options, huh.NewOption(label, strconv.Itoa(snap.Number)))
+	}
+	return options
+}
+
+// cleanupOptions builds the cleanup algorithm choices, labelling the empty one
+func cleanupOptions() []huh.Option[string] {
+	options := make([]huh.Option[string], 0, len(cleanupAlgorithms))
+	for _, algo := range cleanupAlgorithms {
+		options = append(options, huh.NewOption(nonEmpty(algo, "none"), algo))
+	}
+	return options
+}
+
+// requireText returns a validator rejecting blank values
+func requireText(name string) func(string) error {
+	return func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("%s is required", name)
+		}
+		return nil
+	}
+}
+
+// modifySnapshotsCmd applies the dialog to every target, then re-reads just
+// those snapshots so the table can patch the affected rows
+func modifySnapshotsCmd(client SnapperClient, input *modifyFormInput) tea.Cmd {
+	return func() tea.Msg {
+		msg := ActionResultMsg{Kind: ActionModify, Snap: input.Targets[0]}
+		for _, snap := range input.Targets {
+			opts, err := input.options(snap)
+			if err == nil {
+				var output string
+				output, err = client.Modify(snap.Config, snap.Number, opts)
+				if err != nil {
+					err = fmt.Errorf("%s #%d: %s", snap.Config, snap.Number, nonEmpty(output, err.Error()))
+				}
+			}
+			if err != nil {
+				msg.Snap = snap
+				msg.Err = err
+				msg.Output = err.Error()
+				break
+			}
+			if fresh, err := client.Get(snap.Config, snap.Number); err == nil {
+				msg.Snapshots = append(msg.Snapshots, fresh)
+			}
+		}
+		return msg
+	}
+}
+
+// createSnapshotCmd creates the snapshot and reports its key back
+func createSnapshotCmd(client SnapperClient, opts CreateOptions) tea.Cmd {
+	return func() tea.Msg {
+		number, err := client.Create(opts)
+		msg := ActionResultMsg{Kind: ActionCreate, Snap: Snapshot{Config: opts.Config, Number: number}, Err: err}
+		if err != nil {
+			msg.Output = err.Error()
+		}
+		return msg
+	}
+}
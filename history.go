@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileVersion is one version of a file: a run of consecutive snapshots in
+// which it is identical, or absent
+type FileVersion struct {
+	Snaps   []Snapshot // oldest first; number 0 is the live system
+	Present bool
+	Mode    fs.FileMode
+	Size    int64
+	ModTime time.Time // modification time in the newest snapshot of the run
+	Hash    string    // SHA-256 of the contents, or of the link target
+	Err     error
+}
+
+// First and Last are the oldest and newest snapshot of the run
+func (v FileVersion) First() Snapshot { return v.Snaps[0] }
+func (v FileVersion) Last() Snapshot  { return v.Snaps[len(v.Snaps)-1] }
+
+// same reports whether two versions may be collapsed into one
+func (v FileVersion) same(other FileVersion) bool {
+	if v.Err != nil || other.Err != nil || v.Present != other.Present {
+		return false
+	}
+	return !v.Present || (v.Mode == other.Mode && v.Hash == other.Hash)
+}
+
+// label names the run of snapshots, e.g. "#12–#17" or "#21–live"
+func (v FileVersion) label() string {
+	name := func(snap Snapshot) string {
+		if snap.Number == 0 {
+			return "live"
+		}
+		return fmt.Sprintf("#%d", snap.Number)
+	}
+	if len(v.Snaps) == 1 {
+		return name(v.First())
+	}
+	return name(v.First()) + "–" + name(v.Last())
+}
+
+// historySnapshots lists the snapshots of a config oldest first, followed by
+// the live system
+func historySnapshots(snapshots []Snapshot, config string) []Snapshot {
+	var snaps []Snapshot
+	var subvolume string
+	for _, snap := range snapshots {
+		if snap.Config != config {
+			continue
+		}
+		subvolume = snap.Subvolume
+		if snap.Number != 0 {
+			snaps = append(snaps, snap)
+		}
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Number < snaps[j].Number })
+	return append(snaps, Snapshot{Config: config, Subvolume: subvolume})
+}
+
+// fileVersionAt reads the version of rel in one snapshot, or on the live
+// system for snapshot 0
+func fileVersionAt(snap Snapshot, rel string) FileVersion {
+	version := FileVersion{Snaps: []Snapshot{snap}}
+	path := livePath(snap, rel)
+	if snap.Number != 0 {
+		src, err := snapshotSource(snapshotRoot(snap), rel)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return version
+			}
+			version.Err = err
+			return version
+		}
+		path = src
+	}
+
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return version
+	}
+	if err != nil {
+		version.Err = err
+		return version
+	}
+	version.Present = true
+	version.Mode = info.Mode()
+	version.Size = info.Size()
+	version.ModTime = info.ModTime()
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			version.Err = err
+			break
+		}
+		sum := sha256.Sum256([]byte(target))
+		version.Hash = hex.EncodeToString(sum[:])
+	case info.Mode().IsRegular():
+		digest := digestFile(path)
+		version.Hash, version.Err = digest.SHA256, digest.Err
+	}
+	return version
+}
+
+// fileHistory reads rel in every snapshot and collapses consecutive
+// identical versions
+func fileHistory(snaps []Snapshot, rel string) []FileVersion {
+	var versions []FileVersion
+	for _, snap := range snaps {
+		version := fileVersionAt(snap, rel)
+		if n := len(versions); n > 0 && versions[n-1].same(version) {
+			last := &versions[n-1]
+			last.Snaps = append(last.Snaps, snap)
+			last.ModTime = version.ModTime
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// historyCountText summarises a history, e.g. "4 versions in 23 snapshots"
+func historyCountText(versions []FileVersion) string {
+	present, snaps := 0, 0
+	for _, version := range versions {
+		if version.Present {
+			present++
+			for _, snap := range version.Snaps {
+				if snap.Number != 0 {
+					snaps++
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("%d version(s) in %d snapshot(s)", present, snaps)
+}
+
+// versionText describes a version for the history list
+func (v FileVersion) versionText() string {
+	switch {
+	case v.Err != nil:
+		return "error: " + v.Err.Error()
+	case !v.Present:
+		return "(not present)"
+	}
+	details := []string{v.Mode.String(), v.ModTime.Format("2006-01-02 15:04:05")}
+	switch {
+	case v.Mode.IsRegular():
+		details = append(details, humanReadableBytes(ptrInt64(v.Size)), "sha256:"+v.Hash[:12])
+	case v.Mode&fs.ModeSymlink != 0:
+		details = append(details, "symlink", "sha256:"+v.Hash[:12])
+	case v.Mode.IsDir():
+		details = append(details, "directory")
+	}
+	return strings.Join(details, "  ")
+}
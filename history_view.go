@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var historyMarkStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#fbbf24"))
+
+// HistoryView lists the versions of one path across the snapshots of a config
+type HistoryView struct {
+	Config   string
+	Path     string // path on the live system
+	Rel      string // snapshot-relative path
+	Versions []FileVersion
+	Cursor   int
+	Offset   int
+	Mark     int    // version picked as one side of a diff, -1 when none
+	Back     string // screen to return to
+}
+
+// HistoryResultMsg carries the versions of a path
+type HistoryResultMsg struct {
+	Config   string
+	Path     string
+	Rel      string
+	Versions []FileVersion
+}
+
+// historyFormInput holds the path typed into the history prompt
+type historyFormInput struct {
+	Snap Snapshot
+	Path string
+}
+
+// historyCmd reads a path in every snapshot of its config
+func historyCmd(snaps []Snapshot, path, rel string) tea.Cmd {
+	return func() tea.Msg {
+		return HistoryResultMsg{
+			Config:   snaps[0].Config,
+			Path:     path,
+			Rel:      rel,
+			Versions: fileHistory(snaps, rel),
+		}
+	}
+}
+
+// openHistory starts loading the history of a live path in the config of snap
+func (m UIState) openHistory(snap Snapshot, path string) (tea.Model, tea.Cmd) {
+	rel, err := snapshotRelPath(snap, path)
+	if err != nil {
+		m.Status = fmt.Sprintf("No history: %v", err)
+		return m, nil
+	}
+	m.Status = fmt.Sprintf("Reading %s in every %s snapshot...", path, snap.Config)
+	return m, historyCmd(historySnapshots(m.Snapshots, snap.Config), path, rel)
+}
+
+// handleHistoryResult shows the history, keeping the cursor on the same
+// version when it is reloaded
+func (m UIState) handleHistoryResult(msg HistoryResultMsg) (tea.Model, tea.Cmd) {
+	v := &HistoryView{Config: msg.Config, Path: msg.Path, Rel: msg.Rel, Versions: msg.Versions, Mark: -1, Back: m.Screen}
+	if old := m.HistoryView; m.Screen == "history" && old != nil {
+		if old.Path != msg.Path || old.Config != msg.Config {
+			return m, nil
+		}
+		v.Back = old.Back
+		v.Cursor, v.Offset = old.Cursor, old.Offset
+		v.moveCursor(0, m.pageHeight())
+	} else {
+		// Start on the newest version
+		v.moveCursor(len(v.Versions), m.pageHeight())
+	}
+	m.HistoryView = v
+	m.Screen = "history"
+	m.Status = fmt.Sprintf("History of %s: %s", msg.Path, historyCountText(msg.Versions))
+	return m, nil
+}
+
+// openHistoryForm asks for the path whose history to show
+func (m *UIState) openHistoryForm() tea.Cmd {
+	snap := m.currentSnapshot()
+	if snap == nil {
+		return nil
+	}
+	input := &historyFormInput{Snap: *snap}
+	m.HistoryInput = input
+	m.FormKind = "history"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(fmt.Sprintf("Path to trace through every %s snapshot", snap.Config)).
+				Placeholder("/etc/fstab").
+				Validate(func(path string) error {
+					path = strings.TrimSpace(path)
+					if !filepath.IsAbs(path) {
+						return fmt.Errorf("enter an absolute path")
+					}
+					_, err := snapshotRelPath(input.Snap, filepath.Clean(path))
+					return err
+				}).
+				Value(&input.Path),
+		),
+	).WithShowHelp(true).WithWidth(70)
+
+	m.Status = "File history: enter a path, esc to cancel"
+	return m.Form.Init()
+}
+
+// moveCursor moves the highlighted version and scrolls it into view
+func (v *HistoryView) moveCursor(delta, page int) {
+	if len(v.Versions) == 0 {
+		v.Cursor, v.Offset = 0, 0
+		return
+	}
+	v.Cursor = max(0, min(len(v.Versions)-1, v.Cursor+delta))
+	if v.Cursor < v.Offset {
+		v.Offset = v.Cursor
+	}
+	if v.Cursor >= v.Offset+page {
+		v.Offset = v.Cursor - page + 1
+	}
+	v.Offset = max(0, min(v.Offset, len(v.Versions)-page))
+}
+
+// diffPair picks the two versions to compare: the marked one and the cursor,
+// or the cursor and the present version before it
+func (v *HistoryView) diffPair() (older, newer *FileVersion, err error) {
+	if v.Cursor >= len(v.Versions) {
+		return nil, nil, fmt.Errorf("no version under the cursor")
+	}
+	a, b := v.Mark, v.Cursor
+	if a < 0 {
+		for a = b - 1; a >= 0 && !v.Versions[a].Present; a-- {
+		}
+		if a < 0 {
+			return nil, nil, fmt.Errorf("no earlier version to compare with; mark one with space")
+		}
+	}
+	if a == b {
+		return nil, nil, fmt.Errorf("mark a different version to compare with")
+	}
+	if a > b {
+		a, b = b, a
+	}
+	older, newer = &v.Versions[a], &v.Versions[b]
+	if !older.Present || !newer.Present {
+		return nil, nil, fmt.Errorf("the file is not present in both versions")
+	}
+	return older, newer, nil
+}
+
+// handleHistoryKey drives the history view: moving, marking, diffing and
+// restoring versions
+func (m UIState) handleHistoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.HistoryView
+	page := m.pageHeight()
+	switch msg.String() {
+	case "esc", "q":
+		m.Screen = v.Back
+		m.HistoryView = nil
+		m.Status = "Closed file history"
+	case "j", "down":
+		v.moveCursor(1, page)
+	case "k", "up":
+		v.moveCursor(-1, page)
+	case "pgdn", "pagedown":
+		v.moveCursor(page, page)
+	case "pgup", "pageup":
+		v.moveCursor(-page, page)
+	case "g", "home":
+		v.moveCursor(-len(v.Versions), page)
+	case "G", "end":
+		v.moveCursor(len(v.Versions), page)
+	case " ", "m":
+		if v.Mark == v.Cursor {
+			v.Mark = -1
+			m.Status = "Cleared mark"
+		} else if v.Cursor < len(v.Versions) {
+			v.Mark = v.Cursor
+			m.Status = fmt.Sprintf("Marked %s; move to another version and press d to diff", v.Versions[v.Cursor].label())
+		}
+	case "enter", "d":
+		older, newer, err := v.diffPair()
+		if err != nil {
+			m.Status = err.Error()
+			return m, nil
+		}
+		from, to := older.Last(), newer.Last()
+		m.Status = fmt.Sprintf("Loading diff of %s %s..%s...", v.Path, older.label(), newer.label())
+		return m, diffFileCmd(m.Client, to, from.Number, to.Number, v.Path)
+	case "R":
+		if v.Cursor >= len(v.Versions) || !v.Versions[v.Cursor].Present {
+			m.Status = "The file is not present in this version"
+			return m, nil
+		}
+		version := v.Versions[v.Cursor]
+		snap := version.First()
+		if snap.Number == 0 {
+			m.Status = "This is the live version; pick an older one to restore"
+			return m, nil
+		}
+		return m, m.openRestoreForm(snap, v.Rel)
+	case "r":
+		return m.openHistory(v.Versions[len(v.Versions)-1].Last(), v.Path)
+	}
+	return m, nil
+}
+
+// handleHistoryMouse scrolls with the wheel and selects a version on click
+func (m UIState) handleHistoryMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	v := m.HistoryView
+	page := m.pageHeight()
+	switch msg.Type {
+	case tea.MouseWheelUp:
+		v.moveCursor(-3, page)
+	case tea.MouseWheelDown:
+		v.moveCursor(3, page)
+	case tea.MouseLeft:
+		row := msg.Y - 3
+		if pos := v.Offset + row; row >= 0 && row < page && pos < len(v.Versions) {
+			v.moveCursor(pos-v.Cursor, page)
+		}
+	}
+	return m, nil
+}
+
+// renderHistoryView draws one row per version, newest last
+func (m UIState) renderHistoryView(width, height int) string {
+	v := m.HistoryView
+	page := m.pageHeight()
+
+	title := detailHeaderStyle.Render(fmt.Sprintf("History of %s in %s", v.Path, v.Config))
+	info := historyCountText(v.Versions)
+	if v.Mark >= 0 && v.Mark < len(v.Versions) {
+		info += fmt.Sprintf(" | marked %s", v.Versions[v.Mark].label())
+	}
+
+	var body []string
+	if len(v.Versions) == 0 {
+		body = append(body, "No snapshots in this config.")
+	}
+	for pos := v.Offset; pos < min(v.Offset+page, len(v.Versions)); pos++ {
+		version := v.Versions[pos]
+		mark := "  "
+		if pos == v.Mark {
+			mark = historyMarkStyle.Render("● ")
+		}
+		date := version.First().Date
+		if version.First().Number == 0 {
+			date = "live system"
+		}
+		line := fmt.Sprintf("%-14s %-4s %-19s  %s",
+			version.label(),
+			fmt.Sprintf("(%d)", len(version.Snaps)),
+			padOrTruncate(date, 19),
+			version.versionText())
+		line = padOrTruncate(line, width-2)
+		switch {
+		case version.Err != nil:
+			line = browserWarnStyle.Render(line)
+		case !version.Present:
+			line = pagerHelpStyle.Render(line)
+		}
+		if pos == v.Cursor {
+			line = pagerCursorStyle.Render(line)
+		}
+		body = append(body, mark+line)
+	}
+
+	footer := pagerHelpStyle.Render("↑↓/PgUp/PgDn/g/G: Move | space/m: Mark | enter/d: Diff with mark or previous | R: Restore | r: Reload | esc/q: Back")
+	ui := lipgloss.JoinVertical(lipgloss.Left,
+		headerStyle.Width(width).Render(m.headerTitle()),
+		title,
+		summaryStyle.Render(padOrTruncate(info, width)),
+		lipgloss.NewStyle().Height(page).Render(strings.Join(body, "\n")),
+		footer,
+	)
+	return lipgloss.Place(width, height, lipgloss.Top, lipgloss.Left, ui)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handleSnapshotPatch applies a live snapperd change to the snapshot list in
+// place, keeping the cursor on the same snapshot and pruning stale selections
+func (m UIState) handleSnapshotPatch(msg SnapshotPatchMsg) (tea.Model, tea.Cmd) {
+	next := watchEventsCmd(m.Client, m.Events)
+	if msg.Err != nil {
+		// Not fatal: the next refresh picks the change up
+		m.Status = fmt.Sprintf("Live update for %s failed: %v", msg.Event.Config, msg.Err)
+		return m, next
+	}
+	if m.Placeholder {
+		return m, next
+	}
+
+	var cursorKey *SnapshotKey
+	if snap := m.currentSnapshot(); snap != nil {
+		key := snap.Key()
+		cursorKey = &key
+	}
+
+	ev := msg.Event
+	switch ev.Kind {
+	case EventSnapshotsDeleted:
+		removed := map[SnapshotKey]bool{}
+		for _, n := range ev.Numbers {
+			removed[SnapshotKey{Config: ev.Config, Number: n}] = true
+		}
+		m.removeSnapshots(func(s Snapshot) bool { return removed[s.Key()] })
+		m.Status = fmt.Sprintf("%s: %d snapshot(s) deleted", ev.Config, len(ev.Numbers))
+	case EventConfigDeleted:
+		m.removeSnapshots(func(s Snapshot) bool { return s.Config == ev.Config })
+		m.Status = fmt.Sprintf("Config %s deleted", ev.Config)
+	case EventConfigCreated:
+		m.removeSnapshots(func(s Snapshot) bool { return s.Config == ev.Config })
+		m.upsertSnapshots(msg.Snapshots)
+		m.Status = fmt.Sprintf("Config %s created", ev.Config)
+	case EventSnapshotCreated:
+		m.upsertSnapshots(msg.Snapshots)
+		m.Status = fmt.Sprintf("%s: snapshot %s created", ev.Config, joinNumbers(ev.Numbers))
+	case EventSnapshotModified:
+		m.upsertSnapshots(msg.Snapshots)
+		m.Status = fmt.Sprintf("%s: snapshot %s modified", ev.Config, joinNumbers(ev.Numbers))
+	}
+
+	if ev.Kind != EventSnapshotModified {
+		// The config's qgroup no longer holds the snapshots listed
+		delete(m.ConfigExclusive, ev.Config)
+	}
+	m.sortSnapshots()
+	m.restoreCursor(cursorKey)
+	m.selectPending()
+	m.Summary = buildSummary(m.Snapshots)
+	return m, next
+}
+
+// replaceRows patches refreshed rows into the list while keeping the cursor on
+// the same snapshot
+func (m *UIState) replaceRows(fresh []Snapshot) {
+	var cursorKey *SnapshotKey
+	if snap := m.currentSnapshot(); snap != nil {
+		key := snap.Key()
+		cursorKey = &key
+	}
+	m.upsertSnapshots(fresh)
+	m.sortSnapshots()
+	m.restoreCursor(cursorKey)
+	m.Summary = buildSummary(m.Snapshots)
+}
+
+// removeSnapshots drops matching rows, their selection and dangling post links
+func (m *UIState) removeSnapshots(match func(Snapshot) bool) {
+	removed := map[SnapshotKey]bool{}
+	kept := make([]Snapshot, 0, len(m.Snapshots))
+	for _, snap := range m.Snapshots {
+		if match(snap) {
+			removed[snap.Key()] = true
+			delete(m.SelectedSnapshots, snap.Key())
+			continue
+		}
+		kept = append(kept, snap)
+	}
+	for i := range kept {
+		if post := kept[i].PostNumber; post != nil && removed[SnapshotKey{Config: kept[i].Config, Number: *post}] {
+			kept[i].PostNumber = nil
+		}
+	}
+	m.Snapshots = kept
+}
+
+// upsertSnapshots replaces rows with the same key or appends new ones; a new
+// post snapshot also links its pre partner
+func (m *UIState) upsertSnapshots(fresh []Snapshot) {
+	snaps := append([]Snapshot(nil), m.Snapshots...)
+	index := map[SnapshotKey]int{}
+	for i, snap := range snaps {
+		index[snap.Key()] = i
+	}
+	for _, snap := range fresh {
+		if i, ok := index[snap.Key()]; ok {
+			snaps[i] = snap
+		} else {
+			index[snap.Key()] = len(snaps)
+			snaps = append(snaps, snap)
+		}
+		if snap.SnapshotType == "post" && snap.PreNumber != nil {
+			if i, ok := index[SnapshotKey{Config: snap.Config, Number: *snap.PreNumber}]; ok {
+				snaps[i].PostNumber = toOptionalInt(snap.Number)
+			}
+		}
+	}
+	m.Snapshots = snaps
+}
+
+// restoreCursor moves the cursor back onto the given snapshot if it still exists
+func (m *UIState) restoreCursor(key *SnapshotKey) {
+	if key != nil {
+		for i, snap := range m.Visible {
+			if snap.Key() == *key {
+				m.Cursor = i
+				break
+			}
+		}
+	}
+	if m.Cursor >= len(m.Visible) {
+		m.Cursor = max(0, len(m.Visible)-1)
+	}
+	m.ensureCursorVisible()
+}
+
+// watchEventsCmd waits for the next snapperd event and resolves it into a patch
+func watchEventsCmd(client SnapperClient, events <-chan SnapperEvent) tea.Cmd {
+	if events == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		ev, ok := <-events
+		if !ok {
+			return nil
+		}
+		return resolveSnapperEvent(client, ev)
+	}
+}
+
+// resolveSnapperEvent fetches the rows an event refers to; deletions need no lookup
+func resolveSnapperEvent(client SnapperClient, ev SnapperEvent) SnapshotPatchMsg {
+	msg := SnapshotPatchMsg{Event: ev}
+	switch ev.Kind {
+	case EventSnapshotCreated, EventSnapshotModified:
+		for _, n := range ev.Numbers {
+			snap, err := client.Get(ev.Config, n)
+			if err != nil {
+				msg.Err = err
+				return msg
+			}
+			msg.Snapshots = append(msg.Snapshots, snap)
+		}
+	case EventConfigCreated:
+		msg.Snapshots, msg.Err = client.ListConfig(ev.Config)
+	}
+	msg.Snapshots, _ = readQgroupSizes(client, msg.Snapshots)
+	return msg
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
-	"os/exec"
 	"sort"
 	"strconv"
 	"strings"
@@ -45,6 +45,10 @@ var (
 			Bold(true).
 			Underline(true)
 
+	doomedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#f87171")).
+			Strikethrough(true)
+
 	// Panels
 	panelStyle = lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
@@ -138,7 +142,7 @@ var columnSpecs = []ColumnSpec{
 		Key:       "used_space",
 		Label:     "Size",
 		Width:     12,
-		Accessor:  func(s Snapshot) string { return humanReadableBytes(s.UsedSpace) },
+		Accessor:  func(s Snapshot) string { return humanReadableBytes(snapshotBytes(s)) },
 		SortField: "used_space",
 	},
 	{
@@ -193,16 +197,82 @@ var sampleSnapshots = []Snapshot{
 }
 
 func main() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen(), tea.WithMouseCellMotion())
+	backend := flag.String("backend", "cli", "snapper backend: cli, dbus (snapperd) or fake (in-memory sample data)")
+	sessionBus := flag.Bool("session-bus", false, "talk to snapperd on the session bus instead of the system bus")
+	dryRun := flag.Bool("dry-run", false, "record the snapper commands of actions instead of running them (toggle in the app with n)")
+	auditPath := flag.String("audit-log", defaultAuditPath(), "append-only JSON Lines log of every action; empty disables it")
+	viewsPath := flag.String("views", defaultViewsPath(), "per-user saved views file; views in "+systemViewsPath+" are offered too")
+	startView := flag.String("view", "", "saved view to start with")
+	confirm := flag.String("confirm", "", "how to confirm destructive actions, e.g. delete=typed,rollback=yes,undochange=none (modes: none, yes, batch, typed)")
+	flag.Parse()
+
+	rules, err := parseConfirmRules(*confirm)
+	if err != nil {
+		fmt.Printf("snapper-TUI failed: %v\n", err)
+		os.Exit(1)
+	}
+	views, err := loadViewSet(systemViewsPath, *viewsPath)
+	if err != nil {
+		fmt.Printf("snapper-TUI failed: cannot read saved views: %v\n", err)
+		os.Exit(1)
+	}
+	client, err := newClient(*backend, *sessionBus)
+	if err != nil {
+		fmt.Printf("snapper-TUI failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	model := initialModel(client)
+	model.ConfirmRules = rules
+	model.Views = views
+	if *startView != "" {
+		view, ok := views.Find(*startView)
+		if !ok {
+			fmt.Printf("snapper-TUI failed: no saved view named %q\n", *startView)
+			os.Exit(1)
+		}
+		model.applyView(view)
+	}
+	model.Recorder.SetDryRun(*dryRun)
+	if *auditPath != "" {
+		audit, err := openAuditLog(*auditPath)
+		if err != nil {
+			fmt.Printf("snapper-TUI failed: cannot open the audit log: %v\n", err)
+			os.Exit(1)
+		}
+		model.Recorder.Audit = audit
+	}
+	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("snapper-TUI failed: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func initialModel() UIState {
+// newClient builds the SnapperClient selected by the --backend flag
+func newClient(backend string, sessionBus bool) (SnapperClient, error) {
+	switch backend {
+	case "cli":
+		return newExecClient(), nil
+	case "dbus":
+		return newDBusClient(sessionBus)
+	case "fake":
+		return newFakeClient(sampleSnapshots), nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q", backend)
+	}
+}
+
+func initialModel(client SnapperClient) UIState {
+	recorder := newRecorderClient(client)
 	m := UIState{
+		Client:            recorder,
+		Recorder:          recorder,
 		Snapshots:         sampleSnapshots,
+		Visible:           sampleSnapshots,
+		FilterInput:       newFilterInput(),
+		Columns:           columnSpecs,
+		Views:             &ViewSet{},
 		Placeholder:       true,
 		DetailOpen:        true,
 		ActionMessage:     "Select a snapshot to preview the snapper commands.",
@@ -211,19 +281,42 @@ func initialModel() UIState {
 		SortKey:           columnSpecs[0].SortField,
 		SortReverse:       false,
 		Loading:           true,
-		SelectedSnapshots: make(map[int]bool),
+		SelectedSnapshots: make(map[SnapshotKey]bool),
 		FocusedElement:    "table",
 		ButtonRects:       make(map[string]Rect),
+		ConfirmRules:      defaultConfirmRules,
+	}
+
+	// Live updates are best effort: without a reachable snapperd the list
+	// still refreshes on demand
+	if watcher, ok := client.(SnapshotWatcher); ok {
+		if events, err := watcher.Watch(); err == nil {
+			m.Events = events
+		}
 	}
 
 	return m
 }
 
 func (m UIState) Init() tea.Cmd {
-	return tea.Batch(refreshSnapshotsCmd(), tickCmd())
+	return tea.Batch(refreshSnapshotsCmd(m.Client), tickCmd(), watchEventsCmd(m.Client, m.Events))
 }
 
 func (m UIState) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.Form != nil {
+		if handled, model, cmd := m.routeToForm(msg); handled {
+			return model, cmd
+		}
+	}
+
+	// Actions that only recorded their commands report those instead
+	switch msg.(type) {
+	case ActionResultMsg, ConfigSavedMsg, ConfigChangedMsg:
+		if commands := m.Recorder.takeDryRun(); len(commands) > 0 {
+			return m.handleDryRunResult(commands)
+		}
+	}
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.TermWidth = msg.Width
@@ -233,21 +326,95 @@ func (m UIState) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Header: 1
 		// Table Header: 2 (Text + Border)
 		// Scrollbar: 1
+		// Filter bar: 1
 		// Action Panel: 6 (4 lines content + 2 border)
 		// Summary: 1
 		// Footer: 1
-		// Total Fixed Overhead: 1 + 2 + 1 + 6 + 1 + 1 = 12 lines.
+		// Total Fixed Overhead: 1 + 2 + 1 + 1 + 6 + 1 + 1 = 13 lines.
 		// We add 1 extra line of buffer to be safe.
-		availableHeight := msg.Height - 13
+		availableHeight := msg.Height - 14
 		if availableHeight < 1 {
 			availableHeight = 1
 		}
 		m.ViewportHeight = availableHeight
 		m.ensureCursorVisible()
+		if m.Form != nil {
+			return m, m.resizeForm(msg)
+		}
 	case tea.KeyMsg:
+		if m.Screen == "status" && msg.String() != "ctrl+c" {
+			return m.handleStatusKey(msg)
+		}
+		if m.Screen == "diff" && msg.String() != "ctrl+c" {
+			return m.handleDiffKey(msg)
+		}
+		if m.Screen == "browse" && msg.String() != "ctrl+c" {
+			return m.handleBrowserKey(msg)
+		}
+		if m.Screen == "history" && msg.String() != "ctrl+c" {
+			return m.handleHistoryKey(msg)
+		}
+		if m.Screen == "search" && msg.String() != "ctrl+c" {
+			return m.handleSearchKey(msg)
+		}
+		if m.Screen == "configs" && msg.String() != "ctrl+c" {
+			return m.handleConfigsKey(msg)
+		}
+		if m.Screen == "audit" && msg.String() != "ctrl+c" {
+			return m.handleAuditKey(msg)
+		}
 		return m.handleKey(msg)
 	case tea.MouseMsg:
+		if m.Screen == "status" {
+			return m.handleStatusMouse(msg)
+		}
+		if m.Screen == "diff" {
+			return m.handleDiffMouse(msg)
+		}
+		if m.Screen == "browse" {
+			return m.handleBrowserMouse(msg)
+		}
+		if m.Screen == "history" {
+			return m.handleHistoryMouse(msg)
+		}
+		if m.Screen == "search" {
+			return m.handleSearchMouse(msg)
+		}
+		if m.Screen == "configs" {
+			return m.handleConfigsMouse(msg)
+		}
+		if m.Screen == "audit" {
+			return m.handleAuditMouse(msg)
+		}
 		return m.handleMouse(msg)
+	case DiffResultMsg:
+		return m.handleDiffResult(msg)
+	case BrowserDirMsg:
+		return m.handleBrowserDir(msg)
+	case BrowserPreviewMsg:
+		return m.handleBrowserPreview(msg)
+	case HistoryResultMsg:
+		return m.handleHistoryResult(msg)
+	case SearchProgressMsg:
+		return m.handleSearchProgress(msg)
+	case ConfigsLoadedMsg:
+		return m.handleConfigsLoaded(msg)
+	case ConfigSavedMsg:
+		return m.handleConfigSaved(msg)
+	case ConfigChangedMsg:
+		return m.handleConfigChanged(msg)
+	case CleanupPreviewMsg:
+		return m.handleCleanupPreview(msg)
+	case AuditLoadedMsg:
+		return m.handleAuditLoaded(msg)
+	case ViewsSavedMsg:
+		return m.handleViewsSaved(msg)
+	case StatusSavedMsg:
+		if msg.Err != nil {
+			m.Status = fmt.Sprintf("Save failed: %v", msg.Err)
+		} else {
+			m.Status = fmt.Sprintf("Saved status output to %s", msg.Path)
+		}
 	case TickMsg:
 		return m.handleTick()
 	case RefreshTriggerMsg:
@@ -256,6 +423,20 @@ func (m UIState) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleRefreshResult(RefreshResult(msg))
 	case ActionResultMsg:
 		return m.handleActionResult(ActionResult(msg))
+	case SnapshotPatchMsg:
+		return m.handleSnapshotPatch(msg)
+	default:
+		// Cursor blinks and other internal messages of the pager's prompt
+		if m.StatusView != nil && m.StatusView.InputMode != "" {
+			var cmd tea.Cmd
+			m.StatusView.Input, cmd = m.StatusView.Input.Update(msg)
+			return m, cmd
+		}
+		if m.Screen == "" && m.FocusedElement == "filter" {
+			var cmd tea.Cmd
+			m.FilterInput, cmd = m.FilterInput.Update(msg)
+			return m, cmd
+		}
 	}
 	return m, nil
 }
@@ -275,29 +456,36 @@ func (m UIState) handleRefreshTrigger() (tea.Model, tea.Cmd) {
 	}
 	m.Loading = true
 	m.Status = "Refreshing snapshots..."
-	return m, tea.Batch(refreshSnapshotsCmd(), tickCmd())
+	return m, tea.Batch(refreshSnapshotsCmd(m.Client), tickCmd())
 }
 
 func (m UIState) handleRefreshResult(msg RefreshResult) (tea.Model, tea.Cmd) {
 	m.Loading = false
 	if msg.Err != nil {
+		m.PendingNotice = nil
 		m.Snapshots = sampleSnapshots
 		m.Placeholder = true
 		m.Status = fmt.Sprintf("snapper list failed: %v", msg.Err)
 		m.Summary = buildSummary(m.Snapshots)
+		m.applyFilter()
 		m.ActionMessage = "Using sample data; install snapper for real snapshots."
 		return m, nil
 	}
 
 	m.Snapshots = msg.Snapshots
+	m.QuotaOff = msg.QuotaOff
+	m.ConfigExclusive = msg.ConfigExclusive
 	m.Placeholder = false
 	m.sortSnapshots()
-	if m.Cursor >= len(m.Snapshots) {
-		m.Cursor = max(0, len(m.Snapshots)-1)
-	}
 	m.Summary = buildSummary(m.Snapshots)
 	m.Status = fmt.Sprintf("Loaded %d snapshots", len(m.Snapshots))
+	m.selectPending()
 	m.setActionPreview()
+	if m.PendingNotice != nil {
+		m.Status = m.PendingNotice.Status
+		m.ActionMessage = m.PendingNotice.Message
+		m.PendingNotice = nil
+	}
 	return m, nil
 }
 
@@ -309,11 +497,21 @@ func (m UIState) handleActionResult(msg ActionResult) (tea.Model, tea.Cmd) {
 			m.ActionMessage = fmt.Sprintf("Snapshot(s) deleted successfully. Refreshing list...")
 			m.Status = fmt.Sprintf("Deleted snapshot(s)")
 			// Clear selection after successful delete
-			m.SelectedSnapshots = make(map[int]bool)
+			m.SelectedSnapshots = make(map[SnapshotKey]bool)
 			return m, waitRefreshCmd(time.Second)
 		}
+		// Part of a batch may have gone; drop those rows from the selection
+		// and refresh so the table shows what is left
+		for _, snap := range msg.Snapshots {
+			delete(m.SelectedSnapshots, snap.Key())
+		}
 		m.ActionMessage = fmt.Sprintf("Delete failed: %s", msg.Output)
 		m.Status = "Delete failed"
+		if len(msg.Snapshots) > 0 {
+			m.Status = fmt.Sprintf("Delete failed in part: %v", msg.Err)
+		}
+		m.PendingNotice = &Notice{Status: m.Status, Message: m.ActionMessage}
+		return m, waitRefreshCmd(0)
 	case ActionRestore:
 		if msg.Err == nil {
 			m.ActionMessage = fmt.Sprintf("Rollback to snapshot %d complete. Press r to refresh or reboot.", msg.Snap.Number)
@@ -324,12 +522,56 @@ func (m UIState) handleActionResult(msg ActionResult) (tea.Model, tea.Cmd) {
 		m.Status = "Apply failed"
 	case ActionStatus:
 		if msg.Err == nil {
-			m.ActionMessage = fmt.Sprintf("Status output:\n%s", msg.Output)
+			start := computeStatusStart(msg.Snap)
+			m.StatusView = newStatusView(msg.Snap, start, msg.Snap.Number, msg.Output, m.StatusTree)
+			m.Screen = "status"
+			m.ActionMessage = fmt.Sprintf("Status %s %d..%d: %d changed entries", msg.Snap.Config, start, msg.Snap.Number, len(m.StatusView.Lines))
 			m.Status = "Status fetched"
 			return m, nil
 		}
 		m.ActionMessage = fmt.Sprintf("Status failed: %s", msg.Output)
 		m.Status = "Status failed"
+	case ActionCreate:
+		if msg.Err == nil {
+			key := msg.Snap.Key()
+			m.PendingSelect = &key
+			m.ActionMessage = fmt.Sprintf("Created snapshot %d in %s. Refreshing list...", key.Number, key.Config)
+			m.Status = fmt.Sprintf("Created snapshot %d", key.Number)
+			return m, waitRefreshCmd(0)
+		}
+		m.ActionMessage = fmt.Sprintf("Create failed: %s", msg.Output)
+		m.Status = "Create failed"
+	case ActionModify:
+		m.replaceRows(msg.Snapshots)
+		if msg.Err == nil {
+			m.ActionMessage = fmt.Sprintf("Modified %d snapshot(s).", len(msg.Snapshots))
+			m.Status = "Modified snapshot metadata"
+			return m, nil
+		}
+		m.ActionMessage = fmt.Sprintf("Modify failed: %s", msg.Output)
+		m.Status = "Modify failed"
+	case ActionUndoChange:
+		return m.handleUndoResult(msg)
+	case ActionRestoreFile:
+		return m.handleRestoreResult(msg)
+	case ActionSetupQuota:
+		if msg.Err == nil {
+			m.ActionMessage = fmt.Sprintf("Quota groups set up for %s. Refreshing list...", msg.Snap.Config)
+			m.Status = "Quota set up"
+			return m, waitRefreshCmd(0)
+		}
+		m.ActionMessage = fmt.Sprintf("setup-quota failed: %s", nonEmpty(msg.Output, msg.Err.Error()))
+		m.Status = "setup-quota failed"
+	case ActionCleanup:
+		if msg.Err == nil {
+			m.CleanupPlan = nil
+			m.ActionMessage = fmt.Sprintf("Cleanup of %s done. Refreshing list...", msg.Snap.Config)
+			m.Status = "Cleanup done"
+			m.SelectedSnapshots = make(map[SnapshotKey]bool)
+			return m, waitRefreshCmd(time.Second)
+		}
+		m.ActionMessage = fmt.Sprintf("Cleanup failed: %s", nonEmpty(msg.Output, msg.Err.Error()))
+		m.Status = "Cleanup failed"
 	}
 	return m, nil
 }
@@ -337,14 +579,14 @@ func (m UIState) handleActionResult(msg ActionResult) (tea.Model, tea.Cmd) {
 func (m UIState) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 	switch msg.Type {
 	case tea.MouseWheelUp:
-		if len(m.Snapshots) > 0 {
+		if len(m.Visible) > 0 {
 			m.Cursor = max(0, m.Cursor-1)
 			m.ensureCursorVisible()
 			m.setActionPreview()
 		}
 	case tea.MouseWheelDown:
-		if len(m.Snapshots) > 0 {
-			m.Cursor = min(len(m.Snapshots)-1, m.Cursor+1)
+		if len(m.Visible) > 0 {
+			m.Cursor = min(len(m.Visible)-1, m.Cursor+1)
 			m.ensureCursorVisible()
 			m.setActionPreview()
 		}
@@ -371,7 +613,7 @@ func (m UIState) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 				}
 
 				currentX := 0
-				for _, spec := range columnSpecs {
+				for _, spec := range m.Columns {
 					// Width + 1 for space separator
 					w := spec.Width
 					// Check if click is within this column's width
@@ -386,10 +628,13 @@ func (m UIState) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 
 			// Table rows start at y=3
 			if msg.Y >= 3 && msg.Y < m.TermHeight-4 {
-				m.FocusedElement = "table"
 				rowClicked := msg.Y - 3
+				if rowClicked >= m.ViewportHeight {
+					return m, nil
+				}
+				m.focusElement("table")
 				actualRowIdx := m.Offset + rowClicked
-				if actualRowIdx < len(m.Snapshots) {
+				if actualRowIdx < len(m.Visible) {
 					m.Cursor = actualRowIdx
 					m.ensureCursorVisible()
 					m.setActionPreview()
@@ -407,17 +652,13 @@ func (m UIState) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 				if msg.Y >= restoreY && msg.Y < restoreY+3 {
 					if !m.ActionInProgress && m.currentSnapshot() != nil {
 						m.FocusedElement = "restore"
-						m.ActionInProgress = true
-						m.ActionMessage = "⏳ Executing apply..."
-						return m, executeActionCmd(ActionRestore, *m.currentSnapshot(), m.SelectedSnapshots, m.Snapshots)
+						return m, m.confirmAction(ActionRestore)
 					}
 				}
 				if msg.Y >= deleteY && msg.Y < deleteY+3 {
 					if !m.ActionInProgress && m.currentSnapshot() != nil {
 						m.FocusedElement = "delete"
-						m.ActionInProgress = true
-						m.ActionMessage = "⏳ Executing delete..."
-						return m, executeActionCmd(ActionDelete, *m.currentSnapshot(), m.SelectedSnapshots, m.Snapshots)
+						return m, m.confirmAction(ActionDelete)
 					}
 				}
 				if msg.Y >= statusY && msg.Y < statusY+3 {
@@ -425,7 +666,7 @@ func (m UIState) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 						m.FocusedElement = "status"
 						m.ActionInProgress = true
 						m.ActionMessage = "⏳ Fetching status..."
-						return m, executeActionCmd(ActionStatus, *m.currentSnapshot(), m.SelectedSnapshots, m.Snapshots)
+						return m, executeActionCmd(m.Client, ActionStatus, *m.currentSnapshot(), m.visibleSelection(), m.Visible)
 					}
 				}
 			}
@@ -472,21 +713,19 @@ func (m UIState) getButtonYPositions() (int, int, int) {
 func (m UIState) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	cmd := tea.Cmd(nil)
 
+	// The filter bar takes every key while it is being typed into
+	if m.FocusedElement == "filter" {
+		return m.handleFilterKey(msg)
+	}
+
 	// Global keys
 	switch msg.String() {
 	case "q", "ctrl+c":
 		return m, tea.Quit
 	case "tab":
-		// Cycle through focused elements
-		elements := []string{"table", "restore", "delete", "status"}
-		for i, e := range elements {
-			if e == m.FocusedElement {
-				m.FocusedElement = elements[(i+1)%len(elements)]
-				return m, nil
-			}
-		}
-		m.FocusedElement = "table"
-		return m, nil
+		return m, m.cycleFocus(1)
+	case "shift+tab":
+		return m, m.cycleFocus(-1)
 	}
 
 	// Element-specific key handling
@@ -497,28 +736,28 @@ func (m UIState) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if !m.Loading {
 				m.Loading = true
 				m.Status = "Refreshing snapshots..."
-				cmd = tea.Batch(refreshSnapshotsCmd(), tickCmd())
+				cmd = tea.Batch(refreshSnapshotsCmd(m.Client), tickCmd())
 			}
 		case "j", "down":
-			if len(m.Snapshots) > 0 {
-				m.Cursor = (m.Cursor + 1) % len(m.Snapshots)
+			if len(m.Visible) > 0 {
+				m.Cursor = (m.Cursor + 1) % len(m.Visible)
 				m.ensureCursorVisible()
 				m.setActionPreview()
 			}
 		case "k", "up":
-			if len(m.Snapshots) > 0 {
-				m.Cursor = (m.Cursor - 1 + len(m.Snapshots)) % len(m.Snapshots)
+			if len(m.Visible) > 0 {
+				m.Cursor = (m.Cursor - 1 + len(m.Visible)) % len(m.Visible)
 				m.ensureCursorVisible()
 				m.setActionPreview()
 			}
 		case "pgdn", "pagedown":
-			if len(m.Snapshots) > 0 {
-				m.Cursor = min(m.Cursor+m.ViewportHeight, len(m.Snapshots)-1)
+			if len(m.Visible) > 0 {
+				m.Cursor = min(m.Cursor+m.ViewportHeight, len(m.Visible)-1)
 				m.ensureCursorVisible()
 				m.setActionPreview()
 			}
 		case "pgup", "pageup":
-			if len(m.Snapshots) > 0 {
+			if len(m.Visible) > 0 {
 				m.Cursor = max(0, m.Cursor-m.ViewportHeight)
 				m.ensureCursorVisible()
 				m.setActionPreview()
@@ -526,12 +765,12 @@ func (m UIState) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case "enter":
 			m.DetailOpen = !m.DetailOpen
 		case " ":
-			if len(m.Snapshots) > 0 {
-				snap := m.Snapshots[m.Cursor]
-				if m.SelectedSnapshots[snap.Number] {
-					delete(m.SelectedSnapshots, snap.Number)
+			if len(m.Visible) > 0 {
+				key := m.Visible[m.Cursor].Key()
+				if m.SelectedSnapshots[key] {
+					delete(m.SelectedSnapshots, key)
 				} else {
-					m.SelectedSnapshots[snap.Number] = true
+					m.SelectedSnapshots[key] = true
 				}
 				m.setActionPreview()
 			}
@@ -541,20 +780,12 @@ func (m UIState) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "restore":
 		if msg.String() == "enter" {
-			if !m.ActionInProgress && m.currentSnapshot() != nil {
-				m.ActionInProgress = true
-				m.ActionMessage = "⏳ Executing apply..."
-				cmd = executeActionCmd(ActionRestore, *m.currentSnapshot(), m.SelectedSnapshots, m.Snapshots)
-			}
+			cmd = m.confirmAction(ActionRestore)
 		}
 
 	case "delete":
 		if msg.String() == "enter" {
-			if !m.ActionInProgress && m.currentSnapshot() != nil {
-				m.ActionInProgress = true
-				m.ActionMessage = "⏳ Executing delete..."
-				cmd = executeActionCmd(ActionDelete, *m.currentSnapshot(), m.SelectedSnapshots, m.Snapshots)
-			}
+			cmd = m.confirmAction(ActionDelete)
 		}
 
 	case "status":
@@ -562,7 +793,7 @@ func (m UIState) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if !m.ActionInProgress && m.currentSnapshot() != nil {
 				m.ActionInProgress = true
 				m.ActionMessage = "⏳ Fetching status..."
-				cmd = executeActionCmd(ActionStatus, *m.currentSnapshot(), m.SelectedSnapshots, m.Snapshots)
+				cmd = executeActionCmd(m.Client, ActionStatus, *m.currentSnapshot(), m.visibleSelection(), m.Visible)
 			}
 		}
 	}
@@ -571,22 +802,64 @@ func (m UIState) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.FocusedElement == "table" {
 		switch msg.String() {
 		case "A", "a":
-			if !m.ActionInProgress && m.currentSnapshot() != nil {
-				m.ActionInProgress = true
-				m.ActionMessage = "⏳ Executing apply..."
-				cmd = executeActionCmd(ActionRestore, *m.currentSnapshot(), m.SelectedSnapshots, m.Snapshots)
-			}
+			cmd = m.confirmAction(ActionRestore)
 		case "D", "d":
-			if !m.ActionInProgress && m.currentSnapshot() != nil {
-				m.ActionInProgress = true
-				m.ActionMessage = "⏳ Executing delete..."
-				cmd = executeActionCmd(ActionDelete, *m.currentSnapshot(), m.SelectedSnapshots, m.Snapshots)
+			cmd = m.confirmAction(ActionDelete)
+		case "c":
+			if !m.ActionInProgress && !m.Placeholder {
+				cmd = m.openCreateForm()
+			}
+		case "m":
+			if !m.ActionInProgress && !m.Placeholder && m.currentSnapshot() != nil {
+				cmd = m.openModifyForm()
+			}
+		case "b":
+			if !m.Placeholder {
+				return m.openBrowser()
+			}
+		case "H":
+			if !m.Placeholder {
+				cmd = m.openHistoryForm()
+			}
+		case "G":
+			if !m.Placeholder {
+				cmd = m.openSearchForm(m.searchTargets(), "", "")
+			}
+		case "C":
+			if !m.Placeholder {
+				return m.openConfigs()
+			}
+		case "X":
+			if !m.ActionInProgress && !m.Placeholder {
+				cmd = m.openCleanupForm()
+			}
+		case "Q":
+			if !m.ActionInProgress && !m.Placeholder {
+				cmd = m.openQuotaForm()
+			}
+		case "/":
+			cmd = m.focusElement("filter")
+		case "v":
+			m.cycleView()
+		case "V":
+			cmd = m.openViewPicker()
+		case "n":
+			m.toggleDryRun()
+		case "L":
+			return m.openAudit()
+		case "esc":
+			if m.CleanupPlan != nil {
+				m.CleanupPlan = nil
+				m.setActionPreview()
+				m.Status = "Discarded the cleanup preview"
+			} else if m.Filter != nil || m.FilterErr != nil {
+				m.clearFilter()
 			}
 		case "s":
 			if !m.ActionInProgress && m.currentSnapshot() != nil {
 				m.ActionInProgress = true
 				m.ActionMessage = "⏳ Fetching status..."
-				cmd = executeActionCmd(ActionStatus, *m.currentSnapshot(), m.SelectedSnapshots, m.Snapshots)
+				cmd = executeActionCmd(m.Client, ActionStatus, *m.currentSnapshot(), m.visibleSelection(), m.Visible)
 			}
 		}
 	}
@@ -595,12 +868,13 @@ func (m UIState) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *UIState) updateSortKey(key string) {
+	// Number keys count the columns on screen, as header clicks do, so a
+	// view that hides columns does not shift them onto hidden ones
 	index := keyToColumnIndex(key)
-	if index < 0 {
+	if index < 0 || index >= len(m.Columns) {
 		return
 	}
-	spec := columnSpecs[index]
-	m.toggleSort(spec.SortField)
+	m.toggleSort(m.Columns[index].SortField)
 }
 
 func (m *UIState) toggleSort(field string) {
@@ -636,16 +910,32 @@ func (m *UIState) sortSnapshots() {
 		}
 		return iVal < jVal
 	})
+	m.applyFilter()
 }
 
 func (m *UIState) currentSnapshot() *Snapshot {
-	if len(m.Snapshots) == 0 {
+	if len(m.Visible) == 0 {
 		return nil
 	}
-	if m.Cursor >= len(m.Snapshots) {
-		m.Cursor = len(m.Snapshots) - 1
+	if m.Cursor >= len(m.Visible) {
+		m.Cursor = len(m.Visible) - 1
+	}
+	return &m.Visible[m.Cursor]
+}
+
+// selectPending moves the cursor to a snapshot requested before it was listed
+func (m *UIState) selectPending() {
+	if m.PendingSelect == nil {
+		return
+	}
+	for i, snap := range m.Visible {
+		if snap.Key() == *m.PendingSelect {
+			m.Cursor = i
+			m.PendingSelect = nil
+			m.ensureCursorVisible()
+			return
+		}
 	}
-	return &m.Snapshots[m.Cursor]
 }
 
 func (m *UIState) ensureCursorVisible() {
@@ -667,20 +957,30 @@ func (m *UIState) ensureCursorVisible() {
 	if m.Offset < 0 {
 		m.Offset = 0
 	}
-	if m.Offset > max(0, len(m.Snapshots)-m.ViewportHeight) {
-		m.Offset = max(0, len(m.Snapshots)-m.ViewportHeight)
+	if m.Offset > max(0, len(m.Visible)-m.ViewportHeight) {
+		m.Offset = max(0, len(m.Visible)-m.ViewportHeight)
 	}
 }
 
 func (m *UIState) setActionPreview() {
-	if snap := m.currentSnapshot(); snap != nil {
-		start := computeStatusStart(*snap)
-		m.ActionMessage = strings.Join([]string{
-			fmt.Sprintf("Apply: sudo snapper rollback %d", snap.Number),
-			fmt.Sprintf("Delete: sudo snapper delete %d", snap.Number),
-			fmt.Sprintf("Status: sudo snapper status %d..%d", start, snap.Number),
-			"[A]pply • [D]elete • [S]tatus • Click buttons or press Tab+Enter",
-		}, "\n")
+	var selected []Snapshot
+	for _, snap := range m.Visible {
+		if m.SelectedSnapshots[snap.Key()] {
+			selected = append(selected, snap)
+		}
+	}
+	m.Reclaim = estimateReclaim(selected, m.Snapshots, m.ConfigExclusive)
+
+	if m.currentSnapshot() != nil {
+		lines := getActionPreview(m.Client, m.actionTargets())
+		// The action panel has room for four lines; the selection's
+		// estimate replaces the key hints
+		if len(m.Reclaim) > 0 {
+			lines = append(lines, reclaimPreview(m.Reclaim))
+		} else {
+			lines = append(lines, "[A]pply • [D]elete • [S]tatus • Click buttons or press Tab+Enter")
+		}
+		m.ActionMessage = strings.Join(lines, "\n")
 		return
 	}
 	m.ActionMessage = "Select a snapshot to preview the snapper commands."
@@ -697,8 +997,33 @@ func (m UIState) View() string {
 		height = 24
 	}
 
+	if m.Form != nil && m.Screen != "" {
+		return m.renderForm(width, height)
+	}
+	if m.Screen == "diff" && m.DiffView != nil {
+		return m.renderDiffView(width, height)
+	}
+	if m.Screen == "browse" && m.BrowserView != nil {
+		return m.renderBrowserView(width, height)
+	}
+	if m.Screen == "history" && m.HistoryView != nil {
+		return m.renderHistoryView(width, height)
+	}
+	if m.Screen == "search" && m.SearchView != nil {
+		return m.renderSearchView(width, height)
+	}
+	if m.Screen == "configs" && m.ConfigsView != nil {
+		return m.renderConfigsView(width, height)
+	}
+	if m.Screen == "audit" && m.AuditView != nil {
+		return m.renderAuditView(width, height)
+	}
+	if m.Screen == "status" && m.StatusView != nil {
+		return m.renderStatusView(width, height)
+	}
+
 	// 1. Header
-	header := headerStyle.Width(width).Render("Snapper TUI")
+	header := headerStyle.Width(width).Render(m.headerTitle())
 
 	// 2. Main content
 	var mainContent string
@@ -714,21 +1039,35 @@ func (m UIState) View() string {
 		// We want right panel to match this roughly
 		maxContentHeight := m.ViewportHeight + 3
 
-		tableView := m.renderTable()
-		rightPanel := m.renderRightPanel(maxContentHeight)
+		if m.Form != nil {
+			mainContent = m.renderForm(width, maxContentHeight)
+		} else {
+			tableView := m.renderTable()
+			rightPanel := m.renderRightPanel(maxContentHeight)
 
-		// Use JoinHorizontal to combine them safely
-		mainContent = lipgloss.JoinHorizontal(lipgloss.Top, tableView, "  ", rightPanel)
+			// Use JoinHorizontal to combine them safely
+			mainContent = lipgloss.JoinHorizontal(lipgloss.Top, tableView, "  ", rightPanel)
+		}
 	}
 
-	// 3. Action message
+	// 3. Filter bar
+	filterBar := m.renderFilterBar(width)
+
+	// 4. Action message
 	actionMsg := panelStyle.Width(width - 2).Render(m.ActionMessage)
 
-	// 4. Summary
-	summary := summaryStyle.Render(m.Summary)
+	// 5. Summary
+	summaryText := m.Summary
+	if len(m.Reclaim) > 0 {
+		summaryText += " | " + reclaimSummary(m.Reclaim)
+	}
+	if len(m.QuotaOff) > 0 {
+		summaryText += fmt.Sprintf(" | Quotas off for %s (Q: setup-quota)", strings.Join(m.QuotaOff, ", "))
+	}
+	summary := summaryStyle.Render(summaryText)
 
-	// 5. Footer
-	footerText := "q: Quit | r: Refresh | Tab: Navigate | Enter: Select"
+	// 6. Footer
+	footerText := "q: Quit | r: Refresh | /: Filter | v/V: Views | c: Create | m: Modify | b: Browse | X: Cleanup | Tab: Navigate | Enter: Select"
 	footer := footerStyle.Width(width).Render(footerText)
 
 	// Combine all parts vertically
@@ -736,6 +1075,7 @@ func (m UIState) View() string {
 	ui := lipgloss.JoinVertical(lipgloss.Left,
 		header,
 		mainContent,
+		filterBar,
 		actionMsg,
 		summary,
 		footer,
@@ -761,7 +1101,7 @@ func (m UIState) renderTable() string {
 
 	// Header: "📋 " + column labels
 	var headerCells []string
-	for _, spec := range columnSpecs {
+	for _, spec := range m.Columns {
 		headerCells = append(headerCells, padOrTruncate(spec.Label, spec.Width))
 	}
 	headerLine := "📋 " + strings.Join(headerCells, " ")
@@ -780,8 +1120,11 @@ func (m UIState) renderTable() string {
 	}
 	b.WriteString("\n")
 
-	if len(m.Snapshots) == 0 {
+	if len(m.Visible) == 0 {
 		emptyMsg := "No snapshots available. Press r to try again."
+		if len(m.Snapshots) > 0 {
+			emptyMsg = "No snapshots match the filter. Press esc to clear it."
+		}
 		emptyMsg = padOrTruncate(emptyMsg, tableWidth)
 		b.WriteString(emptyMsg)
 		b.WriteString("\n")
@@ -789,18 +1132,25 @@ func (m UIState) renderTable() string {
 	}
 
 	// Determine viewport range
-	endIdx := min(m.Offset+m.ViewportHeight, len(m.Snapshots))
+	endIdx := min(m.Offset+m.ViewportHeight, len(m.Visible))
 
 	// Show rows only in viewport
 	for idx := m.Offset; idx < endIdx; idx++ {
-		snap := m.Snapshots[idx]
+		snap := m.Visible[idx]
 
 		// Row style
 		var rowStyle lipgloss.Style
-		isSelected := m.SelectedSnapshots[snap.Number]
+		isSelected := m.SelectedSnapshots[snap.Key()]
+
+		doomed := m.CleanupPlan.Dooms(snap.Key())
 
 		if idx == m.Cursor && m.FocusedElement == "table" {
 			rowStyle = focusedStyle
+			if doomed {
+				rowStyle = rowStyle.Strikethrough(true)
+			}
+		} else if doomed {
+			rowStyle = doomedStyle
 		} else if isSelected {
 			rowStyle = selectedStyle
 		} else {
@@ -809,13 +1159,16 @@ func (m UIState) renderTable() string {
 
 		// Render columns
 		var rowCells []string
-		for colIdx, spec := range columnSpecs {
+		for colIdx, spec := range m.Columns {
 			val := spec.Accessor(snap)
 
 			// Add selection indicator to the first column
 			if colIdx == 0 && isSelected {
 				val = "✔ " + val
 			}
+			if colIdx == 0 && doomed {
+				val = "✗ " + val
+			}
 
 			// Pad/Truncate
 			cell := padOrTruncate(val, spec.Width)
@@ -839,9 +1192,9 @@ func (m UIState) renderTable() string {
 	}
 
 	// Show scrollbar indicator
-	if len(m.Snapshots) > m.ViewportHeight {
-		scrollPercent := int((float64(m.Offset) / float64(len(m.Snapshots)-m.ViewportHeight)) * 100)
-		scrollLine := fmt.Sprintf("Scroll: %d%% [%d-%d of %d]", scrollPercent, m.Offset+1, endIdx, len(m.Snapshots))
+	if len(m.Visible) > m.ViewportHeight {
+		scrollPercent := int((float64(m.Offset) / float64(len(m.Visible)-m.ViewportHeight)) * 100)
+		scrollLine := fmt.Sprintf("Scroll: %d%% [%d-%d of %d]", scrollPercent, m.Offset+1, endIdx, len(m.Visible))
 		scrollLine = padOrTruncate(scrollLine, tableWidth)
 		b.WriteString(scrollLine)
 		b.WriteString("\n")
@@ -905,7 +1258,7 @@ func (m UIState) renderRightPanel(maxHeight int) string {
 					fmt.Sprintf("Cleanup: %s", nonEmpty(snap.Cleanup, "<none>")),
 					fmt.Sprintf("Pre #: %s | Post #: %s", nullableInt(snap.PreNumber), nullableInt(snap.PostNumber)),
 					fmt.Sprintf("Default: %s | Active: %s", boolText(snap.Default), boolText(snap.Active)),
-					fmt.Sprintf("Size: %s", humanReadableBytes(snap.UsedSpace)),
+					fmt.Sprintf("Size: %s | Refer: %s | Excl: %s", humanReadableBytes(snap.UsedSpace), humanReadableBytes(snap.Referenced), humanReadableBytes(snap.Exclusive)),
 					fmt.Sprintf("Data: %s", nonEmpty(flattenUserData(snap.Userdata), "<none>")),
 				}
 
@@ -979,13 +1332,51 @@ func (m UIState) renderRightPanel(maxHeight int) string {
 }
 
 // Command functions
-func refreshSnapshotsCmd() tea.Cmd {
+func refreshSnapshotsCmd(client SnapperClient) tea.Cmd {
 	return func() tea.Msg {
-		snaps, err := listSnapshots()
-		return RefreshResultMsg{Snapshots: snaps, Err: err}
+		snaps, err := client.List()
+		if err != nil {
+			return RefreshResultMsg{Err: err}
+		}
+		snaps, quotaOff := readQgroupSizes(client, snaps)
+		return RefreshResultMsg{Snapshots: snaps, QuotaOff: quotaOff, ConfigExclusive: readConfigExclusive(client, snaps)}
 	}
 }
 
+// deleteSnapshots deletes the targets with one "snapper -c <cfg> delete 1 2 3"
+// per config, since snapper numbers are per config. A failing config does
+// not stop the others; the result lists what was deleted and what failed
+func deleteSnapshots(client SnapperClient, snap Snapshot, targets []Snapshot) ActionResultMsg {
+	msg := ActionResultMsg{Kind: ActionDelete, Snap: snap}
+	var failures, deleted, failedConfigs []string
+	for _, group := range groupByConfig(targets) {
+		var nums []int
+		for _, t := range group {
+			nums = append(nums, t.Number)
+		}
+		config := group[0].Config
+		output, err := client.Delete(config, nums)
+		if err != nil {
+			failedConfigs = append(failedConfigs, config)
+			failures = append(failures, fmt.Sprintf("[%s] %s", config, nonEmpty(output, err.Error())))
+			continue
+		}
+		deleted = append(deleted, config+" "+strings.Trim(fmt.Sprint(nums), "[]"))
+		msg.Snapshots = append(msg.Snapshots, group...)
+	}
+
+	if len(failures) == 0 {
+		msg.Output = "All selected snapshots deleted."
+		return msg
+	}
+	msg.Err = fmt.Errorf("delete failed in %s", strings.Join(failedConfigs, ", "))
+	msg.Output = strings.Join(failures, "\n")
+	if len(deleted) > 0 {
+		msg.Output += "\nDeleted: " + strings.Join(deleted, "; ")
+	}
+	return msg
+}
+
 func tickCmd() tea.Cmd {
 	return tea.Tick(time.Millisecond*120, func(t time.Time) tea.Msg {
 		return TickMsg(t)
@@ -998,14 +1389,14 @@ func waitRefreshCmd(d time.Duration) tea.Cmd {
 	})
 }
 
-func executeActionCmd(kind ActionKind, snap Snapshot, selected map[int]bool, allSnaps []Snapshot) tea.Cmd {
+func executeActionCmd(client SnapperClient, kind ActionKind, snap Snapshot, selected map[SnapshotKey]bool, allSnaps []Snapshot) tea.Cmd {
 	return func() tea.Msg {
 		// Determine targets
 		var targets []Snapshot
 		if len(selected) > 0 {
 			// If selection exists, use it
 			for _, s := range allSnaps {
-				if selected[s.Number] {
+				if selected[s.Key()] {
 					targets = append(targets, s)
 				}
 			}
@@ -1028,49 +1419,25 @@ func executeActionCmd(kind ActionKind, snap Snapshot, selected map[int]bool, all
 
 		// Execute
 		if kind == ActionDelete {
-			// Batch delete
-			// Optimization: check if we can do batch delete with snapper
-			// snapper delete 1 2 3
-			var args []string
-			args = append(args, "delete")
-			for _, t := range targets {
-				args = append(args, fmt.Sprint(t.Number))
-			}
-
-			cmd := exec.Command("snapper", args...)
-			output, err := cmd.CombinedOutput()
-			if err != nil {
-				return ActionResultMsg{
-					Kind:   kind,
-					Snap:   snap, // Representative
-					Err:    err,
-					Output: string(output),
-				}
-			}
-			return ActionResultMsg{
-				Kind:   kind,
-				Snap:   snap,
-				Err:    nil,
-				Output: "All selected snapshots deleted.",
-			}
+			return deleteSnapshots(client, snap, targets)
 		}
 
 		// Single action (Restore/Status)
 		// Re-use existing logic for single action
 		target := targets[0]
-		var args []string
+		var output string
+		var err error
 		switch kind {
 		case ActionRestore:
-			args = []string{"rollback", fmt.Sprint(target.Number)}
+			output, err = client.Rollback(target.Config, target.Number)
 		case ActionStatus:
-			start := computeStatusStart(target)
-			args = []string{"status", fmt.Sprintf("%d..%d", start, target.Number)}
+			output, err = client.Status(target.Config, computeStatusStart(target), target.Number)
 		}
 
-		cmd := exec.Command("snapper", args...)
-		output, err := cmd.CombinedOutput()
-		trimmed := strings.TrimSpace(string(output))
-		if len(trimmed) > 500 {
+		// Status output goes to the pager in full; other output lands in the
+		// small action panel
+		trimmed := output
+		if kind != ActionStatus && len(trimmed) > 500 {
 			trimmed = trimmed[:497] + "..."
 		}
 		return ActionResultMsg{Kind: kind, Snap: target, Output: trimmed, Err: err}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// testSnapshots spans two configs with overlapping numbers
+var testSnapshots = []Snapshot{
+	{Config: "root", Subvolume: "/nonexistent/root", Number: 1, SnapshotType: "single", Date: "2025-11-18 08:00:00", User: "root", Cleanup: "number", Description: "root one"},
+	{Config: "root", Subvolume: "/nonexistent/root", Number: 2, SnapshotType: "single", Date: "2025-11-18 09:00:00", User: "root", Cleanup: "number", Description: "root two"},
+	{Config: "root", Subvolume: "/nonexistent/root", Number: 3, SnapshotType: "single", Date: "2025-11-18 10:00:00", User: "root", Cleanup: "timeline", Description: "root three"},
+	{Config: "home", Subvolume: "/nonexistent/home", Number: 1, SnapshotType: "single", Date: "2025-11-18 08:30:00", User: "root", Cleanup: "number", Description: "home one"},
+	{Config: "home", Subvolume: "/nonexistent/home", Number: 2, SnapshotType: "single", Date: "2025-11-18 09:30:00", User: "root", Cleanup: "number", Description: "home two"},
+}
+
+// newTestModel returns a model on a fake backend with the given snapshots
+// loaded, sorted by date; nothing asks for confirmation unless a test sets
+// its own rules
+func newTestModel(t *testing.T, snaps []Snapshot) (UIState, *FakeClient) {
+	t.Helper()
+	fake := newFakeClient(snaps)
+	m := initialModel(fake)
+	m.Events = nil // live updates are driven explicitly by the tests that want them
+	m.ConfirmRules = ConfirmRules{ActionDelete: confirmNone, ActionRestore: confirmNone, ActionUndoChange: confirmNone}
+	m = update(t, m, tea.WindowSizeMsg{Width: 160, Height: 40})
+	m.SortKey = "date"
+	m = drive(t, m, refreshSnapshotsCmd(m.Client))
+	if m.Loading || m.Placeholder {
+		t.Fatalf("snapshots not loaded: %s", m.Status)
+	}
+	return m, fake
+}
+
+// update feeds one message to the model
+func update(t *testing.T, m UIState, msg tea.Msg) UIState {
+	t.Helper()
+	model, cmd := m.Update(msg)
+	return drive(t, model.(UIState), cmd)
+}
+
+// drive runs a command and feeds whatever it returns back through Update
+// until no command is left; spinner ticks are dropped
+func drive(t *testing.T, m UIState, cmd tea.Cmd) UIState {
+	t.Helper()
+	for depth := 0; cmd != nil; depth++ {
+		if depth > 50 {
+			t.Fatal("commands keep coming back")
+		}
+		switch msg := cmd().(type) {
+		case nil, TickMsg:
+			return m
+		case tea.BatchMsg:
+			for _, c := range msg {
+				m = drive(t, m, c)
+			}
+			return m
+		default:
+			var model tea.Model
+			model, cmd = m.Update(msg)
+			m = model.(UIState)
+		}
+	}
+	return m
+}
+
+// press sends keys to the model; single characters are typed as runes
+func press(t *testing.T, m UIState, keys ...string) UIState {
+	t.Helper()
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		case "backspace":
+			msg = tea.KeyMsg{Type: tea.KeyBackspace}
+		case " ":
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		m = update(t, m, msg)
+	}
+	return m
+}
+
+// moveTo puts the cursor on a snapshot
+func moveTo(t *testing.T, m UIState, config string, number int) UIState {
+	t.Helper()
+	for i, snap := range m.Visible {
+		if snap.Config == config && snap.Number == number {
+			m.Cursor = i
+			return m
+		}
+	}
+	t.Fatalf("%s #%d is not shown", config, number)
+	return m
+}
+
+// keys lists the snapshots of the table in order
+func keys(snaps []Snapshot) string {
+	var out []string
+	for _, snap := range snaps {
+		out = append(out, fmt.Sprintf("%s#%d", snap.Config, snap.Number))
+	}
+	return strings.Join(out, " ")
+}
+
+func TestRefreshListsSnapshots(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	if got, want := keys(m.Visible), "root#1 home#1 root#2 home#2 root#3"; got != want {
+		t.Errorf("rows = %s, want %s", got, want)
+	}
+	if m.Status != "Loaded 5 snapshots" {
+		t.Errorf("status = %q", m.Status)
+	}
+
+	// A snapshot appearing in the backend shows up on refresh, with the
+	// cursor left where it was
+	m = moveTo(t, m, "root", 2)
+	fake.Snapshots = append(fake.Snapshots, Snapshot{Config: "home", Subvolume: "/nonexistent/home", Number: 3, SnapshotType: "single", Date: "2025-11-18 11:00:00"})
+	m = press(t, m, "r")
+	if got := keys(m.Visible); !strings.HasSuffix(got, "home#3") {
+		t.Errorf("rows after refresh = %s", got)
+	}
+	if snap := m.currentSnapshot(); snap.Config != "root" || snap.Number != 2 {
+		t.Errorf("cursor moved to %s #%d", snap.Config, snap.Number)
+	}
+}
+
+func TestRefreshFailureFallsBackToSampleData(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	fake.Failures["list"] = errors.New("snapperd is not running")
+	m = press(t, m, "r")
+	if !m.Placeholder || !strings.Contains(m.Status, "snapperd is not running") {
+		t.Errorf("placeholder = %v, status = %q", m.Placeholder, m.Status)
+	}
+	if len(m.Visible) != len(sampleSnapshots) {
+		t.Errorf("%d rows shown, want the %d samples", len(m.Visible), len(sampleSnapshots))
+	}
+}
+
+func TestRefreshLatencyKeepsLoading(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	fake.Latencies["list"] = 50 * time.Millisecond
+
+	model, cmd := m.Update(RefreshTriggerMsg{})
+	m = model.(UIState)
+	if !m.Loading || m.Status != "Refreshing snapshots..." {
+		t.Fatalf("loading = %v, status = %q", m.Loading, m.Status)
+	}
+	// A second refresh while the first is still running is ignored
+	if _, again := m.Update(RefreshTriggerMsg{}); again != nil {
+		t.Error("a second refresh was started")
+	}
+
+	start := time.Now()
+	m = drive(t, m, cmd)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("refresh took %v, want at least the injected latency", elapsed)
+	}
+	if m.Loading {
+		t.Error("still loading after the refresh returned")
+	}
+}
+
+func TestDeleteAcrossConfigs(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	m = moveTo(t, m, "root", 1)
+	m = press(t, m, " ")
+	m = moveTo(t, m, "home", 1)
+	m = press(t, m, " ")
+	m = moveTo(t, m, "root", 3)
+	m = press(t, m, " ")
+	fake.Calls = nil
+
+	m = press(t, m, "d")
+	want := []string{"-c root delete 1 3", "-c home delete 1", "list"}
+	if fmt.Sprint(fake.Calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", fake.Calls, want)
+	}
+	if got, want := keys(m.Visible), "root#2 home#2"; got != want {
+		t.Errorf("rows = %s, want %s", got, want)
+	}
+	if len(m.SelectedSnapshots) != 0 {
+		t.Errorf("selection not cleared: %v", m.SelectedSnapshots)
+	}
+}
+
+func TestDeleteFailureKeepsSnapshots(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	fake.Failures["delete"] = errors.New("snapshot is in use")
+	m = moveTo(t, m, "home", 2)
+	m = press(t, m, " ", "d")
+	if !strings.Contains(m.ActionMessage, "Delete failed: [home] snapshot is in use") || m.Status != "Delete failed" {
+		t.Errorf("action message = %q, status = %q", m.ActionMessage, m.Status)
+	}
+	if len(fake.Snapshots) != len(testSnapshots) || len(m.Visible) != len(testSnapshots) {
+		t.Errorf("snapshots removed after a failed delete")
+	}
+	if !m.SelectedSnapshots[SnapshotKey{Config: "home", Number: 2}] {
+		t.Error("selection dropped after a failed delete")
+	}
+	if m.ActionInProgress {
+		t.Error("action still in progress")
+	}
+}
+
+func TestDeleteFailureInOneConfigDeletesTheOthers(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	fake.Failures["delete:root"] = errors.New("snapshot is in use")
+	m = moveTo(t, m, "root", 1)
+	m = press(t, m, " ")
+	m = moveTo(t, m, "home", 1)
+	m = press(t, m, " ")
+	m = moveTo(t, m, "root", 3)
+	m = press(t, m, " ")
+	fake.Calls = nil
+
+	m = press(t, m, "d")
+	// The config after the failing one is still tried, and the list is
+	// refreshed despite the failure
+	want := []string{"-c root delete 1 3", "-c home delete 1", "list"}
+	if fmt.Sprint(fake.Calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", fake.Calls, want)
+	}
+	if got, want := keys(m.Visible), "root#1 root#2 home#2 root#3"; got != want {
+		t.Errorf("rows = %s, want %s", got, want)
+	}
+	if want := "Delete failed: [root] snapshot is in use\nDeleted: home 1"; m.ActionMessage != want {
+		t.Errorf("action message = %q, want %q", m.ActionMessage, want)
+	}
+	if m.Status != "Delete failed in part: delete failed in root" {
+		t.Errorf("status = %q", m.Status)
+	}
+	if len(m.SelectedSnapshots) != 2 || m.SelectedSnapshots[SnapshotKey{Config: "home", Number: 1}] {
+		t.Errorf("selection = %v, want only the snapshots left", m.SelectedSnapshots)
+	}
+}
+
+func TestRollbackMarksDefault(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	m = moveTo(t, m, "home", 2)
+	m = press(t, m, "a")
+	if m.Status != "Applied snapshot 2" {
+		t.Errorf("status = %q", m.Status)
+	}
+	for _, snap := range fake.Snapshots {
+		if snap.Default != (snap.Config == "home" && snap.Number == 2) {
+			t.Errorf("%s #%d default = %v", snap.Config, snap.Number, snap.Default)
+		}
+	}
+}
+
+func TestCreateSelectsNewSnapshot(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	m = moveTo(t, m, "home", 1)
+	m = press(t, m, "c")
+	if m.FormKind != "create" || m.CreateInput.Config != "home" {
+		t.Fatalf("form = %q, input = %+v", m.FormKind, m.CreateInput)
+	}
+	m.CreateInput.Description = "before upgrade"
+	m.CreateInput.Userdata = "important=yes"
+	_, model, cmd := m.submitForm()
+	m = model.(UIState)
+	if !m.ActionInProgress {
+		t.Error("create not started")
+	}
+
+	// Create → PendingSelect → refresh → cursor on the new snapshot
+	m = drive(t, m, cmd)
+	if m.PendingSelect != nil {
+		t.Errorf("pending selection left over: %+v", *m.PendingSelect)
+	}
+	snap := m.currentSnapshot()
+	if snap.Config != "home" || snap.Number != 3 || snap.Description != "before upgrade" || snap.Userdata["important"] != "yes" {
+		t.Errorf("cursor on %+v", *snap)
+	}
+	if got := fake.Calls[len(fake.Calls)-2]; got != "-c home create --type single" {
+		t.Errorf("create call = %q", got)
+	}
+}
+
+func TestCreateFailureReported(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	fake.Failures["create"] = errors.New("no space left on device")
+	m = press(t, m, "c")
+	_, model, cmd := m.submitForm()
+	m = drive(t, model.(UIState), cmd)
+	if m.Status != "Create failed" || !strings.Contains(m.ActionMessage, "no space left on device") {
+		t.Errorf("status = %q, action message = %q", m.Status, m.ActionMessage)
+	}
+	if m.PendingSelect != nil {
+		t.Error("a failed create left a pending selection")
+	}
+}
+
+func TestLiveEventPatchesTable(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	events, err := fake.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = moveTo(t, m, "root", 2)
+	if _, err := fake.Create(CreateOptions{Config: "root", Type: "single", Description: "timeline"}); err != nil {
+		t.Fatal(err)
+	}
+	// Feed the one pending event; the command it returns would wait for the next
+	model, _ := m.Update(watchEventsCmd(fake, events)())
+	m = model.(UIState)
+	if got := keys(m.Visible); !strings.HasSuffix(got, "root#4") {
+		t.Errorf("rows = %s", got)
+	}
+	if snap := m.currentSnapshot(); snap.Config != "root" || snap.Number != 2 {
+		t.Errorf("cursor moved to %s #%d", snap.Config, snap.Number)
+	}
+}
+
+func TestDryRunRestoreIsRecorded(t *testing.T) {
+	m, _ := newTestModel(t, testSnapshots)
+	m.Recorder.SetDryRun(true)
+	live := t.TempDir() + "/fstab"
+	m.FormKind = "restore"
+	m.RestoreInput = &restoreFormInput{Snap: testSnapshots[0], Rel: "/fstab", Live: live, Confirmed: true}
+	_, model, cmd := m.submitForm()
+	m = drive(t, model.(UIState), cmd)
+
+	log := m.Recorder.Log()
+	if len(log) != 1 || log[0].Action != "restore-file" || log[0].Path != live || !log[0].DryRun {
+		t.Fatalf("log = %+v", log)
+	}
+	if m.Status != "Dry run: restore-file "+live || m.ActionInProgress {
+		t.Errorf("status = %q, in progress = %v", m.Status, m.ActionInProgress)
+	}
+}
+
+func TestConfirmedDeleteRunsListedTargets(t *testing.T) {
+	m, fake := newTestModel(t, testSnapshots)
+	m.ConfirmRules = ConfirmRules{ActionDelete: confirmBatch}
+	m = moveTo(t, m, "root", 1)
+	m = press(t, m, " ")
+	m = moveTo(t, m, "home", 2)
+	m = press(t, m, " ", "d")
+	if m.FormKind != "confirm" {
+		t.Fatalf("form = %q", m.FormKind)
+	}
+	// A batch is confirmed with its highest snapshot number, not the count
+	if m.ConfirmInput.Expected != "2" {
+		t.Errorf("expected text = %q", m.ConfirmInput.Expected)
+	}
+
+	// The selection changes behind the dialog; only what it listed is deleted
+	m.SelectedSnapshots[SnapshotKey{Config: "root", Number: 3}] = true
+	fake.Calls = nil
+	m.ConfirmInput.Typed = "2"
+	_, model, cmd := m.submitForm()
+	m = drive(t, model.(UIState), cmd)
+	want := []string{"-c root delete 1", "-c home delete 2", "list"}
+	if fmt.Sprint(fake.Calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", fake.Calls, want)
+	}
+}
+
+func TestNumberKeysSortShownColumns(t *testing.T) {
+	m, _ := newTestModel(t, testSnapshots)
+	m.Columns = []ColumnSpec{*findColumn("description"), *findColumn("number")}
+	m = press(t, m, "2")
+	if m.SortKey != "number" {
+		t.Errorf("2 sorts by %q, want the second shown column", m.SortKey)
+	}
+	if got, want := keys(m.Visible), "root#1 home#1 root#2 home#2 root#3"; got != want {
+		t.Errorf("rows = %s, want %s", got, want)
+	}
+	// Keys past the shown columns do nothing
+	m = press(t, m, "5")
+	if m.SortKey != "number" {
+		t.Errorf("5 sorts by %q", m.SortKey)
+	}
+}
+
+func TestModifyCostsOneCallPerTarget(t *testing.T) {
+	snaps := append([]Snapshot(nil), testSnapshots...)
+	snaps[1].Userdata = map[string]string{"important": "yes"}
+	m, fake := newTestModel(t, snaps)
+	m = moveTo(t, m, "root", 2)
+	m = press(t, m, "m")
+	if m.FormKind != "modify" {
+		t.Fatalf("form = %q", m.FormKind)
+	}
+	m.ModifyInput.Description = "kept"
+	m.ModifyInput.Userdata = ""
+	fake.Calls = nil
+	_, model, cmd := m.submitForm()
+	m = drive(t, model.(UIState), cmd)
+
+	want := []string{"-c root modify 2", "-c root list 2"}
+	if fmt.Sprint(fake.Calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", fake.Calls, want)
+	}
+	// The userdata listed with the row is what the CLI is told to clear
+	log := m.Recorder.Log()
+	if len(log) != 1 || !strings.Contains(log[0].command(), "--userdata important=") {
+		t.Errorf("log = %+v", log)
+	}
+	if snap := m.currentSnapshot(); snap.Description != "kept" || snap.Userdata != nil {
+		t.Errorf("row = %+v", *snap)
+	}
+}
+
+func TestOpenDialogPassesResizesAndResultsThrough(t *testing.T) {
+	m, _ := newTestModel(t, testSnapshots)
+	m = press(t, m, "c")
+	if m.FormKind != "create" {
+		t.Fatalf("form = %q", m.FormKind)
+	}
+
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 30})
+	if m.TermWidth != 100 || m.TermHeight != 30 || m.ViewportHeight != 16 {
+		t.Errorf("layout = %dx%d, viewport %d", m.TermWidth, m.TermHeight, m.ViewportHeight)
+	}
+	// A result that was in flight when the dialog opened still lands
+	m = update(t, m, StatusSavedMsg{Path: "/root/status.txt"})
+	if m.Status != "Saved status output to /root/status.txt" {
+		t.Errorf("status = %q", m.Status)
+	}
+	if m.FormKind != "create" || m.Form == nil {
+		t.Errorf("dialog closed: %q", m.FormKind)
+	}
+}
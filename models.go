@@ -1,6 +1,11 @@
 package main
 
-import "time"
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/huh"
+)
 
 // Snapshot represents a snapper snapshot
 type Snapshot struct {
@@ -16,10 +21,58 @@ type Snapshot struct {
 	Description  string
 	Userdata     map[string]string
 	UsedSpace    *int64
+	Referenced   *int64 // bytes of the snapshot's qgroup, nil without quotas
+	Exclusive    *int64 // bytes only this snapshot holds, nil without quotas
 	Default      bool
 	Active       bool
 }
 
+// CreateOptions describes a snapshot to create
+type CreateOptions struct {
+	Config      string
+	Type        string // "single", "pre" or "post"
+	PreNumber   int    // pre snapshot a post snapshot pairs with
+	Description string
+	Cleanup     string
+	Userdata    map[string]string
+}
+
+// ModifyOptions holds the metadata a snapshot should have after "snapper modify";
+// Userdata is the complete desired set, not a delta
+type ModifyOptions struct {
+	Description string
+	Cleanup     string
+	Userdata    map[string]string
+	Current     map[string]string // userdata the snapshot has now, as listed
+}
+
+// ConfigInfo is a snapper config and its settings, keyed as in the config
+// file (SUBVOLUME, NUMBER_LIMIT, TIMELINE_CREATE, ...)
+type ConfigInfo struct {
+	Name      string
+	Subvolume string
+	Values    map[string]string
+}
+
+// ConfigOptions describes a config for "snapper create-config"
+type ConfigOptions struct {
+	Name      string
+	Subvolume string
+	FSType    string // "btrfs", "lvm(xfs)", ...
+	Template  string // file in the config-templates directory
+}
+
+// SnapshotKey identifies a snapshot across configs; numbers are only unique per config
+type SnapshotKey struct {
+	Config string
+	Number int
+}
+
+// Key returns the config-qualified identity of the snapshot
+func (s Snapshot) Key() SnapshotKey {
+	return SnapshotKey{Config: s.Config, Number: s.Number}
+}
+
 // ColumnSpec defines a column in the snapshot table
 type ColumnSpec struct {
 	Key       string
@@ -31,7 +84,17 @@ type ColumnSpec struct {
 
 // UIState represents the state of the application
 type UIState struct {
+	Client            SnapperClient
+	Recorder          *RecorderClient // logs every action and holds the dry-run switch; wraps Client
 	Snapshots         []Snapshot
+	Visible           []Snapshot      // rows passing Filter, in table order; Cursor and Offset index these
+	Filter            *SnapshotFilter // parsed filter bar query, nil when the bar is empty
+	FilterInput       textinput.Model // the filter bar
+	FilterErr         error           // why the typed query does not parse
+	Columns           []ColumnSpec    // table columns in order, from the active view
+	Views             *ViewSet        // saved views from the system and user files
+	ViewName          string          // active saved view, "" for the default
+	ViewInput         *viewFormInput  // choice in the view picker
 	Cursor            int
 	Offset            int // for scrolling
 	SortKey           string
@@ -45,13 +108,49 @@ type UIState struct {
 	ActionInProgress  bool
 	DetailOpen        bool
 	SelectedSnapshot  *Snapshot
-	SelectedSnapshots map[int]bool // Set of selected snapshot numbers
-	FocusedElement    string       // "table", "restore", "delete", "status"
+	SelectedSnapshots map[SnapshotKey]bool // Set of selected snapshots
+	FocusedElement    string               // "filter", "table", "restore", "delete", "status"
 	TableRect         Rect
 	ButtonRects       map[string]Rect // button ID -> rectangle
 	TermWidth         int
 	TermHeight        int
-	ViewportHeight    int // how many rows fit on screen
+	ViewportHeight    int                 // how many rows fit on screen
+	Events            <-chan SnapperEvent // live snapperd updates, nil when unavailable
+	Form              *huh.Form           // active dialog, nil when none is open
+	FormKind          string              // "create", "modify", "undochange", "restore", "history", "search", "setconfig", "createconfig", "deleteconfig", "cleanup", "runcleanup", "setupquota", "confirm", "views", "summary"
+	CreateInput       *createFormInput    // values bound to the create dialog
+	ModifyInput       *modifyFormInput    // values bound to the modify dialog
+	UndoInput         *undoFormInput      // files awaiting the undochange confirmation
+	RestoreInput      *restoreFormInput   // path awaiting the restore confirmation
+	HistoryInput      *historyFormInput   // path typed into the file history prompt
+	SearchInput       *searchFormInput    // pattern and glob of the search dialog
+	ConfigInput       *configFormInput    // config setting being edited
+	WizardInput       *configWizardInput  // config being created or deleted
+	CleanupInput      *cleanupFormInput   // cleanup being simulated or confirmed
+	CleanupPlan       *CleanupPlan        // simulated cleanup whose snapshots are marked in the table
+	QuotaInput        *quotaFormInput     // config awaiting the setup-quota confirmation
+	ConfirmInput      *confirmFormInput   // rollback or delete awaiting confirmation
+	ConfirmRules      ConfirmRules        // how each destructive action is confirmed
+	QuotaOff          []string            // configs on btrfs with quota groups disabled
+	Reclaim           []ReclaimEstimate   // space deleting the selection would free, per config
+	ConfigExclusive   map[string]int64    // exclusive bytes of each config's QGROUP, as of the last refresh
+	PendingSelect     *SnapshotKey        // snapshot to move the cursor to once it is listed
+	PendingNotice     *Notice             // outcome of a failed action, shown again once the refresh it started lands
+	Screen            string              // full-screen view replacing the table: "", "status", "diff", "browse", "history", "search", "configs" or "audit"
+	StatusView        *StatusView         // status pager, set while Screen is "status"
+	StatusTree        bool                // open the status viewer as a directory tree
+	DiffView          *DiffView           // per-file diff, set while Screen is "diff"
+	BrowserView       *BrowserView        // snapshot file browser, set while Screen is "browse"
+	HistoryView       *HistoryView        // versions of one file, set while Screen is "history"
+	SearchView        *SearchView         // content search results, kept while a match is browsed
+	ConfigsView       *ConfigsView        // snapper configs and their settings, set while Screen is "configs"
+	AuditView         *AuditView          // audit log browser, set while Screen is "audit"
+}
+
+// Notice is a status line and action panel message to show later
+type Notice struct {
+	Status  string
+	Message string
 }
 
 // Rect represents a rectangular area for mouse tracking
@@ -67,20 +166,82 @@ const (
 	ActionRestore
 	ActionDelete
 	ActionStatus
+	ActionCreate
+	ActionModify
+	ActionUndoChange
+	ActionRestoreFile
+	ActionCleanup
+	ActionSetupQuota
 )
 
+// String returns the lowercase action name used in messages
+func (k ActionKind) String() string {
+	switch k {
+	case ActionRestore:
+		return "rollback"
+	case ActionDelete:
+		return "delete"
+	case ActionStatus:
+		return "status"
+	case ActionCreate:
+		return "create"
+	case ActionModify:
+		return "modify"
+	case ActionUndoChange:
+		return "undochange"
+	case ActionRestoreFile:
+		return "restore-file"
+	case ActionCleanup:
+		return "cleanup"
+	case ActionSetupQuota:
+		return "setup-quota"
+	default:
+		return "unknown"
+	}
+}
+
 // ActionResult represents the result of an action
 type ActionResult struct {
-	Kind   ActionKind
-	Snap   Snapshot
+	Kind      ActionKind
+	Snap      Snapshot
+	Snapshots []Snapshot   // refreshed rows for actions that patch the list in place; the deleted rows of a delete
+	Files     []FileResult // per-file outcome of actions on individual files
+	Output    string
+	Err       error
+}
+
+// FileResult is the outcome of an action on one file of a snapshot
+type FileResult struct {
+	Path   string
+	Effect string // what the action does to the file: "create", "modify" or "delete"
 	Output string
 	Err    error
 }
 
+// SnapperEventKind represents the kind of change pushed by snapperd
+type SnapperEventKind int
+
+const (
+	EventSnapshotCreated SnapperEventKind = iota
+	EventSnapshotModified
+	EventSnapshotsDeleted
+	EventConfigCreated
+	EventConfigDeleted
+)
+
+// SnapperEvent is a snapshot or config change announced by snapperd
+type SnapperEvent struct {
+	Kind    SnapperEventKind
+	Config  string
+	Numbers []int
+}
+
 // RefreshResult represents the result of a refresh operation
 type RefreshResult struct {
-	Snapshots []Snapshot
-	Err       error
+	Snapshots       []Snapshot
+	QuotaOff        []string
+	ConfigExclusive map[string]int64
+	Err             error
 }
 
 // Custom message types for Bubble Tea
@@ -88,15 +249,27 @@ type RefreshTriggerMsg struct{}
 type TickMsg time.Time
 
 type RefreshResultMsg struct {
-	Snapshots []Snapshot
-	Err       error
+	Snapshots       []Snapshot
+	QuotaOff        []string
+	ConfigExclusive map[string]int64
+	Err             error
 }
 
 type ActionResultMsg struct {
-	Kind   ActionKind
-	Snap   Snapshot
-	Output string
-	Err    error
+	Kind      ActionKind
+	Snap      Snapshot
+	Snapshots []Snapshot   // refreshed rows for actions that patch the list in place; the deleted rows of a delete
+	Files     []FileResult // per-file outcome of actions on individual files
+	Output    string
+	Err       error
+}
+
+// SnapshotPatchMsg carries a resolved SnapperEvent: the fresh rows for created,
+// modified or new-config events, nothing extra for deletions
+type SnapshotPatchMsg struct {
+	Event     SnapperEvent
+	Snapshots []Snapshot
+	Err       error
 }
 
 type MouseClickMsg struct {
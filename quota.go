@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+const (
+	btrfsIocInoLookup = 0xd0009412 // _IOWR(0x94, 18, struct btrfs_ioctl_ino_lookup_args)
+	btrfsIocFSInfo    = 0x8400941f // _IOR(0x94, 31, struct btrfs_ioctl_fs_info_args)
+)
+
+// btrfsSysfs is where the kernel exposes btrfs filesystems and their qgroups
+var btrfsSysfs = "/sys/fs/btrfs"
+
+// btrfsInoLookupArgs is struct btrfs_ioctl_ino_lookup_args
+type btrfsInoLookupArgs struct {
+	TreeID   uint64
+	ObjectID uint64
+	Name     [4080]byte
+}
+
+// btrfsFSInfoArgs is struct btrfs_ioctl_fs_info_args
+type btrfsFSInfoArgs struct {
+	MaxID      uint64
+	NumDevices uint64
+	FSID       [16]byte
+	Rest       [992]byte
+}
+
+// btrfsIoctl runs a btrfs ioctl on a directory
+func btrfsIoctl(path string, request uintptr, arg unsafe.Pointer) error {
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_DIRECTORY, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// btrfsSubvolumeID returns the id of the subvolume a directory belongs to;
+// the lookup needs no privileges
+func btrfsSubvolumeID(path string) (uint64, error) {
+	args := btrfsInoLookupArgs{ObjectID: btrfsSubvolIno}
+	if err := btrfsIoctl(path, btrfsIocInoLookup, unsafe.Pointer(&args)); err != nil {
+		return 0, err
+	}
+	return args.TreeID, nil
+}
+
+// btrfsFSID returns the UUID of the btrfs filesystem a path is on, as named
+// under /sys/fs/btrfs
+func btrfsFSID(path string) (string, error) {
+	var args btrfsFSInfoArgs
+	if err := btrfsIoctl(path, btrfsIocFSInfo, unsafe.Pointer(&args)); err != nil {
+		return "", err
+	}
+	return formatFSID(args.FSID), nil
+}
+
+// formatFSID writes a filesystem UUID the way sysfs names it
+func formatFSID(id [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+// quotaEnabled reports whether quota groups are on for the filesystem with
+// the given UUID
+func quotaEnabled(fsid string) bool {
+	_, err := os.Stat(filepath.Join(btrfsSysfs, fsid, "qgroups"))
+	return err == nil
+}
+
+// qgroupSizes reads the referenced and exclusive bytes of a qgroup such as
+// "0/257", a subvolume's own, or "1/0"
+func qgroupSizes(fsid, qgroup string) (referenced, exclusive int64, err error) {
+	dir := filepath.Join(btrfsSysfs, fsid, "qgroups", strings.ReplaceAll(qgroup, "/", "_"))
+	read := func(name string) (int64, error) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+	if referenced, err = read("referenced"); err != nil {
+		return 0, 0, err
+	}
+	exclusive, err = read("exclusive")
+	return referenced, exclusive, err
+}
+
+// withQgroupSizes fills in the referenced and exclusive sizes of snapshots on
+// btrfs with quotas enabled, and returns the configs on btrfs whose quotas
+// are off. Snapshots that cannot be looked up keep nil sizes.
+func withQgroupSizes(snaps []Snapshot) ([]Snapshot, []string) {
+	fsids := map[string]string{} // subvolume -> fsid, "" when not btrfs
+	var quotaOff []string
+	for i := range snaps {
+		snap := &snaps[i]
+		fsid, seen := fsids[snap.Subvolume]
+		if !seen {
+			fsid, _ = btrfsFSID(snap.Subvolume)
+			fsids[snap.Subvolume] = fsid
+			if fsid != "" && !quotaEnabled(fsid) {
+				quotaOff = append(quotaOff, snap.Config)
+			}
+		}
+		if fsid == "" || snap.Number == 0 {
+			continue
+		}
+		id, err := btrfsSubvolumeID(snapshotRoot(*snap))
+		if err != nil {
+			continue
+		}
+		if referenced, exclusive, err := qgroupSizes(fsid, fmt.Sprintf("0/%d", id)); err == nil {
+			snap.Referenced = &referenced
+			snap.Exclusive = &exclusive
+		}
+	}
+	return snaps, quotaOff
+}
+
+// configExclusive reads the exclusive bytes of each config's QGROUP. Snapper
+// adds every snapshot it creates to that qgroup, so its exclusive size is what
+// deleting all of the config's snapshots frees, shared data included.
+// Configs without a QGROUP or quotas are left out.
+func configExclusive(client SnapperClient, snaps []Snapshot) map[string]int64 {
+	sizes := map[string]int64{}
+	seen := map[string]bool{}
+	for _, snap := range snaps {
+		if seen[snap.Config] {
+			continue
+		}
+		seen[snap.Config] = true
+		info, err := client.GetConfig(snap.Config)
+		if err != nil || info.Values["QGROUP"] == "" {
+			continue
+		}
+		fsid, err := btrfsFSID(snap.Subvolume)
+		if err != nil || !quotaEnabled(fsid) {
+			continue
+		}
+		if _, exclusive, err := qgroupSizes(fsid, info.Values["QGROUP"]); err == nil {
+			sizes[snap.Config] = exclusive
+		}
+	}
+	return sizes
+}
+
+// snapshotBytes is the space a snapshot alone holds: its exclusive qgroup
+// size when known, otherwise what snapper reports as used space
+func snapshotBytes(snap Snapshot) *int64 {
+	if snap.Exclusive != nil {
+		return snap.Exclusive
+	}
+	return snap.UsedSpace
+}
+
+// quotaFormInput holds the config awaiting the setup-quota confirmation
+type quotaFormInput struct {
+	Config    string
+	Confirmed bool
+}
+
+// openQuotaForm offers "snapper setup-quota" for a config on a filesystem
+// without quota groups
+func (m *UIState) openQuotaForm() tea.Cmd {
+	if len(m.QuotaOff) == 0 {
+		m.Status = "Quota groups are already enabled"
+		return nil
+	}
+	client := m.Client
+	input := &quotaFormInput{Config: m.QuotaOff[0]}
+	if snap := m.currentSnapshot(); snap != nil {
+		for _, config := range m.QuotaOff {
+			if config == snap.Config {
+				input.Config = config
+			}
+		}
+	}
+	m.QuotaInput = input
+	m.FormKind = "setupquota"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Config").
+				Description("Quota groups are off on the filesystem of these configs").
+				Options(huh.NewOptions(m.QuotaOff...)...).
+				Value(&input.Config),
+			huh.NewConfirm().
+				Title("Enable quota groups?").
+				DescriptionFunc(func() string {
+					return "Snapshot sizes and space-aware cleanup need btrfs quota groups. Runs: " +
+						client.Describe(Operation{Kind: "setup-quota", Config: input.Config}).String()
+				}, &input.Config).
+				Affirmative("Set up").
+				Negative("Cancel").
+				Value(&input.Confirmed),
+		),
+	).WithShowHelp(true).WithWidth(70)
+	m.Status = "Set up quota: confirm or esc to cancel"
+	return m.Form.Init()
+}
+
+// setupQuotaCmd runs "snapper setup-quota"
+func setupQuotaCmd(client SnapperClient, config string) tea.Cmd {
+	return func() tea.Msg {
+		output, err := client.SetupQuota(config)
+		return ActionResultMsg{Kind: ActionSetupQuota, Snap: Snapshot{Config: config}, Output: output, Err: err}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSysfs points btrfsSysfs at a temp tree for the rest of the test
+func fakeSysfs(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	old := btrfsSysfs
+	btrfsSysfs = dir
+	t.Cleanup(func() { btrfsSysfs = old })
+	return dir
+}
+
+// writeQgroup creates a qgroup directory with its referenced and exclusive files
+func writeQgroup(t *testing.T, sysfs, fsid, qgroup, referenced, exclusive string) {
+	t.Helper()
+	dir := filepath.Join(sysfs, fsid, "qgroups", qgroup)
+	writeFile(t, filepath.Join(dir, "referenced"), referenced, 0o444)
+	writeFile(t, filepath.Join(dir, "exclusive"), exclusive, 0o444)
+}
+
+func TestFormatFSID(t *testing.T) {
+	id := [16]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x00, 0x0a, 0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54}
+	if got, want := formatFSID(id), "01234567-89ab-cdef-000a-fedcba987654"; got != want {
+		t.Errorf("formatFSID = %q, want %q", got, want)
+	}
+	if got, want := formatFSID([16]byte{}), "00000000-0000-0000-0000-000000000000"; got != want {
+		t.Errorf("formatFSID(zero) = %q, want %q", got, want)
+	}
+}
+
+func TestQgroupSizes(t *testing.T) {
+	sysfs := fakeSysfs(t)
+	const fsid = "01234567-89ab-cdef-000a-fedcba987654"
+	writeQgroup(t, sysfs, fsid, "0_257", "16384\n", "4096\n")
+	writeQgroup(t, sysfs, fsid, "0_258", "16384\n", "lots\n")
+
+	if !quotaEnabled(fsid) {
+		t.Error("quotas off with a qgroups directory")
+	}
+	if quotaEnabled("other") {
+		t.Error("quotas on without a qgroups directory")
+	}
+
+	referenced, exclusive, err := qgroupSizes(fsid, "0/257")
+	if err != nil || referenced != 16384 || exclusive != 4096 {
+		t.Errorf("qgroupSizes(0/257) = %d, %d, %v", referenced, exclusive, err)
+	}
+	if _, _, err := qgroupSizes(fsid, "0/258"); err == nil {
+		t.Error("an unparsable size was accepted")
+	}
+	if _, _, err := qgroupSizes(fsid, "0/300"); !os.IsNotExist(err) {
+		t.Errorf("missing qgroup: err = %v", err)
+	}
+}
+
+func TestWithQgroupSizesSkipsOtherFilesystems(t *testing.T) {
+	fakeSysfs(t)
+	snaps, quotaOff := withQgroupSizes([]Snapshot{
+		{Config: "root", Subvolume: t.TempDir(), Number: 1},
+		{Config: "home", Subvolume: filepath.Join(t.TempDir(), "missing"), Number: 1},
+	})
+	for _, snap := range snaps {
+		if snap.Referenced != nil || snap.Exclusive != nil {
+			t.Errorf("%s #%d got qgroup sizes", snap.Config, snap.Number)
+		}
+	}
+	if len(quotaOff) != 0 {
+		t.Errorf("quotaOff = %v", quotaOff)
+	}
+}
+
+// qgroupFake is a fake backend that reads qgroups by numbering them
+type qgroupFake struct {
+	*FakeClient
+	reads int
+}
+
+func (q *qgroupFake) QgroupSizes(snaps []Snapshot) ([]Snapshot, []string) {
+	q.reads++
+	for i := range snaps {
+		size := int64(snaps[i].Number) << 20
+		snaps[i].Exclusive = &size
+	}
+	return snaps, []string{"home"}
+}
+
+func (q *qgroupFake) ConfigExclusive(snaps []Snapshot) map[string]int64 {
+	return map[string]int64{"root": 100 << 20}
+}
+
+func TestRefreshReadsQgroupsThroughTheBackend(t *testing.T) {
+	// The plain fake reads no qgroups
+	m, _ := newTestModel(t, testSnapshots)
+	for _, snap := range m.Snapshots {
+		if snap.Exclusive != nil {
+			t.Fatalf("fake backend filled in qgroup sizes for %s #%d", snap.Config, snap.Number)
+		}
+	}
+
+	reader := &qgroupFake{FakeClient: newFakeClient(testSnapshots)}
+	m = initialModel(reader)
+	m.Events = nil
+	m = drive(t, m, refreshSnapshotsCmd(m.Client))
+	if reader.reads != 1 {
+		t.Fatalf("qgroups read %d times", reader.reads)
+	}
+	for _, snap := range m.Snapshots {
+		if snap.Exclusive == nil || *snap.Exclusive != int64(snap.Number)<<20 {
+			t.Errorf("%s #%d exclusive = %v", snap.Config, snap.Number, humanReadableBytes(snap.Exclusive))
+		}
+	}
+	if len(m.QuotaOff) != 1 || m.QuotaOff[0] != "home" {
+		t.Errorf("QuotaOff = %v", m.QuotaOff)
+	}
+	if m.ConfigExclusive["root"] != 100<<20 {
+		t.Errorf("ConfigExclusive = %v", m.ConfigExclusive)
+	}
+}
+
+func TestBuildSummaryTotalsExclusiveBytes(t *testing.T) {
+	summary := buildSummary([]Snapshot{
+		{Number: 1, Exclusive: ptrInt64(1 << 20), UsedSpace: ptrInt64(5 << 20)},
+		{Number: 2, Exclusive: ptrInt64(2 << 20)},
+		{Number: 3, UsedSpace: ptrInt64(1 << 20)}, // no qgroup size
+		{Number: 4},
+	})
+	if !strings.HasPrefix(summary, "Snapshots: 4 | Total used: 4.0 MiB | Free on ") {
+		t.Errorf("summary = %q", summary)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReclaimEstimate is the space deleting some snapshots of one config would
+// free on the config's filesystem
+type ReclaimEstimate struct {
+	Config    string
+	Subvolume string
+	Count     int
+	Bytes     *int64  // exclusive bytes of the snapshots together, nil when unknown
+	AtLeast   bool    // Bytes is only a lower bound
+	Free      *uint64 // free space on the filesystem now, nil when unknown
+}
+
+// estimateReclaim finds the exclusive size of the targets of each config as
+// a set. Data shared only among the targets is freed too, so adding up their
+// own exclusive sizes undercounts; the set's size is only known exactly for a
+// single snapshot, from its qgroup, and for all of a config's snapshots, from
+// the config's QGROUP. Other selections get that sum as a lower bound.
+func estimateReclaim(targets, all []Snapshot, configExclusive map[string]int64) []ReclaimEstimate {
+	inConfig := map[string]int{}
+	for _, snap := range all {
+		if snap.Number != 0 {
+			inConfig[snap.Config]++
+		}
+	}
+
+	var estimates []ReclaimEstimate
+	for _, group := range groupByConfig(targets) {
+		est := ReclaimEstimate{Config: group[0].Config, Subvolume: group[0].Subvolume}
+		var sum *int64
+		for _, snap := range group {
+			if snap.Number == 0 {
+				continue
+			}
+			est.Count++
+			if snap.Exclusive != nil {
+				sum = ptrInt64(int64Value(sum) + *snap.Exclusive)
+			}
+		}
+		if est.Count == 0 {
+			continue
+		}
+		if size, ok := configExclusive[est.Config]; ok && est.Count == inConfig[est.Config] {
+			est.Bytes = &size
+		} else {
+			est.Bytes = sum
+			est.AtLeast = est.Count > 1 && sum != nil
+		}
+		if free, err := freeSpaceForPath(est.Subvolume); err == nil {
+			est.Free = &free
+		}
+		estimates = append(estimates, est)
+	}
+	return estimates
+}
+
+// freedText describes the bytes freed
+func (e ReclaimEstimate) freedText() string {
+	if e.Bytes == nil {
+		return "an unknown amount (no qgroup size)"
+	}
+	return e.bound() + humanReadableBytes(e.Bytes)
+}
+
+// projectedText is the free space on the config's filesystem once the
+// snapshots are gone
+func (e ReclaimEstimate) projectedText() string {
+	if e.Free == nil || e.Bytes == nil {
+		return "n/a"
+	}
+	return e.bound() + humanReadableFromUint64(*e.Free+uint64(*e.Bytes))
+}
+
+// bound prefixes figures that are only a lower bound
+func (e ReclaimEstimate) bound() string {
+	if e.AtLeast {
+		return "at least "
+	}
+	return ""
+}
+
+// reclaimPreview is the action panel line for deleting the selection
+func reclaimPreview(estimates []ReclaimEstimate) string {
+	var parts []string
+	for _, e := range estimates {
+		parts = append(parts, fmt.Sprintf("%d snapshot(s) in %s frees %s", e.Count, e.Config, e.freedText()))
+	}
+	return "Delete selected: " + strings.Join(parts, "; ")
+}
+
+// reclaimSummary is the projected free space shown next to "Free on /"
+func reclaimSummary(estimates []ReclaimEstimate) string {
+	var parts []string
+	for _, e := range estimates {
+		parts = append(parts, fmt.Sprintf("%s on %s", e.projectedText(), e.Subvolume))
+	}
+	return "Free after delete: " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEstimateReclaim(t *testing.T) {
+	all := []Snapshot{
+		{Config: "root", Number: 0},
+		{Config: "root", Number: 1, Exclusive: ptrInt64(1 << 20), UsedSpace: ptrInt64(9 << 20)},
+		{Config: "root", Number: 2, Exclusive: ptrInt64(2 << 20)},
+		{Config: "root", Number: 3, Exclusive: ptrInt64(3 << 20)},
+		{Config: "home", Number: 1, UsedSpace: ptrInt64(3 << 20)},
+		{Config: "home", Number: 2, Exclusive: ptrInt64(4 << 20)},
+	}
+	configExclusive := map[string]int64{"root": 10 << 20}
+	pick := func(keys ...SnapshotKey) []Snapshot {
+		var picked []Snapshot
+		for _, key := range keys {
+			for _, snap := range all {
+				if snap.Key() == key {
+					picked = append(picked, snap)
+				}
+			}
+		}
+		return picked
+	}
+
+	tests := []struct {
+		name    string
+		targets []Snapshot
+		want    string // "config count bytes" per estimate, "?" for unknown bytes, ">=" for a lower bound
+	}{
+		{"single snapshot uses its own qgroup", pick(SnapshotKey{"root", 1}), "root 1 1.0 MiB"},
+		{"single snapshot without a qgroup size", pick(SnapshotKey{"home", 1}), "home 1 ?"},
+		{"part of a config sums to a lower bound", pick(SnapshotKey{"root", 1}, SnapshotKey{"root", 3}), "root 2 >=4.0 MiB"},
+		{"the bound skips unknown sizes", pick(SnapshotKey{"home", 1}, SnapshotKey{"home", 2}, SnapshotKey{"root", 1}), "home 2 >=4.0 MiB; root 1 1.0 MiB"},
+		{"all of a config uses the config qgroup", pick(SnapshotKey{"root", 1}, SnapshotKey{"root", 2}, SnapshotKey{"root", 3}), "root 3 10.0 MiB"},
+		{"the current system does not count", pick(SnapshotKey{"root", 0}, SnapshotKey{"root", 1}, SnapshotKey{"root", 2}, SnapshotKey{"root", 3}), "root 3 10.0 MiB"},
+		{"only the current system", pick(SnapshotKey{"root", 0}), ""},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, e := range estimateReclaim(tt.targets, all, configExclusive) {
+			bytes := "?"
+			if e.Bytes != nil {
+				bytes = humanReadableBytes(e.Bytes)
+			}
+			if e.AtLeast {
+				bytes = ">=" + bytes
+			}
+			got = append(got, strings.Join([]string{e.Config, strconv.Itoa(e.Count), bytes}, " "))
+		}
+		if strings.Join(got, "; ") != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, strings.Join(got, "; "), tt.want)
+		}
+	}
+
+	// Without the config's qgroup, all of a config only has the lower bound
+	if e := estimateReclaim(pick(SnapshotKey{"home", 1}, SnapshotKey{"home", 2}), all, nil); !e[0].AtLeast || int64Value(e[0].Bytes) != 4<<20 {
+		t.Errorf("estimate without a config qgroup = %+v", e[0])
+	}
+}
+
+func TestReclaimTexts(t *testing.T) {
+	free := uint64(1 << 30)
+	known := ReclaimEstimate{Config: "root", Subvolume: "/", Count: 2, Bytes: ptrInt64(512 << 20), Free: &free}
+	bound := ReclaimEstimate{Config: "home", Subvolume: "/home", Count: 3, Bytes: ptrInt64(256 << 20), AtLeast: true, Free: &free}
+	unknown := ReclaimEstimate{Config: "srv", Subvolume: "/srv", Count: 1, Free: &free}
+	estimates := []ReclaimEstimate{known, bound, unknown}
+	if got := reclaimPreview(estimates); got != "Delete selected: 2 snapshot(s) in root frees 512.0 MiB; 3 snapshot(s) in home frees at least 256.0 MiB; 1 snapshot(s) in srv frees an unknown amount (no qgroup size)" {
+		t.Errorf("preview = %q", got)
+	}
+	if got := reclaimSummary(estimates); got != "Free after delete: 1.5 GiB on /, at least 1.2 GiB on /home, n/a on /srv" {
+		t.Errorf("summary = %q", got)
+	}
+}
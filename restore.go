@@ -0,0 +1,490 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// backupSuffix is inserted between a file name and the timestamp of its backup
+const backupSuffix = ".snapper-tui-"
+
+// Restore effects reported per file
+const (
+	restoreCreated     = "create"
+	restoreOverwritten = "overwrite"
+	restoreMetadata    = "metadata" // existing directory given the snapshot's owner and mode
+	restoreUnchanged   = "unchanged"
+	restoreSkipped     = "skip"
+)
+
+// restoreFormInput holds the file or directory to restore and the answer of
+// its confirmation
+type restoreFormInput struct {
+	Snap      Snapshot // snapshot the file is restored from
+	Rel       string   // snapshot-relative path
+	Live      string   // path on the live system
+	IsDir     bool
+	Stamp     string // timestamp of the backups made by this restore
+	Confirmed bool
+}
+
+// backupPath names the copy of the current version kept before a restore
+func backupPath(live, stamp string) string {
+	path := live + backupSuffix + stamp
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s%s%s-%d", live, backupSuffix, stamp, i)
+	}
+}
+
+// openRestoreForm asks to confirm restoring a path from a snapshot
+func (m *UIState) openRestoreForm(snap Snapshot, rel string) tea.Cmd {
+	if snap.Number == 0 {
+		m.Status = "Snapshot 0 is the live system; nothing to restore from"
+		return nil
+	}
+	root := snapshotRoot(snap)
+	src, err := snapshotSource(root, rel)
+	if err != nil {
+		m.Status = fmt.Sprintf("Cannot restore %s: %v", rel, err)
+		return nil
+	}
+	info, err := os.Lstat(src)
+	if err != nil {
+		m.Status = fmt.Sprintf("Cannot restore %s: not in snapshot %d", rel, snap.Number)
+		return nil
+	}
+
+	input := &restoreFormInput{
+		Snap:  snap,
+		Rel:   rel,
+		Live:  livePath(snap, rel),
+		IsDir: info.IsDir(),
+		Stamp: time.Now().Format("20060102-150405"),
+	}
+	what := "file"
+	description := fmt.Sprintf("The current version is saved as\n  %s\nwith ownership, mode and xattrs taken from the snapshot.", input.Live+backupSuffix+input.Stamp)
+	if input.IsDir {
+		what = "directory"
+		description = fmt.Sprintf("Every entry below it is copied back; files that differ are first saved as\n  <file>%s%s\nExisting directories whose owner or mode differ get the snapshot's, without a backup.\nFiles missing from the snapshot are left alone.", backupSuffix, input.Stamp)
+	}
+
+	m.RestoreInput = input
+	m.FormKind = "restore"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("Restore %s %s from %s #%d?", what, input.Live, snap.Config, snap.Number)).
+				Description(description).
+				Affirmative("Restore").
+				Negative("Cancel").
+				Value(&input.Confirmed),
+		),
+	).WithShowHelp(true).WithWidth(80)
+
+	m.Status = "Restore: confirm to copy the snapshot version back, esc to cancel"
+	return m.Form.Init()
+}
+
+// snapshotSource is the path of rel inside the snapshot, with symlinks in its
+// parent directories resolved without leaving the snapshot. The entry itself
+// is not followed, so a symlink is restored as a symlink.
+func snapshotSource(root, rel string) (string, error) {
+	dir, err := resolveInSnapshot(root, filepath.Dir(rel))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, dir, filepath.Base(rel)), nil
+}
+
+// restoreCmd copies a file or directory tree back from a snapshot, recording
+// it in the audit log; in dry-run mode it is only recorded
+func restoreCmd(recorder *RecorderClient, input *restoreFormInput) tea.Cmd {
+	return func() tea.Msg {
+		msg := ActionResultMsg{Kind: ActionRestoreFile, Snap: input.Snap}
+		entry := AuditEntry{Action: "restore-file", Config: input.Snap.Config, Numbers: []int{input.Snap.Number}, Path: input.Live}
+		recorder.record(entry, func() (string, error) {
+			msg = restoreFiles(input)
+			return msg.Output, msg.Err
+		})
+		return msg
+	}
+}
+
+// restoreFiles copies a file or directory tree back from a snapshot
+func restoreFiles(input *restoreFormInput) ActionResultMsg {
+	msg := ActionResultMsg{Kind: ActionRestoreFile, Snap: input.Snap}
+	src, err := snapshotSource(snapshotRoot(input.Snap), input.Rel)
+	if err == nil {
+		msg.Files, err = restoreTree(src, input.Live, input.Stamp)
+	}
+	if err != nil {
+		msg.Err = err
+		msg.Output = fmt.Sprintf("Restore of %s failed: %v", input.Live, err)
+		return msg
+	}
+	failed := 0
+	for _, file := range msg.Files {
+		if file.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		msg.Err = fmt.Errorf("%d of %d entries failed", failed, len(msg.Files))
+	}
+	msg.Output = restoreSummary(input, msg.Files)
+	return msg
+}
+
+// restoreTree restores src to dst, walking directories without following
+// symlinks. Directory metadata is applied last, deepest first, so the
+// restored entries do not disturb it; existing directories that already
+// have the snapshot's owner and mode are left alone.
+func restoreTree(src, dst, stamp string) ([]FileResult, error) {
+	if _, err := os.Stat(filepath.Dir(dst)); err != nil {
+		return nil, fmt.Errorf("parent directory of %s is missing; restore the parent instead", dst)
+	}
+
+	var results []FileResult
+	type dirMeta struct {
+		path string
+		info fs.FileInfo
+		src  string
+	}
+	var dirs []dirMeta
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		target := filepath.Join(dst, strings.TrimPrefix(path, src))
+		if err != nil {
+			results = append(results, FileResult{Path: target, Err: err})
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			results = append(results, FileResult{Path: target, Err: err})
+			return nil
+		}
+		result := restoreEntry(path, target, info, stamp)
+		results = append(results, result)
+		if info.IsDir() {
+			if result.Err != nil {
+				return fs.SkipDir
+			}
+			if result.Effect != restoreUnchanged {
+				dirs = append(dirs, dirMeta{path: target, info: info, src: path})
+			}
+		}
+		return nil
+	})
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := applyMetadata(dirs[i].src, dirs[i].path, dirs[i].info); err != nil {
+			results = append(results, FileResult{Path: dirs[i].path, Effect: "metadata", Err: err})
+		}
+	}
+	return results, err
+}
+
+// restoreEntry puts one snapshot entry in place, moving a differing current
+// version aside first
+func restoreEntry(src, dst string, info fs.FileInfo, stamp string) FileResult {
+	result := FileResult{Path: dst}
+	current, statErr := os.Lstat(dst)
+	exists := statErr == nil
+
+	switch {
+	case info.IsDir():
+		if exists && current.IsDir() {
+			result.Effect = restoreUnchanged
+			if !sameOwnerAndMode(info, current) {
+				result.Effect = restoreMetadata
+			}
+			return result
+		}
+		return replaceWith(dst, exists, stamp, result, func(dir, pattern string) (string, error) {
+			tmp, err := os.MkdirTemp(dir, pattern)
+			if err != nil {
+				return "", err
+			}
+			return tmp, os.Chmod(tmp, info.Mode().Perm())
+		})
+
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if exists && current.Mode()&fs.ModeSymlink != 0 {
+			if have, _ := os.Readlink(dst); have == target {
+				result.Effect = restoreUnchanged
+				return result
+			}
+		}
+		return replaceWith(dst, exists, stamp, result, func(dir, pattern string) (string, error) {
+			tmp, err := tempSymlink(target, dir, pattern)
+			if err != nil {
+				return "", err
+			}
+			return tmp, applyMetadata(src, tmp, info)
+		})
+
+	case info.Mode().IsRegular():
+		if exists && current.Mode().IsRegular() && sameFile(src, dst, info, current) {
+			result.Effect = restoreUnchanged
+			return result
+		}
+		return replaceWith(dst, exists, stamp, result, func(dir, pattern string) (string, error) {
+			tmp, err := copyToTemp(src, dir, pattern)
+			if err != nil {
+				return tmp, err
+			}
+			return tmp, applyMetadata(src, tmp, info)
+		})
+	}
+
+	result.Effect = restoreSkipped
+	result.Output = fmt.Sprintf("%s not restored", info.Mode().Type())
+	return result
+}
+
+// replaceWith builds the new entry under a fresh temporary name next to dst,
+// moves the current version to its backup name and renames the new entry
+// into place. build creates the entry in dir with a name matching pattern
+// and returns it, or "" when it created nothing.
+func replaceWith(dst string, exists bool, stamp string, result FileResult, build func(dir, pattern string) (string, error)) FileResult {
+	tmp, err := build(filepath.Dir(dst), "."+filepath.Base(dst)+backupSuffix+"*")
+	if err != nil {
+		if tmp != "" {
+			os.RemoveAll(tmp)
+		}
+		result.Err = err
+		return result
+	}
+
+	result.Effect = restoreCreated
+	if exists {
+		backup := backupPath(dst, stamp)
+		if err := os.Rename(dst, backup); err != nil {
+			os.RemoveAll(tmp)
+			result.Err = fmt.Errorf("backing up current version: %w", err)
+			return result
+		}
+		result.Effect = restoreOverwritten
+		result.Output = backup
+		if err := os.Rename(tmp, dst); err != nil {
+			// Put the current version back rather than leave nothing
+			os.Rename(backup, dst)
+			os.RemoveAll(tmp)
+			result.Err = err
+		}
+		return result
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.RemoveAll(tmp)
+		result.Err = err
+	}
+	return result
+}
+
+// copyToTemp copies the data of src into a new file in dir named after
+// pattern, returning its name once it exists
+func copyToTemp(src, dir, pattern string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return out.Name(), err
+	}
+	return out.Name(), out.Close()
+}
+
+// tempSymlink creates a symlink to target in dir under a fresh name matching
+// pattern. os.CreateTemp picks the name; the symlink takes it over and fails
+// rather than replace anything that appears there in between.
+func tempSymlink(target, dir, pattern string) (string, error) {
+	placeholder, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	name := placeholder.Name()
+	placeholder.Close()
+	if err := os.Remove(name); err != nil {
+		return "", err
+	}
+	if err := os.Symlink(target, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// applyMetadata gives dst the ownership, mode, xattrs and mtime of the
+// snapshot entry; symlinks only get their ownership
+func applyMetadata(src, dst string, info fs.FileInfo) error {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		if err := os.Lchown(dst, int(stat.Uid), int(stat.Gid)); err != nil {
+			return err
+		}
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return nil
+	}
+	mode := info.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+	if err := os.Chmod(dst, mode); err != nil {
+		return err
+	}
+	if err := copyXattrs(src, dst); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// copyXattrs copies every extended attribute of src to dst; filesystems
+// without xattr support are not an error
+func copyXattrs(src, dst string) error {
+	size, err := syscall.Listxattr(src, nil)
+	if err != nil || size == 0 {
+		if errors.Is(err, syscall.ENOTSUP) {
+			return nil
+		}
+		return err
+	}
+	names := make([]byte, size)
+	size, err = syscall.Listxattr(src, names)
+	if err != nil {
+		return err
+	}
+	for _, name := range bytes.Split(names[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		attr := string(name)
+		valueSize, err := syscall.Getxattr(src, attr, nil)
+		if err != nil {
+			return fmt.Errorf("reading xattr %s: %w", attr, err)
+		}
+		value := make([]byte, valueSize)
+		valueSize, err = syscall.Getxattr(src, attr, value)
+		if err != nil {
+			return fmt.Errorf("reading xattr %s: %w", attr, err)
+		}
+		if err := syscall.Setxattr(dst, attr, value[:valueSize], 0); err != nil {
+			return fmt.Errorf("setting xattr %s: %w", attr, err)
+		}
+	}
+	return nil
+}
+
+// sameOwnerAndMode reports whether have already has the ownership and mode of
+// the snapshot entry want
+func sameOwnerAndMode(want, have fs.FileInfo) bool {
+	if want.Mode() != have.Mode() {
+		return false
+	}
+	ws, ok1 := want.Sys().(*syscall.Stat_t)
+	hs, ok2 := have.Sys().(*syscall.Stat_t)
+	return !ok1 || !ok2 || (ws.Uid == hs.Uid && ws.Gid == hs.Gid)
+}
+
+// sameFile reports whether dst already matches the snapshot entry in content,
+// mode and ownership
+func sameFile(src, dst string, want, have fs.FileInfo) bool {
+	if want.Size() != have.Size() || !sameOwnerAndMode(want, have) {
+		return false
+	}
+	a, b := digestFile(src), digestFile(dst)
+	return a.Err == nil && b.Err == nil && a.SHA256 == b.SHA256
+}
+
+// restoreSummary counts the outcome of a restore and lists what was
+// overwritten, created and failed
+func restoreSummary(input *restoreFormInput, files []FileResult) string {
+	byEffect := map[string][]string{}
+	var failed []string
+	for _, file := range files {
+		if file.Err != nil {
+			failed = append(failed, fmt.Sprintf("✗ %s: %v", file.Path, file.Err))
+			continue
+		}
+		line := file.Path
+		switch file.Effect {
+		case restoreOverwritten:
+			line = fmt.Sprintf("%s (was saved as %s)", file.Path, filepath.Base(file.Output))
+		case restoreSkipped:
+			line = fmt.Sprintf("%s (%s)", file.Path, file.Output)
+		}
+		byEffect[file.Effect] = append(byEffect[file.Effect], line)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Restored %s from %s #%d: %d overwritten, %d created, %d metadata restored, %d unchanged, %d skipped, %d failed",
+		input.Live, input.Snap.Config, input.Snap.Number,
+		len(byEffect[restoreOverwritten]), len(byEffect[restoreCreated]), len(byEffect[restoreMetadata]),
+		len(byEffect[restoreUnchanged]), len(byEffect[restoreSkipped]), len(failed))
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		sort.Strings(lines)
+		fmt.Fprintf(&b, "\n\n%s:", title)
+		for i, line := range lines {
+			if i == maxUndoListed {
+				fmt.Fprintf(&b, "\n  … and %d more", len(lines)-maxUndoListed)
+				break
+			}
+			fmt.Fprintf(&b, "\n  %s", line)
+		}
+	}
+	section("Failed", failed)
+	section("Overwritten", byEffect[restoreOverwritten])
+	section("Created", byEffect[restoreCreated])
+	section("Metadata restored", byEffect[restoreMetadata])
+	section("Skipped", byEffect[restoreSkipped])
+	return b.String()
+}
+
+// handleRestoreResult shows the restore summary in a dialog
+func (m UIState) handleRestoreResult(msg ActionResult) (tea.Model, tea.Cmd) {
+	m.ActionMessage = msg.Output
+	m.Status = firstLine(msg.Output)
+	cmd := m.openSummary("Restore finished", msg.Output)
+	if v := m.HistoryView; m.Screen == "history" && v != nil && len(v.Versions) > 0 {
+		// The live version changed; read it again
+		_, reload := m.openHistory(v.Versions[len(v.Versions)-1].Last(), v.Path)
+		cmd = tea.Batch(cmd, reload)
+	}
+	return m, cmd
+}
+
+// noteEscaper keeps paths from being read as a note's markup
+var noteEscaper = strings.NewReplacer(`\`, `\\`, "_", `\_`, "*", `\*`, "`", "\\`")
+
+// openSummary shows a read-only message until it is dismissed
+func (m *UIState) openSummary(title, text string) tea.Cmd {
+	m.FormKind = "summary"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewNote().Title(title).Description(noteEscaper.Replace(text)).Next(true).NextLabel("Close"),
+		),
+	).WithShowHelp(true).WithWidth(90)
+	return m.Form.Init()
+}
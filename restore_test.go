@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeFile creates a file with the given content and mode, making its directory
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile returns the content of a file, failing the test if it is missing
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// effects maps each restored path, relative to dir, to its effect
+func effects(t *testing.T, dir string, files []FileResult) map[string]string {
+	t.Helper()
+	got := map[string]string{}
+	for _, file := range files {
+		if file.Err != nil {
+			t.Errorf("%s: %v", file.Path, file.Err)
+		}
+		rel, _ := filepath.Rel(dir, file.Path)
+		got[rel] = file.Effect
+	}
+	return got
+}
+
+func TestBackupPath(t *testing.T) {
+	dir := t.TempDir()
+	live := filepath.Join(dir, "fstab")
+	if got, want := backupPath(live, "20250101-120000"), live+".snapper-tui-20250101-120000"; got != want {
+		t.Errorf("backup = %q, want %q", got, want)
+	}
+	// Existing backups of the same second get a counter
+	writeFile(t, live+".snapper-tui-20250101-120000", "", 0o644)
+	writeFile(t, live+".snapper-tui-20250101-120000-1", "", 0o644)
+	if got, want := backupPath(live, "20250101-120000"), live+".snapper-tui-20250101-120000-2"; got != want {
+		t.Errorf("backup = %q, want %q", got, want)
+	}
+}
+
+func TestRestoreFileOverwritesWithBackup(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "snap", "fstab"), filepath.Join(dir, "live", "fstab")
+	writeFile(t, src, "snapshot\n", 0o640)
+	writeFile(t, dst, "current\n", 0o644)
+	// An entry named like the old fixed temp name survives the restore
+	bystander := filepath.Join(dir, "live", ".fstab.snapper-tui-tmp")
+	writeFile(t, bystander, "keep me", 0o644)
+
+	files, err := restoreTree(src, dst, "stamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := effects(t, dir, files); len(got) != 1 || got["live/fstab"] != restoreOverwritten {
+		t.Errorf("effects = %v", got)
+	}
+	if got := readFile(t, dst); got != "snapshot\n" {
+		t.Errorf("restored content = %q", got)
+	}
+	if info, _ := os.Stat(dst); info.Mode().Perm() != 0o640 {
+		t.Errorf("restored mode = %v", info.Mode())
+	}
+	if got := readFile(t, dst+".snapper-tui-stamp"); got != "current\n" {
+		t.Errorf("backup content = %q", got)
+	}
+	if got := readFile(t, bystander); got != "keep me" {
+		t.Errorf("bystander = %q", got)
+	}
+	// Nothing but the file, its backup and the bystander is left behind
+	entries, _ := os.ReadDir(filepath.Dir(dst))
+	if len(entries) != 3 {
+		t.Errorf("%d entries next to the target", len(entries))
+	}
+
+	// Restoring again finds nothing to do
+	files, err = restoreTree(src, dst, "stamp2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := effects(t, dir, files); got["live/fstab"] != restoreUnchanged {
+		t.Errorf("second restore effects = %v", got)
+	}
+	if _, err := os.Lstat(dst + ".snapper-tui-stamp2"); !os.IsNotExist(err) {
+		t.Error("an unchanged file was backed up")
+	}
+}
+
+func TestRestoreSymlink(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "snap", "link"), filepath.Join(dir, "live", "link")
+	os.MkdirAll(filepath.Dir(src), 0o755)
+	os.MkdirAll(filepath.Dir(dst), 0o755)
+	if err := os.Symlink("/etc/new", src); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc/old", dst); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := restoreTree(src, dst, "stamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := effects(t, dir, files); got["live/link"] != restoreOverwritten {
+		t.Errorf("effects = %v", got)
+	}
+	if target, _ := os.Readlink(dst); target != "/etc/new" {
+		t.Errorf("link points to %q", target)
+	}
+	if target, _ := os.Readlink(dst + ".snapper-tui-stamp"); target != "/etc/old" {
+		t.Errorf("backup link points to %q", target)
+	}
+}
+
+func TestRestoreDirectory(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "snap", "conf"), filepath.Join(dir, "live", "conf")
+	writeFile(t, filepath.Join(src, "same"), "same", 0o644)
+	writeFile(t, filepath.Join(src, "changed"), "old", 0o644)
+	writeFile(t, filepath.Join(src, "sub", "gone"), "gone", 0o600)
+	writeFile(t, filepath.Join(src, "dir"), "was a file", 0o644)
+	os.Chmod(src, 0o750)
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(src, "sub"), mtime, mtime)
+
+	writeFile(t, filepath.Join(dst, "same"), "same", 0o644)
+	writeFile(t, filepath.Join(dst, "changed"), "new", 0o644)
+	writeFile(t, filepath.Join(dst, "extra"), "not in the snapshot", 0o644)
+	os.MkdirAll(filepath.Join(dst, "dir"), 0o755)
+	os.Chmod(dst, 0o755)
+
+	files, err := restoreTree(src, dst, "stamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"live/conf":          restoreMetadata,
+		"live/conf/same":     restoreUnchanged,
+		"live/conf/changed":  restoreOverwritten,
+		"live/conf/dir":      restoreOverwritten,
+		"live/conf/sub":      restoreCreated,
+		"live/conf/sub/gone": restoreCreated,
+	}
+	got := effects(t, dir, files)
+	for path, effect := range want {
+		if got[path] != effect {
+			t.Errorf("%s: effect %q, want %q", path, got[path], effect)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("effects = %v", got)
+	}
+
+	if got := readFile(t, filepath.Join(dst, "sub", "gone")); got != "gone" {
+		t.Errorf("created file = %q", got)
+	}
+	if got := readFile(t, filepath.Join(dst, "extra")); got != "not in the snapshot" {
+		t.Errorf("extra file = %q", got)
+	}
+	if info, _ := os.Stat(filepath.Join(dst, "dir.snapper-tui-stamp")); info == nil || !info.IsDir() {
+		t.Error("the replaced directory was not backed up")
+	}
+	// Created directories get the snapshot's mtime, existing ones its mode
+	if info, _ := os.Stat(filepath.Join(dst, "sub")); !info.ModTime().Equal(mtime) {
+		t.Errorf("created directory mtime = %v", info.ModTime())
+	}
+	if info, _ := os.Stat(dst); info.Mode().Perm() != 0o750 {
+		t.Errorf("directory mode = %v", info.Mode())
+	}
+
+	input := &restoreFormInput{Snap: Snapshot{Config: "root", Number: 7}, Live: dst}
+	summary := restoreSummary(input, files)
+	if first := firstLine(summary); first != "Restored "+dst+" from root #7: 2 overwritten, 2 created, 1 metadata restored, 1 unchanged, 0 skipped, 0 failed" {
+		t.Errorf("summary = %q", first)
+	}
+	for _, section := range []string{"Overwritten:", "Created:", "Metadata restored:"} {
+		if !strings.Contains(summary, "\n\n"+section) {
+			t.Errorf("summary lacks %s:\n%s", section, summary)
+		}
+	}
+}
+
+func TestRestoreLeavesMatchingDirectoriesAlone(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "snap", "conf"), filepath.Join(dir, "live", "conf")
+	writeFile(t, filepath.Join(src, "file"), "data", 0o644)
+	writeFile(t, filepath.Join(dst, "file"), "data", 0o644)
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(dst, mtime, mtime)
+
+	files, err := restoreTree(src, dst, "stamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := effects(t, dir, files); got["live/conf"] != restoreUnchanged {
+		t.Errorf("effects = %v", got)
+	}
+	if info, _ := os.Stat(dst); !info.ModTime().Equal(mtime) {
+		t.Errorf("mtime of an unchanged directory moved to %v", info.ModTime())
+	}
+}
+
+func TestRestoreNeedsParent(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "snap", "fstab")
+	writeFile(t, src, "data", 0o644)
+	_, err := restoreTree(src, filepath.Join(dir, "missing", "fstab"), "stamp")
+	if err == nil || !strings.Contains(err.Error(), "restore the parent instead") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSameFile(t *testing.T) {
+	dir := t.TempDir()
+	a, b, c := filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")
+	writeFile(t, a, "abc", 0o644)
+	writeFile(t, b, "abc", 0o644)
+	writeFile(t, c, "xyz", 0o644)
+	stat := func(path string) os.FileInfo {
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info
+	}
+	if !sameFile(a, b, stat(a), stat(b)) {
+		t.Error("identical files differ")
+	}
+	if sameFile(a, c, stat(a), stat(c)) {
+		t.Error("files of the same size with other content match")
+	}
+	os.Chmod(b, 0o600)
+	if sameFile(a, b, stat(a), stat(b)) {
+		t.Error("files with another mode match")
+	}
+}
+
+func TestCopyXattrs(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	writeFile(t, src, "", 0o644)
+	writeFile(t, dst, "", 0o644)
+	if err := syscall.Setxattr(src, "user.snapper-tui", []byte("yes"), 0); err != nil {
+		if errors.Is(err, syscall.ENOTSUP) {
+			t.Skip("no xattr support in the temp directory")
+		}
+		t.Fatal(err)
+	}
+	if err := copyXattrs(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	value := make([]byte, 16)
+	n, err := syscall.Getxattr(dst, "user.snapper-tui", value)
+	if err != nil || string(value[:n]) != "yes" {
+		t.Errorf("copied xattr = %q, %v", value[:n], err)
+	}
+}
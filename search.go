@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxSearchMatches = 10000                  // the search stops after this many matches
+	maxSearchLine    = 1024 * 1024            // longer lines end the scan of a file
+	maxMatchText     = 512                    // bytes of a matching line kept for display
+	searchFlushEvery = 100 * time.Millisecond // how often progress is sent to the UI
+	searchBatchSize  = 200                    // matches that trigger an early flush
+)
+
+// SearchQuery is a content search over the files of some snapshots
+type SearchQuery struct {
+	Pattern *regexp.Regexp
+	Glob    string // optional; matched against the base name, or the path when it contains "/"
+	Snaps   []Snapshot
+}
+
+// SearchMatch is one matching line in a snapshot file
+type SearchMatch struct {
+	Snap Snapshot
+	Path string // snapshot-relative
+	Line int
+	Text string
+}
+
+// SearchProgress is a batch of new matches with running totals
+type SearchProgress struct {
+	Matches   []SearchMatch
+	Files     int64 // files scanned so far
+	Snapshot  int   // index into the query's snapshots being walked
+	Truncated bool  // stopped at maxSearchMatches
+	Errors    int64 // files or directories that could not be read
+}
+
+// searchJob is one file for a worker to scan
+type searchJob struct {
+	snap Snapshot
+	path string // absolute path inside the snapshot
+	rel  string
+}
+
+// searchWorkers is the size of the worker pool scanning files
+func searchWorkers() int {
+	return max(2, min(8, runtime.NumCPU()))
+}
+
+// globRegexp turns a path glob into a regexp: "**" crosses directories, "*"
+// and "?" do not
+func globRegexp(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// startSearch scans the snapshots with a bounded pool of workers and streams
+// progress on the returned channel, which is closed when the search ends or
+// ctx is cancelled
+func startSearch(ctx context.Context, query SearchQuery) <-chan SearchProgress {
+	out := make(chan SearchProgress)
+	parent := ctx
+	ctx, stop := context.WithCancel(parent)
+
+	var glob *regexp.Regexp
+	globPath := strings.Contains(query.Glob, "/")
+	if query.Glob != "" {
+		glob = globRegexp(query.Glob)
+	}
+
+	var files, errs atomic.Int64
+	var walking atomic.Int32
+	jobs := make(chan searchJob, 256)
+	found := make(chan []SearchMatch, 64)
+
+	// Walker: lists regular files without following symlinks
+	go func() {
+		defer close(jobs)
+		for i, snap := range query.Snaps {
+			walking.Store(int32(i))
+			root := snapshotRoot(snap)
+			filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+				if ctx.Err() != nil {
+					return fs.SkipAll
+				}
+				if err != nil {
+					errs.Add(1)
+					return nil
+				}
+				if !d.Type().IsRegular() {
+					return nil
+				}
+				rel := "/" + strings.TrimPrefix(strings.TrimPrefix(path, root), "/")
+				if glob != nil {
+					name := d.Name()
+					if globPath {
+						name = rel
+					}
+					if !glob.MatchString(name) {
+						return nil
+					}
+				}
+				select {
+				case jobs <- searchJob{snap: snap, path: path, rel: rel}:
+					return nil
+				case <-ctx.Done():
+					return fs.SkipAll
+				}
+			})
+		}
+	}()
+
+	// Workers: scan one file at a time
+	var wg sync.WaitGroup
+	for i := 0; i < searchWorkers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				matches, err := grepFile(ctx, job, query.Pattern)
+				files.Add(1)
+				if err != nil {
+					errs.Add(1)
+				}
+				if len(matches) == 0 {
+					continue
+				}
+				select {
+				case found <- matches:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(found)
+	}()
+
+	// Collector: batches matches so the UI is not flooded with messages
+	go func() {
+		defer close(out)
+		defer stop()
+		ticker := time.NewTicker(searchFlushEvery)
+		defer ticker.Stop()
+
+		total := 0
+		truncated := false
+		var pending []SearchMatch
+		progress := func() SearchProgress {
+			p := SearchProgress{
+				Matches:   pending,
+				Files:     files.Load(),
+				Snapshot:  int(walking.Load()),
+				Truncated: truncated,
+				Errors:    errs.Load(),
+			}
+			pending = nil
+			return p
+		}
+		// Sends only give up when the caller cancels, so the final
+		// progress still arrives after hitting maxSearchMatches
+		send := func(p SearchProgress) bool {
+			select {
+			case out <- p:
+				return true
+			case <-parent.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case matches, ok := <-found:
+				if !ok {
+					send(progress())
+					return
+				}
+				if truncated {
+					continue
+				}
+				if room := maxSearchMatches - total; len(matches) >= room {
+					matches = matches[:room]
+					truncated = true
+					stop()
+				}
+				total += len(matches)
+				pending = append(pending, matches...)
+				if len(pending) >= searchBatchSize && !send(progress()) {
+					return
+				}
+			case <-ticker.C:
+				if !send(progress()) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// grepFile returns the lines of a file matching pattern; binary files are
+// skipped
+func grepFile(ctx context.Context, job searchJob, pattern *regexp.Regexp) ([]SearchMatch, error) {
+	file, err := os.Open(job.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	head, err := reader.Peek(8192)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	var matches []SearchMatch
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxSearchLine)
+	for line := 1; scanner.Scan(); line++ {
+		if line%1000 == 0 && ctx.Err() != nil {
+			break
+		}
+		text := scanner.Bytes()
+		if !pattern.Match(text) {
+			continue
+		}
+		if len(text) > maxMatchText {
+			text = text[:maxMatchText]
+		}
+		matches = append(matches, SearchMatch{
+			Snap: job.snap,
+			Path: job.rel,
+			Line: line,
+			Text: string(bytes.ToValidUTF8(text, []byte("�"))),
+		})
+	}
+	return matches, scanner.Err()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// searchSnap creates snapshot n of a temp subvolume with the given files
+func searchSnap(t *testing.T, subvolume string, n int, files map[string]string) Snapshot {
+	t.Helper()
+	snap := Snapshot{Config: "root", Subvolume: subvolume, Number: n}
+	for rel, content := range files {
+		writeFile(t, filepath.Join(snapshotRoot(snap), rel), content, 0o644)
+	}
+	return snap
+}
+
+// collectSearch drains a search, failing the test if it does not end in time
+func collectSearch(t *testing.T, progress <-chan SearchProgress) []SearchProgress {
+	t.Helper()
+	var all []SearchProgress
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case p, ok := <-progress:
+			if !ok {
+				return all
+			}
+			all = append(all, p)
+		case <-timeout:
+			t.Fatal("search did not finish")
+		}
+	}
+}
+
+// matchLines renders matches as "snapshot:path:line:text", sorted
+func matchLines(updates []SearchProgress) []string {
+	var lines []string
+	for _, p := range updates {
+		for _, m := range p.Matches {
+			lines = append(lines, fmt.Sprintf("%d:%s:%d:%s", m.Snap.Number, m.Path, m.Line, m.Text))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestSearchFindsMatchesAcrossSnapshots(t *testing.T) {
+	sub := t.TempDir()
+	snaps := []Snapshot{
+		searchSnap(t, sub, 1, map[string]string{
+			"etc/fstab":       "UUID=1 / btrfs defaults\n# swap\n",
+			"etc/hosts":       "127.0.0.1 localhost\n",
+			"usr/lib/blob.so": "btrfs\x00binary",
+		}),
+		searchSnap(t, sub, 2, map[string]string{
+			"etc/fstab":        "UUID=2 / btrfs noatime\n",
+			"etc/default/grub": "rootflags=subvol=@\n# btrfs root\n",
+		}),
+	}
+	query := SearchQuery{Pattern: regexp.MustCompile(`btrfs`), Snaps: snaps}
+	updates := collectSearch(t, startSearch(context.Background(), query))
+
+	want := []string{
+		"1:/etc/fstab:1:UUID=1 / btrfs defaults",
+		"2:/etc/default/grub:2:# btrfs root",
+		"2:/etc/fstab:1:UUID=2 / btrfs noatime",
+	}
+	if got := matchLines(updates); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("matches:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	last := updates[len(updates)-1]
+	if last.Files != 5 || last.Errors != 0 || last.Truncated {
+		t.Errorf("final progress = %+v", last)
+	}
+}
+
+func TestSearchGlob(t *testing.T) {
+	sub := t.TempDir()
+	snap := searchSnap(t, sub, 1, map[string]string{
+		"etc/fstab":              "needle\n",
+		"etc/snapper/root.conf":  "needle\n",
+		"etc/snapper/configs/x":  "needle\n",
+		"home/alice/notes.conf":  "needle\n",
+		"home/alice/notes.conf~": "needle\n",
+	})
+	tests := []struct {
+		glob string
+		want []string
+	}{
+		{"*.conf", []string{"/etc/snapper/root.conf", "/home/alice/notes.conf"}},
+		{"/etc/*", []string{"/etc/fstab"}},
+		{"/etc/**", []string{"/etc/fstab", "/etc/snapper/configs/x", "/etc/snapper/root.conf"}},
+		{"fsta?", []string{"/etc/fstab"}},
+		{"*.txt", nil},
+	}
+	for _, tt := range tests {
+		query := SearchQuery{Pattern: regexp.MustCompile(`needle`), Glob: tt.glob, Snaps: []Snapshot{snap}}
+		var got []string
+		for _, p := range collectSearch(t, startSearch(context.Background(), query)) {
+			for _, m := range p.Matches {
+				got = append(got, m.Path)
+			}
+		}
+		sort.Strings(got)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("glob %q matched %v, want %v", tt.glob, got, tt.want)
+		}
+	}
+}
+
+func TestSearchStreamsInBatches(t *testing.T) {
+	sub := t.TempDir()
+	files := map[string]string{}
+	for i := 0; i < 5*searchBatchSize; i++ {
+		files[fmt.Sprintf("d%d/f%d", i%10, i)] = "match\n"
+	}
+	snap := searchSnap(t, sub, 1, files)
+	query := SearchQuery{Pattern: regexp.MustCompile(`match`), Snaps: []Snapshot{snap}}
+	updates := collectSearch(t, startSearch(context.Background(), query))
+
+	batches, total := 0, 0
+	for _, p := range updates {
+		if len(p.Matches) > 0 {
+			batches++
+		}
+		total += len(p.Matches)
+	}
+	if total != len(files) {
+		t.Errorf("%d matches, want %d", total, len(files))
+	}
+	if batches < 2 {
+		t.Errorf("all matches arrived in %d batch(es)", batches)
+	}
+}
+
+func TestSearchTruncates(t *testing.T) {
+	sub := t.TempDir()
+	snap := searchSnap(t, sub, 1, map[string]string{
+		"big":  strings.Repeat("hit\n", maxSearchMatches+50),
+		"long": strings.Repeat("x", maxMatchText+10) + "\n",
+	})
+	query := SearchQuery{Pattern: regexp.MustCompile(`hit|x`), Snaps: []Snapshot{snap}}
+	updates := collectSearch(t, startSearch(context.Background(), query))
+
+	total := 0
+	for _, p := range updates {
+		total += len(p.Matches)
+		for _, m := range p.Matches {
+			if len(m.Text) > maxMatchText {
+				t.Fatalf("kept %d bytes of a matching line", len(m.Text))
+			}
+		}
+	}
+	if total != maxSearchMatches {
+		t.Errorf("%d matches, want %d", total, maxSearchMatches)
+	}
+	if !updates[len(updates)-1].Truncated {
+		t.Error("final progress is not marked truncated")
+	}
+}
+
+func TestSearchCancel(t *testing.T) {
+	sub := t.TempDir()
+	files := map[string]string{}
+	for i := 0; i < 2000; i++ {
+		files[fmt.Sprintf("f%d", i)] = strings.Repeat("needle\n", 20)
+	}
+	snap := searchSnap(t, sub, 1, files)
+	ctx, cancel := context.WithCancel(context.Background())
+	query := SearchQuery{Pattern: regexp.MustCompile(`needle`), Snaps: []Snapshot{snap}}
+	progress := startSearch(ctx, query)
+
+	// Take the first batch, then cancel; the channel must still close
+	select {
+	case <-progress:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no progress")
+	}
+	cancel()
+	total := 0
+	for _, p := range collectSearch(t, progress) {
+		total += len(p.Matches)
+	}
+	if total >= 2000*20 {
+		t.Errorf("cancelled search still delivered all %d matches", total)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var searchPathStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#7dd3fc"))
+
+// SearchView streams the matches of a content search across snapshots
+type SearchView struct {
+	Query     SearchQuery
+	Updates   <-chan SearchProgress // nil once the search has ended
+	Cancel    context.CancelFunc
+	Matches   []SearchMatch
+	Progress  SearchProgress // latest totals
+	Cancelled bool
+	Cursor    int
+	Offset    int
+}
+
+// SearchProgressMsg carries a batch of matches; Done is set when the search
+// has ended
+type SearchProgressMsg struct {
+	Updates  <-chan SearchProgress
+	Progress SearchProgress
+	Done     bool
+}
+
+// searchFormInput holds the values bound to the search dialog
+type searchFormInput struct {
+	Snaps   []Snapshot
+	Pattern string
+	Glob    string
+}
+
+// waitSearchCmd waits for the next batch of a running search
+func waitSearchCmd(updates <-chan SearchProgress) tea.Cmd {
+	return func() tea.Msg {
+		progress, ok := <-updates
+		return SearchProgressMsg{Updates: updates, Progress: progress, Done: !ok}
+	}
+}
+
+// searchTargets are the selected snapshots, or the one under the cursor, less
+// the live system
+func (m UIState) searchTargets() []Snapshot {
+	var targets []Snapshot
+	for _, snap := range m.Visible {
+		if m.SelectedSnapshots[snap.Key()] && snap.Number != 0 {
+			targets = append(targets, snap)
+		}
+	}
+	if len(targets) == 0 {
+		if snap := m.currentSnapshot(); snap != nil && snap.Number != 0 {
+			targets = []Snapshot{*snap}
+		}
+	}
+	return targets
+}
+
+// openSearchForm asks for the regex and path glob to search for
+func (m *UIState) openSearchForm(snaps []Snapshot, pattern, glob string) tea.Cmd {
+	if len(snaps) == 0 {
+		m.Status = "Select snapshots to search; snapshot 0 is the live system"
+		return nil
+	}
+
+	// huh copies bound values when a field is built, so prefill first
+	input := &searchFormInput{Snaps: snaps, Pattern: pattern, Glob: glob}
+	m.SearchInput = input
+	m.FormKind = "search"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Regular expression").
+				Description(fmt.Sprintf("Searched in %s", describeTargets(snaps))).
+				Placeholder(`UUID=[0-9a-f-]+`).
+				Validate(func(pattern string) error {
+					if pattern == "" {
+						return fmt.Errorf("enter a pattern")
+					}
+					_, err := regexp.Compile(pattern)
+					return err
+				}).
+				Value(&input.Pattern),
+			huh.NewInput().
+				Title("Path glob (optional)").
+				Description(`"*.conf" matches names; "/etc/**" matches paths`).
+				Placeholder("/etc/**").
+				Value(&input.Glob),
+		),
+	).WithShowHelp(true).WithWidth(70)
+
+	m.Status = "Search snapshots: enter to continue, esc to cancel"
+	return m.Form.Init()
+}
+
+// startSearchView cancels any running search and starts a new one
+func (m UIState) startSearchView(input *searchFormInput) (tea.Model, tea.Cmd) {
+	if m.SearchView != nil && m.SearchView.Cancel != nil {
+		m.SearchView.Cancel()
+	}
+	query := SearchQuery{
+		Pattern: regexp.MustCompile(input.Pattern),
+		Glob:    strings.TrimSpace(input.Glob),
+		Snaps:   input.Snaps,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := startSearch(ctx, query)
+	m.SearchView = &SearchView{Query: query, Updates: updates, Cancel: cancel}
+	m.Screen = "search"
+	m.Status = fmt.Sprintf("Searching %s for /%s/...", describeTargets(query.Snaps), input.Pattern)
+	return m, waitSearchCmd(updates)
+}
+
+// handleSearchProgress appends a batch of matches, ignoring searches that
+// have been replaced or cancelled
+func (m UIState) handleSearchProgress(msg SearchProgressMsg) (tea.Model, tea.Cmd) {
+	v := m.SearchView
+	if v == nil || v.Updates == nil || v.Updates != msg.Updates {
+		return m, nil
+	}
+	if msg.Done {
+		v.Updates = nil
+		v.Cancel()
+		m.Status = fmt.Sprintf("Search finished: %s", v.countText())
+		return m, nil
+	}
+	v.Matches = append(v.Matches, msg.Progress.Matches...)
+	msg.Progress.Matches = nil
+	v.Progress = msg.Progress
+	return m, waitSearchCmd(v.Updates)
+}
+
+// stop cancels a running search; matches found so far are kept
+func (v *SearchView) stop() {
+	if v.Updates == nil {
+		return
+	}
+	v.Cancel()
+	v.Updates = nil
+	v.Cancelled = true
+}
+
+// countText summarises the matches and files scanned so far
+func (v *SearchView) countText() string {
+	text := fmt.Sprintf("%d match(es) in %d file(s) scanned", len(v.Matches), v.Progress.Files)
+	if v.Progress.Truncated {
+		text += fmt.Sprintf(", stopped at %d matches", maxSearchMatches)
+	}
+	if v.Progress.Errors > 0 {
+		text += fmt.Sprintf(", %d unreadable", v.Progress.Errors)
+	}
+	return text
+}
+
+// moveCursor moves the highlighted match and scrolls it into view
+func (v *SearchView) moveCursor(delta, page int) {
+	if len(v.Matches) == 0 {
+		v.Cursor, v.Offset = 0, 0
+		return
+	}
+	v.Cursor = max(0, min(len(v.Matches)-1, v.Cursor+delta))
+	if v.Cursor < v.Offset {
+		v.Offset = v.Cursor
+	}
+	if v.Cursor >= v.Offset+page {
+		v.Offset = v.Cursor - page + 1
+	}
+	v.Offset = max(0, min(v.Offset, len(v.Matches)-page))
+}
+
+// openSearchMatch shows the file of the match under the cursor in the
+// snapshot browser
+func (m UIState) openSearchMatch() (tea.Model, tea.Cmd) {
+	v := m.SearchView
+	if v.Cursor >= len(v.Matches) {
+		return m, nil
+	}
+	match := v.Matches[v.Cursor]
+	root := snapshotRoot(match.Snap)
+	m.BrowserView = &BrowserView{Snap: match.Snap, Root: root, Dir: "/", Back: "search"}
+	m.Screen = "browse"
+	m.Status = fmt.Sprintf("%s line %d in %s #%d", match.Path, match.Line, match.Snap.Config, match.Snap.Number)
+	return m, browseDirCmd(root, filepath.Dir(match.Path), filepath.Base(match.Path))
+}
+
+// handleSearchKey drives the results pane
+func (m UIState) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.SearchView
+	page := m.pageHeight()
+	switch msg.String() {
+	case "esc", "q":
+		if v.Updates != nil && msg.String() == "esc" {
+			v.stop()
+			m.Status = fmt.Sprintf("Search cancelled: %s", v.countText())
+			return m, nil
+		}
+		v.stop()
+		m.Screen = ""
+		m.SearchView = nil
+		m.Status = "Closed search"
+	case "j", "down":
+		v.moveCursor(1, page)
+	case "k", "up":
+		v.moveCursor(-1, page)
+	case "pgdn", "pagedown", " ":
+		v.moveCursor(page, page)
+	case "pgup", "pageup":
+		v.moveCursor(-page, page)
+	case "g", "home":
+		v.moveCursor(-len(v.Matches), page)
+	case "G", "end":
+		v.moveCursor(len(v.Matches), page)
+	case "enter", "b":
+		return m.openSearchMatch()
+	case "/":
+		return m, m.openSearchForm(v.Query.Snaps, v.Query.Pattern.String(), v.Query.Glob)
+	}
+	return m, nil
+}
+
+// handleSearchMouse scrolls with the wheel; a click selects a match and a
+// click on the selected match opens it
+func (m UIState) handleSearchMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	v := m.SearchView
+	page := m.pageHeight()
+	switch msg.Type {
+	case tea.MouseWheelUp:
+		v.moveCursor(-3, page)
+	case tea.MouseWheelDown:
+		v.moveCursor(3, page)
+	case tea.MouseLeft:
+		row := msg.Y - 3
+		pos := v.Offset + row
+		if row < 0 || row >= page || pos >= len(v.Matches) {
+			return m, nil
+		}
+		if pos == v.Cursor {
+			return m.openSearchMatch()
+		}
+		v.moveCursor(pos-v.Cursor, page)
+	}
+	return m, nil
+}
+
+// renderSearchView draws one row per match with the matched text highlighted
+func (m UIState) renderSearchView(width, height int) string {
+	v := m.SearchView
+	page := m.pageHeight()
+
+	title := detailHeaderStyle.Render(fmt.Sprintf("Search /%s/ in %s", v.Query.Pattern, describeTargets(v.Query.Snaps)))
+	state := "done"
+	switch {
+	case v.Updates != nil:
+		state = fmt.Sprintf("searching snapshot %d of %d", v.Progress.Snapshot+1, len(v.Query.Snaps))
+	case v.Cancelled:
+		state = "cancelled"
+	}
+	info := fmt.Sprintf("%s | %s", state, v.countText())
+	if v.Query.Glob != "" {
+		info += " | glob " + v.Query.Glob
+	}
+
+	var body []string
+	if len(v.Matches) == 0 {
+		if v.Updates != nil {
+			body = append(body, "No matches yet...")
+		} else {
+			body = append(body, "No matches.")
+		}
+	}
+	for pos := v.Offset; pos < min(v.Offset+page, len(v.Matches)); pos++ {
+		match := v.Matches[pos]
+		location := padOrTruncate(fmt.Sprintf("#%d %s:%d", match.Snap.Number, match.Path, match.Line), max(20, width*2/5))
+		base := lipgloss.NewStyle()
+		if pos == v.Cursor {
+			base = pagerCursorStyle
+		}
+		line := searchPathStyle.Inherit(base).Render(location) + base.Render("  ") +
+			highlightRegexp(match.Text, v.Query.Pattern, max(10, width-lipgloss.Width(location)-2), base)
+		body = append(body, line)
+	}
+
+	footer := pagerHelpStyle.Render("↑↓/PgUp/PgDn/g/G: Move | enter/b/click: Open in browser | /: New search | esc: Cancel search, then back | q: Back")
+	ui := lipgloss.JoinVertical(lipgloss.Left,
+		headerStyle.Width(width).Render(m.headerTitle()),
+		title,
+		summaryStyle.Render(padOrTruncate(info, width)),
+		lipgloss.NewStyle().Height(page).Render(strings.Join(body, "\n")),
+		footer,
+	)
+	return lipgloss.Place(width, height, lipgloss.Top, lipgloss.Left, ui)
+}
+
+// highlightRegexp fits a matching line into width, scrolled so the first
+// match is visible, and highlights every match
+func highlightRegexp(text string, pattern *regexp.Regexp, width int, base lipgloss.Style) string {
+	text = strings.TrimLeft(strings.ReplaceAll(text, "\t", "    "), " ")
+	runes := []rune(text)
+	if loc := pattern.FindStringIndex(text); loc != nil {
+		start := len([]rune(text[:loc[0]]))
+		if end := len([]rune(text[:loc[1]])); end > width {
+			skip := min(start, max(0, start-width/3))
+			runes = append([]rune("…"), runes[skip+1:]...)
+		}
+	}
+	if len(runes) > width {
+		runes = append(runes[:width-1], '…')
+	}
+	visible := string(runes)
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringIndex(visible, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		b.WriteString(base.Render(visible[last:loc[0]]))
+		b.WriteString(pagerMatchStyle.Render(visible[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(base.Render(visible[last:]))
+	return b.String()
+}
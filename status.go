@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangeKind classifies a FileChange for filtering and colouring
+type ChangeKind string
+
+const (
+	ChangeAll         ChangeKind = "all"
+	ChangeCreated     ChangeKind = "created"
+	ChangeDeleted     ChangeKind = "deleted"
+	ChangeContent     ChangeKind = "content"
+	ChangeType        ChangeKind = "type"
+	ChangePermissions ChangeKind = "permissions"
+	ChangeOwner       ChangeKind = "owner"
+	ChangeXattr       ChangeKind = "xattr"
+	ChangeACL         ChangeKind = "acl"
+)
+
+// changeKinds is the filter cycle order of the status viewer
+var changeKinds = []ChangeKind{
+	ChangeAll,
+	ChangeCreated,
+	ChangeDeleted,
+	ChangeContent,
+	ChangeType,
+	ChangePermissions,
+	ChangeOwner,
+	ChangeXattr,
+	ChangeACL,
+}
+
+// Flag is the letter "snapper status" uses for the kind
+func (k ChangeKind) Flag() string {
+	switch k {
+	case ChangeCreated:
+		return "+"
+	case ChangeDeleted:
+		return "-"
+	case ChangeContent:
+		return "c"
+	case ChangeType:
+		return "t"
+	case ChangePermissions:
+		return "p"
+	case ChangeOwner:
+		return "u"
+	case ChangeXattr:
+		return "x"
+	case ChangeACL:
+		return "a"
+	}
+	return ""
+}
+
+// FileChange is one parsed line of "snapper status"
+type FileChange struct {
+	Path        string
+	Created     bool
+	Deleted     bool
+	TypeChanged bool
+	Content     bool
+	Permissions bool
+	User        bool
+	Group       bool
+	Xattr       bool
+	ACL         bool
+}
+
+// parseStatusLine parses "c.ug.. /path" style lines; the flag columns are
+// content/type/created/deleted, permissions, user, group, then xattr and acl
+// when snapper compares them
+func parseStatusLine(line string) (FileChange, bool) {
+	flags, path, ok := strings.Cut(line, " ")
+	if !ok || len(flags) < 4 || len(flags) > 6 || path == "" {
+		return FileChange{}, false
+	}
+	for i, c := range flags {
+		if c != '.' && !strings.ContainsRune(statusFlagAlphabet[i], c) {
+			return FileChange{}, false
+		}
+	}
+
+	flag := func(i int, c byte) bool { return i < len(flags) && flags[i] == c }
+	return FileChange{
+		Path:        path,
+		Created:     flag(0, '+'),
+		Deleted:     flag(0, '-'),
+		TypeChanged: flag(0, 't'),
+		Content:     flag(0, 'c'),
+		Permissions: flag(1, 'p'),
+		User:        flag(2, 'u'),
+		Group:       flag(3, 'g'),
+		Xattr:       flag(4, 'x'),
+		ACL:         flag(5, 'a'),
+	}, true
+}
+
+// statusFlagAlphabet lists the letters allowed in each flag column
+var statusFlagAlphabet = []string{"+-tc", "p", "u", "g", "x", "a"}
+
+// parseStatusOutput parses every line it can; unparseable lines come back as nil
+func parseStatusOutput(lines []string) []*FileChange {
+	changes := make([]*FileChange, len(lines))
+	for i, line := range lines {
+		if change, ok := parseStatusLine(line); ok {
+			changes[i] = &change
+		}
+	}
+	return changes
+}
+
+// Is reports whether the change belongs to the given kind
+func (c FileChange) Is(kind ChangeKind) bool {
+	switch kind {
+	case ChangeAll:
+		return true
+	case ChangeCreated:
+		return c.Created
+	case ChangeDeleted:
+		return c.Deleted
+	case ChangeContent:
+		return c.Content
+	case ChangeType:
+		return c.TypeChanged
+	case ChangePermissions:
+		return c.Permissions
+	case ChangeOwner:
+		return c.User || c.Group
+	case ChangeXattr:
+		return c.Xattr
+	case ChangeACL:
+		return c.ACL
+	}
+	return false
+}
+
+// PrimaryKind is the kind a change is coloured by: created, deleted, type
+// and content changes win over metadata-only ones
+func (c FileChange) PrimaryKind() ChangeKind {
+	switch {
+	case c.Created:
+		return ChangeCreated
+	case c.Deleted:
+		return ChangeDeleted
+	case c.TypeChanged:
+		return ChangeType
+	case c.Content:
+		return ChangeContent
+	case c.Permissions:
+		return ChangePermissions
+	case c.User || c.Group:
+		return ChangeOwner
+	case c.Xattr:
+		return ChangeXattr
+	case c.ACL:
+		return ChangeACL
+	}
+	return ChangeAll
+}
+
+// Flags renders the change back in "snapper status" notation
+func (c FileChange) Flags() string {
+	first := byte('.')
+	switch {
+	case c.Created:
+		first = '+'
+	case c.Deleted:
+		first = '-'
+	case c.TypeChanged:
+		first = 't'
+	case c.Content:
+		first = 'c'
+	}
+	mark := func(set bool, ch byte) byte {
+		if set {
+			return ch
+		}
+		return '.'
+	}
+	return string([]byte{
+		first,
+		mark(c.Permissions, 'p'),
+		mark(c.User, 'u'),
+		mark(c.Group, 'g'),
+		mark(c.Xattr, 'x'),
+		mark(c.ACL, 'a'),
+	})
+}
+
+// countChangeKinds tallies the parsed changes per kind
+func countChangeKinds(changes []*FileChange) map[ChangeKind]int {
+	counts := map[ChangeKind]int{}
+	for _, change := range changes {
+		if change == nil {
+			continue
+		}
+		for _, kind := range changeKinds {
+			if change.Is(kind) {
+				counts[kind]++
+			}
+		}
+	}
+	return counts
+}
+
+// changeCountsText renders the per-kind counts for the viewer header
+func changeCountsText(counts map[ChangeKind]int) string {
+	parts := make([]string, 0, len(changeKinds)-1)
+	for _, kind := range changeKinds[1:] {
+		parts = append(parts, fmt.Sprintf("%s %d", kind, counts[kind]))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseStatusLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		ok    bool
+		want  FileChange
+		flags string // Flags() of the parsed change
+	}{
+		{"c..... /etc/fstab", true, FileChange{Path: "/etc/fstab", Content: true}, "c....."},
+		{"+..... /usr/bin/new", true, FileChange{Path: "/usr/bin/new", Created: true}, "+....."},
+		{"-..... /usr/bin/old", true, FileChange{Path: "/usr/bin/old", Deleted: true}, "-....."},
+		{"t..... /usr/lib/libfoo.so", true, FileChange{Path: "/usr/lib/libfoo.so", TypeChanged: true}, "t....."},
+		{".p.... /etc/shadow", true, FileChange{Path: "/etc/shadow", Permissions: true}, ".p...."},
+		{"..u... /home/alice", true, FileChange{Path: "/home/alice", User: true}, "..u..."},
+		{"...g.. /home/alice", true, FileChange{Path: "/home/alice", Group: true}, "...g.."},
+		{"....x. /var/log", true, FileChange{Path: "/var/log", Xattr: true}, "....x."},
+		{".....a /srv", true, FileChange{Path: "/srv", ACL: true}, ".....a"},
+		{"cpugxa /etc/sudoers", true, FileChange{Path: "/etc/sudoers", Content: true, Permissions: true, User: true, Group: true, Xattr: true, ACL: true}, "cpugxa"},
+		// Without xattr and acl comparison snapper prints four columns
+		{"c.ug /etc/passwd", true, FileChange{Path: "/etc/passwd", Content: true, User: true, Group: true}, "c.ug.."},
+		{"c... /opt/with space/file", true, FileChange{Path: "/opt/with space/file", Content: true}, "c....."},
+
+		{"", false, FileChange{}, ""},
+		{"c.....", false, FileChange{}, ""},
+		{"c..... ", false, FileChange{}, ""},
+		{"c.. /too/short", false, FileChange{}, ""},
+		{"c...... /too/long", false, FileChange{}, ""},
+		{"cc.... /letter/in/wrong/column", false, FileChange{}, ""},
+		{"z..... /unknown/letter", false, FileChange{}, ""},
+		{"Failed to compare snapshots", false, FileChange{}, ""},
+	}
+	for _, tt := range tests {
+		got, ok := parseStatusLine(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseStatusLine(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+			continue
+		}
+		if ok && got.Flags() != tt.flags {
+			t.Errorf("parseStatusLine(%q).Flags() = %q, want %q", tt.line, got.Flags(), tt.flags)
+		}
+	}
+}
+
+func TestPrimaryKind(t *testing.T) {
+	tests := []struct {
+		line string
+		want ChangeKind
+	}{
+		{"+pugxa /a", ChangeCreated},
+		{"-..... /a", ChangeDeleted},
+		{"tp.... /a", ChangeType},
+		{"c.u... /a", ChangeContent},
+		{".pu... /a", ChangePermissions},
+		{"...gx. /a", ChangeOwner},
+		{"....xa /a", ChangeXattr},
+		{".....a /a", ChangeACL},
+		{"...... /a", ChangeAll},
+	}
+	for _, tt := range tests {
+		change, ok := parseStatusLine(tt.line)
+		if !ok {
+			t.Fatalf("parseStatusLine(%q) failed", tt.line)
+		}
+		if got := change.PrimaryKind(); got != tt.want {
+			t.Errorf("PrimaryKind of %q = %s, want %s", tt.line, got, tt.want)
+		}
+	}
+}
+
+// testStatusOutput has every kind once or more and a line that is not an entry
+const testStatusOutput = `+..... /etc/new.conf
+-..... /etc/old.conf
+c..... /etc/fstab
+cp.... /etc/shadow
+t..... /usr/lib/libfoo.so
+..ug.. /home/alice
+..u.x. /home/bob
+.....a /srv
+snapper: some warning
+`
+
+func TestChangeCounts(t *testing.T) {
+	v := newStatusView(Snapshot{Config: "root"}, 16, 17, testStatusOutput, false)
+	want := map[ChangeKind]int{
+		ChangeAll:         8,
+		ChangeCreated:     1,
+		ChangeDeleted:     1,
+		ChangeContent:     2,
+		ChangeType:        1,
+		ChangePermissions: 1,
+		ChangeOwner:       2,
+		ChangeXattr:       1,
+		ChangeACL:         1,
+	}
+	if fmt.Sprint(v.Counts) != fmt.Sprint(want) {
+		t.Errorf("counts = %v, want %v", v.Counts, want)
+	}
+	if got, want := changeCountsText(v.Counts), "created 1 · deleted 1 · content 2 · type 1 · permissions 1 · owner 2 · xattr 1 · acl 1"; got != want {
+		t.Errorf("counts text = %q, want %q", got, want)
+	}
+}
+
+func TestStatusViewFilters(t *testing.T) {
+	tests := []struct {
+		kind ChangeKind
+		want []string
+	}{
+		// All keeps lines that are not entries, so nothing snapper said is hidden
+		{ChangeAll, []string{"/etc/new.conf", "/etc/old.conf", "/etc/fstab", "/etc/shadow", "/usr/lib/libfoo.so", "/home/alice", "/home/bob", "/srv", "snapper: some warning"}},
+		{ChangeCreated, []string{"/etc/new.conf"}},
+		{ChangeDeleted, []string{"/etc/old.conf"}},
+		{ChangeContent, []string{"/etc/fstab", "/etc/shadow"}},
+		{ChangeType, []string{"/usr/lib/libfoo.so"}},
+		{ChangePermissions, []string{"/etc/shadow"}},
+		{ChangeOwner, []string{"/home/alice", "/home/bob"}},
+		{ChangeXattr, []string{"/home/bob"}},
+		{ChangeACL, []string{"/srv"}},
+	}
+	v := newStatusView(Snapshot{Config: "root"}, 16, 17, testStatusOutput, false)
+	for _, tt := range tests {
+		v.setFilter(tt.kind)
+		var got []string
+		for _, idx := range v.Rows {
+			if change := v.Changes[idx]; change != nil {
+				got = append(got, change.Path)
+			} else {
+				got = append(got, v.Lines[idx])
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("filter %s shows %q, want %q", tt.kind, got, tt.want)
+		}
+		if len(v.visibleLines()) != len(tt.want) {
+			t.Errorf("filter %s saves %d lines, want %d", tt.kind, len(v.visibleLines()), len(tt.want))
+		}
+	}
+
+	// Cycling wraps around in both directions
+	v.setFilter(ChangeAll)
+	v.cycleFilter(-1)
+	if v.Filter != ChangeACL {
+		t.Errorf("cycling back from all gives %s", v.Filter)
+	}
+	v.cycleFilter(1)
+	if v.Filter != ChangeAll {
+		t.Errorf("cycling on from acl gives %s", v.Filter)
+	}
+}
+
+func TestSaveStatusNeverOverwrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.txt")
+	lines := []string{"c..... /etc/fstab", "+..... /etc/new"}
+	if msg := saveStatusCmd(path, lines)().(StatusSavedMsg); msg.Err != nil || msg.Path != path {
+		t.Fatalf("first save = %+v", msg)
+	}
+	if got := readFile(t, path); got != "c..... /etc/fstab\n+..... /etc/new\n" {
+		t.Errorf("saved %q", got)
+	}
+
+	msg := saveStatusCmd(path, []string{"-..... /etc/old"})().(StatusSavedMsg)
+	if msg.Err == nil || !strings.Contains(msg.Err.Error(), "already exists") {
+		t.Errorf("second save error = %v", msg.Err)
+	}
+	if got := readFile(t, path); !strings.HasPrefix(got, "c..... /etc/fstab") {
+		t.Errorf("existing file overwritten with %q", got)
+	}
+
+	if msg := saveStatusCmd(filepath.Join(path, "under-a-file"), lines)().(StatusSavedMsg); msg.Err == nil {
+		t.Error("saved under a regular file")
+	}
+}
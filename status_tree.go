@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	treeDirStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#e2e8f0"))
+	treeCountStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#94a3b8"))
+)
+
+// StatusTreeNode is a path component in the directory tree of a status range.
+// Chains of unchanged single-child directories are merged into one node.
+type StatusTreeNode struct {
+	Name     string
+	Path     string
+	Line     int // index into StatusView.Lines, -1 if the node did not change itself
+	Children []*StatusTreeNode
+	Total    int                // changed entries beneath this node
+	Counts   map[ChangeKind]int // Total broken down by kind
+}
+
+// StatusTreeRow is a visible node of the tree at its indentation depth
+type StatusTreeRow struct {
+	Node  *StatusTreeNode
+	Depth int
+}
+
+// IsDir reports whether the node has entries beneath it
+func (n *StatusTreeNode) IsDir() bool {
+	return len(n.Children) > 0
+}
+
+// lines returns the status lines of the node and every node beneath it
+func (n *StatusTreeNode) lines() []int {
+	var lines []int
+	if n.Line >= 0 {
+		lines = append(lines, n.Line)
+	}
+	for _, child := range n.Children {
+		lines = append(lines, child.lines()...)
+	}
+	return lines
+}
+
+// buildStatusTree arranges the parsed changes at the given line indexes by path
+func buildStatusTree(rows []int, changes []*FileChange) *StatusTreeNode {
+	root := &StatusTreeNode{Path: "/", Line: -1, Counts: map[ChangeKind]int{}}
+	nodes := map[string]*StatusTreeNode{"/": root}
+
+	for _, idx := range rows {
+		change := changes[idx]
+		if change == nil {
+			continue
+		}
+		parts := strings.Split(strings.Trim(change.Path, "/"), "/")
+		parent := root
+		for i, part := range parts {
+			parent.Total++
+			for _, kind := range changeKinds[1:] {
+				if change.Is(kind) {
+					parent.Counts[kind]++
+				}
+			}
+
+			path := "/" + strings.Join(parts[:i+1], "/")
+			node, ok := nodes[path]
+			if !ok {
+				node = &StatusTreeNode{Name: part, Path: path, Line: -1, Counts: map[ChangeKind]int{}}
+				nodes[path] = node
+				parent.Children = append(parent.Children, node)
+			}
+			parent = node
+		}
+		parent.Line = idx
+	}
+
+	sortStatusTree(root)
+	for _, child := range root.Children {
+		compactStatusTree(child)
+	}
+	return root
+}
+
+// sortStatusTree orders directories before files, each by name
+func sortStatusTree(node *StatusTreeNode) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if a.IsDir() != b.IsDir() {
+			return a.IsDir()
+		}
+		return a.Name < b.Name
+	})
+	for _, child := range node.Children {
+		sortStatusTree(child)
+	}
+}
+
+// compactStatusTree merges unchanged directories whose only entry is another
+// unchanged directory, so /usr/lib/python3/site-packages takes one row
+func compactStatusTree(node *StatusTreeNode) {
+	for node.Line < 0 && len(node.Children) == 1 {
+		child := node.Children[0]
+		if child.Line >= 0 || !child.IsDir() {
+			break
+		}
+		node.Name += "/" + child.Name
+		node.Path = child.Path
+		node.Children = child.Children
+	}
+	for _, child := range node.Children {
+		compactStatusTree(child)
+	}
+}
+
+// flattenTree lists the rows visible with the current expansion state
+func (v *StatusView) flattenTree() {
+	v.TreeRows = v.TreeRows[:0]
+	var walk func(node *StatusTreeNode, depth int)
+	walk = func(node *StatusTreeNode, depth int) {
+		for _, child := range node.Children {
+			v.TreeRows = append(v.TreeRows, StatusTreeRow{Node: child, Depth: depth})
+			if child.IsDir() && v.Expanded[child.Path] {
+				walk(child, depth+1)
+			}
+		}
+	}
+	walk(v.Tree, 0)
+}
+
+// rebuildTree regenerates the tree for the current filter, keeping the cursor
+// on the same path when it is still present
+func (v *StatusView) rebuildTree() {
+	current := ""
+	if v.Tree != nil && v.Cursor < len(v.TreeRows) {
+		current = v.TreeRows[v.Cursor].Node.Path
+	}
+	v.Tree = buildStatusTree(v.Rows, v.Changes)
+	v.flattenTree()
+	v.Cursor, v.Offset = 0, 0
+	v.focusTreePath(current)
+}
+
+// focusTreePath puts the cursor on the row for path, if visible
+func (v *StatusView) focusTreePath(path string) bool {
+	for pos, row := range v.TreeRows {
+		if row.Node.Path == path {
+			v.Cursor = pos
+			return true
+		}
+	}
+	return false
+}
+
+// revealLine expands every directory above a status line and moves the
+// cursor to it
+func (v *StatusView) revealLine(idx int) {
+	change := v.Changes[idx]
+	if change == nil {
+		return
+	}
+	parts := strings.Split(strings.Trim(change.Path, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		v.Expanded["/"+strings.Join(parts[:i], "/")] = true
+	}
+	v.flattenTree()
+	for pos, row := range v.TreeRows {
+		if row.Node.Line == idx {
+			v.Cursor = pos
+			return
+		}
+	}
+}
+
+// toggleTree switches between the flat list and the tree, keeping the cursor
+// on the same entry
+func (v *StatusView) toggleTree(page int) {
+	if !v.TreeMode {
+		line := -1
+		if v.Cursor < len(v.Rows) {
+			line = v.Rows[v.Cursor]
+		}
+		v.TreeMode = true
+		v.Tree = buildStatusTree(v.Rows, v.Changes)
+		v.Cursor, v.Offset = 0, 0
+		v.flattenTree()
+		if line >= 0 {
+			v.revealLine(line)
+		}
+	} else {
+		var node *StatusTreeNode
+		if v.Cursor < len(v.TreeRows) {
+			node = v.TreeRows[v.Cursor].Node
+		}
+		v.TreeMode = false
+		v.Cursor, v.Offset = 0, 0
+		for pos, idx := range v.Rows {
+			if node == nil {
+				break
+			}
+			change := v.Changes[idx]
+			if idx == node.Line || (change != nil && strings.HasPrefix(change.Path, node.Path+"/")) {
+				v.Cursor = pos
+				break
+			}
+		}
+	}
+	v.search(v.Query)
+	v.moveCursor(0, page)
+}
+
+// setExpanded expands or collapses the directory at the cursor
+func (v *StatusView) setExpanded(expanded bool) {
+	if v.Cursor >= len(v.TreeRows) {
+		return
+	}
+	node := v.TreeRows[v.Cursor].Node
+	if !node.IsDir() {
+		return
+	}
+	v.Expanded[node.Path] = expanded
+	v.flattenTree()
+	v.search(v.Query)
+}
+
+// setAllExpanded expands or collapses every directory, keeping the cursor on
+// its node or the top-level directory holding it
+func (v *StatusView) setAllExpanded(expanded bool) {
+	current := ""
+	if v.Cursor < len(v.TreeRows) {
+		current = v.TreeRows[v.Cursor].Node.Path
+	}
+	var walk func(node *StatusTreeNode)
+	walk = func(node *StatusTreeNode) {
+		for _, child := range node.Children {
+			if child.IsDir() {
+				if expanded {
+					v.Expanded[child.Path] = true
+				} else {
+					delete(v.Expanded, child.Path)
+				}
+				walk(child)
+			}
+		}
+	}
+	walk(v.Tree)
+	v.flattenTree()
+	v.Cursor = 0
+	for pos, row := range v.TreeRows {
+		if row.Node.Path == current || strings.HasPrefix(current, row.Node.Path+"/") {
+			v.Cursor = pos
+			if row.Node.Path == current {
+				break
+			}
+		}
+	}
+	v.search(v.Query)
+}
+
+// treeParent returns the row position of the directory holding the cursor row
+func (v *StatusView) treeParent() int {
+	if v.Cursor >= len(v.TreeRows) {
+		return -1
+	}
+	depth := v.TreeRows[v.Cursor].Depth
+	for pos := v.Cursor - 1; pos >= 0; pos-- {
+		if v.TreeRows[pos].Depth < depth {
+			return pos
+		}
+	}
+	return -1
+}
+
+// treeRowText is the searchable text of a tree row
+func (v *StatusView) treeRowText(row StatusTreeRow) string {
+	if row.Node.Line >= 0 {
+		return v.Lines[row.Node.Line]
+	}
+	return row.Node.Path
+}
+
+// renderTreeRow draws one tree row: expander, flags, name and rolled-up counts
+func (v *StatusView) renderTreeRow(row StatusTreeRow, width int) string {
+	node := row.Node
+	marker := "  "
+	if node.IsDir() {
+		marker = "▸ "
+		if v.Expanded[node.Path] {
+			marker = "▾ "
+		}
+	}
+	flags := strings.Repeat(" ", 6)
+	if node.Line >= 0 {
+		flags = v.Changes[node.Line].Flags()
+	}
+	text := strings.Repeat("  ", row.Depth) + marker + flags + " " + node.Name
+
+	base := lipgloss.NewStyle()
+	if node.Line >= 0 {
+		if style, ok := changeStyles[v.Changes[node.Line].PrimaryKind()]; ok {
+			base = style
+		}
+	}
+	if !node.IsDir() {
+		return highlightMatches(padOrTruncate(text, width), v.Query, base)
+	}
+
+	if node.Line < 0 {
+		base = treeDirStyle
+	}
+	count := " " + treeCountText(node)
+	name := padOrTruncate(text+"/", max(0, width-lipgloss.Width(count)))
+	return highlightMatches(name, v.Query, base) + treeCountStyle.Render(count)
+}
+
+// treeCountText summarises the changes rolled up beneath a directory
+func treeCountText(node *StatusTreeNode) string {
+	var parts []string
+	for _, kind := range []ChangeKind{ChangeCreated, ChangeDeleted, ChangeContent, ChangeType} {
+		if n := node.Counts[kind]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s%d", kind.Flag(), n))
+		}
+	}
+	noun := "changes"
+	if node.Total == 1 {
+		noun = "change"
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("%d %s", node.Total, noun)
+	}
+	return fmt.Sprintf("%d %s (%s)", node.Total, noun, strings.Join(parts, " "))
+}
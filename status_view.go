@@ -0,0 +1,638 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	pagerCursorStyle = lipgloss.NewStyle().Background(lipgloss.Color("#1e293b"))
+	pagerMatchStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#0f172a")).Background(lipgloss.Color("#fde68a"))
+	pagerHelpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#94a3b8"))
+
+	// Change colours: created/deleted/type/content, then metadata-only changes
+	changeStyles = map[ChangeKind]lipgloss.Style{
+		ChangeCreated:     lipgloss.NewStyle().Foreground(lipgloss.Color("#10b981")),
+		ChangeDeleted:     lipgloss.NewStyle().Foreground(lipgloss.Color("#f87171")),
+		ChangeType:        lipgloss.NewStyle().Foreground(lipgloss.Color("#e879f9")),
+		ChangeContent:     lipgloss.NewStyle().Foreground(lipgloss.Color("#fbbf24")),
+		ChangePermissions: lipgloss.NewStyle().Foreground(lipgloss.Color("#7dd3fc")),
+		ChangeOwner:       lipgloss.NewStyle().Foreground(lipgloss.Color("#a5b4fc")),
+		ChangeXattr:       lipgloss.NewStyle().Foreground(lipgloss.Color("#94a3b8")),
+		ChangeACL:         lipgloss.NewStyle().Foreground(lipgloss.Color("#94a3b8")),
+	}
+)
+
+// StatusView is the full-screen pager holding the complete "snapper status"
+// output, parsed into FileChanges and filterable by change kind. In tree mode
+// the filtered changes are shown as a collapsible directory tree.
+type StatusView struct {
+	Snap      Snapshot
+	From, To  int
+	Lines     []string
+	Changes   []*FileChange // parsed Lines; nil where a line is not a status entry
+	Counts    map[ChangeKind]int
+	Filter    ChangeKind
+	Rows      []int // indexes into Lines shown under Filter
+	TreeMode  bool
+	Tree      *StatusTreeNode
+	TreeRows  []StatusTreeRow
+	Expanded  map[string]bool // directory paths open in the tree
+	Cursor    int             // position in Rows, or TreeRows in tree mode
+	Offset    int
+	Query     string
+	Matches   []int         // cursor positions whose row contains Query
+	Ticked    map[int]bool  // lines picked for undochange
+	Undone    map[int]error // undochange outcome per line, nil on success
+	InputMode string        // "", "search" or "save"
+	Input     textinput.Model
+}
+
+// StatusSavedMsg reports the outcome of writing status output to a file
+type StatusSavedMsg struct {
+	Path string
+	Err  error
+}
+
+// newStatusView splits raw status output into a pager, opening in tree mode
+// when tree is set
+func newStatusView(snap Snapshot, from, to int, output string, tree bool) *StatusView {
+	var lines []string
+	if strings.TrimSpace(output) != "" {
+		lines = strings.Split(strings.TrimRight(output, "\n"), "\n")
+	}
+	input := textinput.New()
+	input.CharLimit = 256
+	changes := parseStatusOutput(lines)
+	v := &StatusView{
+		Snap:     snap,
+		From:     from,
+		To:       to,
+		Lines:    lines,
+		Changes:  changes,
+		Counts:   countChangeKinds(changes),
+		TreeMode: tree,
+		Expanded: map[string]bool{},
+		Ticked:   map[int]bool{},
+		Undone:   map[int]error{},
+		Input:    input,
+	}
+	v.setFilter(ChangeAll)
+	return v
+}
+
+// setFilter recomputes the visible rows for a change kind, keeping the
+// cursor on the same line when it stays visible
+func (v *StatusView) setFilter(kind ChangeKind) {
+	current := -1
+	if !v.TreeMode && v.Cursor < len(v.Rows) {
+		current = v.Rows[v.Cursor]
+	}
+
+	v.Filter = kind
+	v.Rows = v.Rows[:0]
+	for i := range v.Lines {
+		if kind == ChangeAll || (v.Changes[i] != nil && v.Changes[i].Is(kind)) {
+			v.Rows = append(v.Rows, i)
+		}
+	}
+
+	if v.TreeMode {
+		v.rebuildTree()
+		v.search(v.Query)
+		return
+	}
+	v.Tree = nil
+	v.Cursor, v.Offset = 0, 0
+	for pos, idx := range v.Rows {
+		if idx >= current {
+			v.Cursor = pos
+			break
+		}
+	}
+	v.search(v.Query)
+}
+
+// cursorLines returns the status lines under the cursor: the line itself, or
+// in tree mode a node and every change beneath it
+func (v *StatusView) cursorLines() []int {
+	if !v.TreeMode {
+		if v.Cursor < len(v.Rows) && v.Changes[v.Rows[v.Cursor]] != nil {
+			return []int{v.Rows[v.Cursor]}
+		}
+		return nil
+	}
+	if v.Cursor < len(v.TreeRows) {
+		return v.TreeRows[v.Cursor].Node.lines()
+	}
+	return nil
+}
+
+// cursorPath is the file, or in the tree the directory, under the cursor
+func (v *StatusView) cursorPath() string {
+	if !v.TreeMode {
+		if lines := v.cursorLines(); len(lines) > 0 {
+			return v.Changes[lines[0]].Path
+		}
+		return ""
+	}
+	if v.Cursor < len(v.TreeRows) {
+		return v.TreeRows[v.Cursor].Node.Path
+	}
+	return ""
+}
+
+// toggleTick ticks the lines under the cursor, or unticks them if all are ticked
+func (v *StatusView) toggleTick() {
+	lines := v.cursorLines()
+	all := len(lines) > 0
+	for _, idx := range lines {
+		all = all && v.Ticked[idx]
+	}
+	for _, idx := range lines {
+		if all {
+			delete(v.Ticked, idx)
+		} else {
+			v.Ticked[idx] = true
+		}
+	}
+}
+
+// tickMark is the two-column gutter for a set of lines: a failed undo, ticked
+// (fully or partly) or undone
+func (v *StatusView) tickMark(lines []int) string {
+	ticked, failed, undone := 0, false, false
+	for _, idx := range lines {
+		if v.Ticked[idx] {
+			ticked++
+		}
+		if err, ok := v.Undone[idx]; ok {
+			failed = failed || err != nil
+			undone = undone || err == nil
+		}
+	}
+	switch {
+	case failed:
+		return changeStyles[ChangeDeleted].Render("✗ ")
+	case ticked > 0 && ticked == len(lines):
+		return "● "
+	case ticked > 0:
+		return "◐ "
+	case undone:
+		return changeStyles[ChangeCreated].Render("✓ ")
+	}
+	return "  "
+}
+
+// rowCount is the number of rows the cursor moves over in the current mode
+func (v *StatusView) rowCount() int {
+	if v.TreeMode {
+		return len(v.TreeRows)
+	}
+	return len(v.Rows)
+}
+
+// rowText is the searchable text of the row at a cursor position
+func (v *StatusView) rowText(pos int) string {
+	if v.TreeMode {
+		return v.treeRowText(v.TreeRows[pos])
+	}
+	return v.Lines[v.Rows[pos]]
+}
+
+// cycleFilter steps through changeKinds in the given direction
+func (v *StatusView) cycleFilter(dir int) {
+	for i, kind := range changeKinds {
+		if kind == v.Filter {
+			v.setFilter(changeKinds[(i+dir+len(changeKinds))%len(changeKinds)])
+			return
+		}
+	}
+	v.setFilter(ChangeAll)
+}
+
+// visibleLines returns the raw lines shown under the current filter
+func (v *StatusView) visibleLines() []string {
+	lines := make([]string, 0, len(v.Rows))
+	for _, idx := range v.Rows {
+		lines = append(lines, v.Lines[idx])
+	}
+	return lines
+}
+
+// pageHeight is the number of output lines that fit on screen
+func (m UIState) pageHeight() int {
+	height := m.TermHeight
+	if height == 0 {
+		height = 24
+	}
+	// App header, title, info line and footer
+	return max(1, height-4)
+}
+
+// handleStatusKey drives the status pager: scrolling, filtering, the tree,
+// search and save
+func (m UIState) handleStatusKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.StatusView
+	if v.InputMode != "" {
+		return m.handleStatusInput(msg)
+	}
+
+	page := m.pageHeight()
+	if v.TreeMode && m.handleTreeKey(msg.String(), page) {
+		return m, nil
+	}
+	switch msg.String() {
+	case "esc", "q":
+		m.Screen = ""
+		m.StatusView = nil
+		m.Status = "Closed status view"
+		return m, nil
+	case "j", "down":
+		v.moveCursor(1, page)
+	case "k", "up":
+		v.moveCursor(-1, page)
+	case "pgdn", "pagedown", " ":
+		v.moveCursor(page, page)
+	case "pgup", "pageup":
+		v.moveCursor(-page, page)
+	case "g", "home":
+		v.moveCursor(-v.rowCount(), page)
+	case "G", "end":
+		v.moveCursor(v.rowCount(), page)
+	case "enter":
+		return m.openDiff()
+	case "x":
+		v.toggleTick()
+		v.moveCursor(1, page)
+		m.Status = fmt.Sprintf("%d file(s) ticked; press u to undo their changes", len(v.Ticked))
+	case "X":
+		v.Ticked = map[int]bool{}
+		m.Status = "Cleared ticked files"
+	case "u":
+		return m, m.openUndoForm()
+	case "H":
+		if path := v.cursorPath(); path != "" {
+			return m.openHistory(v.Snap, path)
+		}
+	case "R":
+		path := v.cursorPath()
+		if path == "" {
+			return m, nil
+		}
+		from := v.Snap
+		from.Number = v.From
+		rel, err := snapshotRelPath(from, path)
+		if err != nil {
+			m.Status = fmt.Sprintf("Cannot restore: %v", err)
+			return m, nil
+		}
+		return m, m.openRestoreForm(from, rel)
+	case "t":
+		v.toggleTree(page)
+		m.StatusTree = v.TreeMode
+		m.Status = "Showing status as a list"
+		if v.TreeMode {
+			m.Status = "Showing status as a directory tree"
+		}
+	case "f", "F":
+		dir := 1
+		if msg.String() == "F" {
+			dir = -1
+		}
+		v.cycleFilter(dir)
+		v.moveCursor(0, page)
+		m.Status = fmt.Sprintf("Showing %s changes (%d)", v.Filter, len(v.Rows))
+	case "/":
+		v.InputMode = "search"
+		v.Input.Prompt = "/"
+		v.Input.SetValue(v.Query)
+		return m, v.Input.Focus()
+	case "n":
+		m.Status = v.jumpMatch(1, page)
+	case "N":
+		m.Status = v.jumpMatch(-1, page)
+	case "w":
+		v.InputMode = "save"
+		v.Input.Prompt = "Save to: "
+		name := fmt.Sprintf("snapper-status-%s-%d-%d.txt", v.Snap.Config, v.From, v.To)
+		if v.Filter != ChangeAll {
+			name = fmt.Sprintf("snapper-status-%s-%d-%d-%s.txt", v.Snap.Config, v.From, v.To, v.Filter)
+		}
+		// The working directory is not shown anywhere, so offer the full path
+		if abs, err := filepath.Abs(name); err == nil {
+			name = abs
+		}
+		v.Input.SetValue(name)
+		v.Input.CursorEnd()
+		return m, v.Input.Focus()
+	}
+	return m, nil
+}
+
+// handleTreeKey expands and collapses directories in tree mode and reports
+// whether it consumed the key
+func (m UIState) handleTreeKey(key string, page int) bool {
+	v := m.StatusView
+	if v.Cursor >= len(v.TreeRows) {
+		return false
+	}
+	node := v.TreeRows[v.Cursor].Node
+	switch key {
+	case "enter":
+		if !node.IsDir() {
+			// Files open their diff
+			return false
+		}
+		v.setExpanded(!v.Expanded[node.Path])
+	case "l", "right":
+		if node.IsDir() && v.Expanded[node.Path] {
+			v.moveCursor(1, page)
+			return true
+		}
+		v.setExpanded(true)
+	case "h", "left":
+		if node.IsDir() && v.Expanded[node.Path] {
+			v.setExpanded(false)
+		} else if parent := v.treeParent(); parent >= 0 {
+			v.moveCursor(parent-v.Cursor, page)
+			return true
+		}
+	case "E":
+		v.setAllExpanded(true)
+	case "C":
+		v.setAllExpanded(false)
+	default:
+		return false
+	}
+	v.moveCursor(0, page)
+	return true
+}
+
+// handleStatusInput edits the search or save prompt
+func (m UIState) handleStatusInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.StatusView
+	switch msg.String() {
+	case "esc":
+		v.InputMode = ""
+		v.Input.Blur()
+		return m, nil
+	case "enter":
+		mode := v.InputMode
+		value := strings.TrimSpace(v.Input.Value())
+		v.InputMode = ""
+		v.Input.Blur()
+		if mode == "save" {
+			if value == "" {
+				return m, nil
+			}
+			if abs, err := filepath.Abs(value); err == nil {
+				value = abs
+			}
+			m.Status = fmt.Sprintf("Saving status to %s...", value)
+			return m, saveStatusCmd(value, v.visibleLines())
+		}
+		v.search(value)
+		m.Status = v.jumpMatch(0, m.pageHeight())
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	v.Input, cmd = v.Input.Update(msg)
+	return m, cmd
+}
+
+// handleStatusMouse scrolls the pager with the wheel; clicking a row selects
+// it, and clicking a directory in tree mode expands or collapses it
+func (m UIState) handleStatusMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	v := m.StatusView
+	page := m.pageHeight()
+	switch msg.Type {
+	case tea.MouseWheelUp:
+		v.moveCursor(-3, page)
+	case tea.MouseWheelDown:
+		v.moveCursor(3, page)
+	case tea.MouseLeft:
+		// Rows start below the app header, title and info line
+		row := msg.Y - 3
+		pos := v.Offset + row
+		if row < 0 || row >= page || pos >= v.rowCount() {
+			return m, nil
+		}
+		v.moveCursor(pos-v.Cursor, page)
+		if v.TreeMode {
+			node := v.TreeRows[pos].Node
+			v.setExpanded(!v.Expanded[node.Path])
+			v.moveCursor(0, page)
+		}
+	}
+	return m, nil
+}
+
+// moveCursor moves the highlighted line and scrolls it into view
+func (v *StatusView) moveCursor(delta, page int) {
+	count := v.rowCount()
+	if count == 0 {
+		v.Cursor, v.Offset = 0, 0
+		return
+	}
+	v.Cursor = max(0, min(count-1, v.Cursor+delta))
+	if v.Cursor < v.Offset {
+		v.Offset = v.Cursor
+	}
+	if v.Cursor >= v.Offset+page {
+		v.Offset = v.Cursor - page + 1
+	}
+	v.Offset = max(0, min(v.Offset, count-page))
+}
+
+// search records every visible row containing query, case-insensitively
+func (v *StatusView) search(query string) {
+	v.Query = query
+	v.Matches = nil
+	if query == "" {
+		return
+	}
+	needle := strings.ToLower(query)
+	for pos := 0; pos < v.rowCount(); pos++ {
+		if strings.Contains(strings.ToLower(v.rowText(pos)), needle) {
+			v.Matches = append(v.Matches, pos)
+		}
+	}
+}
+
+// jumpMatch moves to the next (dir 1), previous (-1) or first-from-cursor (0)
+// match, wrapping around, and returns a status line
+func (v *StatusView) jumpMatch(dir, page int) string {
+	if v.Query == "" {
+		return "No search; press / to search"
+	}
+	if len(v.Matches) == 0 {
+		return fmt.Sprintf("Pattern not found: %s", v.Query)
+	}
+
+	target := -1
+	switch {
+	case dir >= 0:
+		start := v.Cursor
+		if dir > 0 {
+			start++
+		}
+		for _, idx := range v.Matches {
+			if idx >= start {
+				target = idx
+				break
+			}
+		}
+		if target < 0 {
+			target = v.Matches[0]
+		}
+	default:
+		for i := len(v.Matches) - 1; i >= 0; i-- {
+			if v.Matches[i] < v.Cursor {
+				target = v.Matches[i]
+				break
+			}
+		}
+		if target < 0 {
+			target = v.Matches[len(v.Matches)-1]
+		}
+	}
+
+	v.moveCursor(target-v.Cursor, page)
+	for i, idx := range v.Matches {
+		if idx == target {
+			return fmt.Sprintf("Match %d of %d for %q", i+1, len(v.Matches), v.Query)
+		}
+	}
+	return ""
+}
+
+// renderStatusView draws the pager over the whole screen
+func (m UIState) renderStatusView(width, height int) string {
+	v := m.StatusView
+	page := m.pageHeight()
+
+	title := detailHeaderStyle.Render(fmt.Sprintf("Status %s %d..%d", v.Snap.Config, v.From, v.To))
+	end := min(v.Offset+page, v.rowCount())
+	info := fmt.Sprintf("Filter: %s | %d of %d lines", v.Filter, len(v.Rows), len(v.Lines))
+	if v.TreeMode {
+		info = fmt.Sprintf("Tree | Filter: %s | %d changes in %d rows", v.Filter, v.Tree.Total, len(v.TreeRows))
+	}
+	if v.rowCount() > 0 {
+		info += fmt.Sprintf(" | showing %d-%d | row %d", v.Offset+1, end, v.Cursor+1)
+	}
+	if v.Query != "" {
+		info += fmt.Sprintf(" | /%s: %d matches", v.Query, len(v.Matches))
+	}
+	if len(v.Ticked) > 0 {
+		info += fmt.Sprintf(" | %d ticked", len(v.Ticked))
+	}
+	for _, idx := range v.cursorLines() {
+		if err := v.Undone[idx]; err != nil {
+			info += fmt.Sprintf(" | undo failed: %v", err)
+			break
+		}
+	}
+
+	var body []string
+	switch {
+	case len(v.Lines) == 0:
+		body = append(body, "No changes between these snapshots.")
+	case len(v.Rows) == 0:
+		body = append(body, fmt.Sprintf("No %s changes; press f to change the filter.", v.Filter))
+	}
+	for pos := v.Offset; pos < end; pos++ {
+		if v.TreeMode {
+			row := v.TreeRows[pos]
+			line := v.tickMark(row.Node.lines()) + v.renderTreeRow(row, width-2)
+			if pos == v.Cursor {
+				line = pagerCursorStyle.Render(line)
+			}
+			body = append(body, line)
+			continue
+		}
+		idx := v.Rows[pos]
+		base := lipgloss.NewStyle()
+		if change := v.Changes[idx]; change != nil {
+			if style, ok := changeStyles[change.PrimaryKind()]; ok {
+				base = style
+			}
+		}
+		line := v.tickMark([]int{idx}) + highlightMatches(padOrTruncate(v.Lines[idx], width-2), v.Query, base)
+		if pos == v.Cursor {
+			line = pagerCursorStyle.Render(line)
+		}
+		body = append(body, line)
+	}
+
+	footer := pagerHelpStyle.Render("↑↓/PgUp/PgDn/g/G: Scroll | enter: Diff | x: Tick | u: Undo | R: Restore | H: History | f/F: Filter | t: Tree | /: Search | n/N: Match | w: Save | esc/q: Back")
+	if v.TreeMode {
+		footer = pagerHelpStyle.Render("↑↓/g/G: Move | enter/click: Toggle or diff | →/←: Expand/Collapse | E/C: All | x: Tick | u: Undo | R: Restore | f/F: Filter | t: List | /: Search | esc/q: Back")
+	}
+	if v.InputMode != "" {
+		footer = v.Input.View()
+	}
+
+	ui := lipgloss.JoinVertical(lipgloss.Left,
+		headerStyle.Width(width).Render(m.headerTitle()),
+		title+"  "+summaryStyle.Render(changeCountsText(v.Counts)),
+		summaryStyle.Render(info),
+		lipgloss.NewStyle().Height(page).Render(strings.Join(body, "\n")),
+		footer,
+	)
+	return lipgloss.Place(width, height, lipgloss.Top, lipgloss.Left, ui)
+}
+
+// highlightMatches marks case-insensitive occurrences of query in line
+func highlightMatches(line, query string, base lipgloss.Style) string {
+	if query == "" {
+		return base.Render(line)
+	}
+	lower := strings.ToLower(line)
+	needle := strings.ToLower(query)
+	var b strings.Builder
+	for {
+		idx := strings.Index(lower, needle)
+		if idx < 0 || len(lower) != len(line) {
+			b.WriteString(base.Render(line))
+			return b.String()
+		}
+		b.WriteString(base.Render(line[:idx]))
+		b.WriteString(pagerMatchStyle.Render(line[idx : idx+len(needle)]))
+		line = line[idx+len(needle):]
+		lower = lower[idx+len(needle):]
+	}
+}
+
+// saveStatusCmd writes the status lines to a new file at path; the pager
+// passes the rows it shows, so a filtered view saves only the filtered
+// changes. An existing file is never overwritten.
+func saveStatusCmd(path string, lines []string) tea.Cmd {
+	return func() tea.Msg {
+		content := strings.Join(lines, "\n")
+		if len(lines) > 0 {
+			content += "\n"
+		}
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			return StatusSavedMsg{Path: path, Err: fmt.Errorf("%s already exists; choose another name", path)}
+		}
+		if err != nil {
+			return StatusSavedMsg{Path: path, Err: err}
+		}
+		_, err = file.WriteString(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return StatusSavedMsg{Path: path, Err: err}
+	}
+}
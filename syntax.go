@@ -0,0 +1,238 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// syntaxClass is what a rune of source text belongs to
+type syntaxClass byte
+
+const (
+	synPlain syntaxClass = iota
+	synKeyword
+	synString
+	synComment
+	synNumber
+)
+
+// syntaxLang holds what the highlighter needs to know about a language
+type syntaxLang struct {
+	Name         string
+	Keywords     map[string]bool
+	LineComments []string  // markers that comment out the rest of the line
+	BlockComment [2]string // start and end of comments that may span lines
+	Quotes       string    // characters that open and close strings
+	Keys         bool      // a leading "name =" or "name:" is highlighted as a key
+}
+
+// keywordSet builds a keyword lookup from a space-separated list
+func keywordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+var (
+	langGo = &syntaxLang{Name: "go", LineComments: []string{"//"}, BlockComment: [2]string{"/*", "*/"}, Quotes: "\"'`",
+		Keywords: keywordSet("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false")}
+	langC = &syntaxLang{Name: "c", LineComments: []string{"//"}, BlockComment: [2]string{"/*", "*/"}, Quotes: `"'`,
+		Keywords: keywordSet("auto break case char const continue default do double else enum extern float for goto if inline int long register return short signed sizeof static struct switch typedef union unsigned void volatile while bool class namespace public private protected template typename virtual nullptr true false #include #define #if #ifdef #ifndef #endif #else")}
+	langPython = &syntaxLang{Name: "python", LineComments: []string{"#"}, Quotes: `"'`,
+		Keywords: keywordSet("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False")}
+	langShell = &syntaxLang{Name: "shell", LineComments: []string{"#"}, Quotes: `"'`,
+		Keywords: keywordSet("if then else elif fi for in do done while until case esac function return export local readonly set unset source alias exit")}
+	langRust = &syntaxLang{Name: "rust", LineComments: []string{"//"}, BlockComment: [2]string{"/*", "*/"}, Quotes: `"`,
+		Keywords: keywordSet("as break const continue crate else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while async await dyn")}
+	langJS = &syntaxLang{Name: "javascript", LineComments: []string{"//"}, BlockComment: [2]string{"/*", "*/"}, Quotes: "\"'`",
+		Keywords: keywordSet("break case catch class const continue default delete do else export extends finally for function if import in instanceof let new return super switch this throw try typeof var void while yield async await null undefined true false interface type")}
+	langJSON = &syntaxLang{Name: "json", Quotes: `"`, Keywords: keywordSet("true false null")}
+	langYAML = &syntaxLang{Name: "yaml", LineComments: []string{"#"}, Quotes: `"'`, Keys: true, Keywords: keywordSet("true false null yes no")}
+	langConf = &syntaxLang{Name: "config", LineComments: []string{"#", ";"}, Quotes: `"'`, Keys: true}
+)
+
+// syntaxByExt picks a language by file extension
+var syntaxByExt = map[string]*syntaxLang{
+	".go": langGo,
+	".c":  langC, ".h": langC, ".cc": langC, ".cpp": langC, ".hpp": langC,
+	".py": langPython,
+	".sh": langShell, ".bash": langShell, ".zsh": langShell,
+	".rs": langRust,
+	".js": langJS, ".mjs": langJS, ".ts": langJS,
+	".json": langJSON,
+	".yaml": langYAML, ".yml": langYAML,
+	".conf": langConf, ".cfg": langConf, ".ini": langConf, ".toml": langConf, ".service": langConf, ".timer": langConf, ".repo": langConf,
+}
+
+// syntaxByName picks a language for well-known files without a telling extension
+var syntaxByName = map[string]*syntaxLang{
+	".bashrc": langShell, ".bash_profile": langShell, ".profile": langShell, ".zshrc": langShell,
+	"fstab": langConf, "hosts": langConf, "crontab": langConf, "sudoers": langConf, "environment": langConf,
+}
+
+// syntaxForPath guesses the language of a file from its name; other files
+// under /etc are read as config files. It returns nil when nothing fits.
+func syntaxForPath(path string) *syntaxLang {
+	base := filepath.Base(path)
+	if lang := syntaxByName[base]; lang != nil {
+		return lang
+	}
+	ext := filepath.Ext(base)
+	if lang := syntaxByExt[strings.ToLower(ext)]; lang != nil {
+		return lang
+	}
+	if ext == "" && strings.HasPrefix(path, "/etc/") {
+		return langConf
+	}
+	return nil
+}
+
+// classify marks each rune of a line; inBlock says whether the line starts
+// inside a block comment, and the result whether the next one does
+func (l *syntaxLang) classify(text string, inBlock bool) ([]syntaxClass, bool) {
+	runes := []rune(text)
+	classes := make([]syntaxClass, len(runes))
+	mark := func(from, to int, class syntaxClass) {
+		for k := from; k < to; k++ {
+			classes[k] = class
+		}
+	}
+
+	keyStart, keyEnd := -1, -1
+	if l.Keys {
+		keyStart, keyEnd = leadingKey(runes)
+	}
+	for i := 0; i < len(runes); {
+		open := l.BlockComment[0]
+		switch {
+		case inBlock || (open != "" && runeIndex(runes, i, open) == i):
+			from := i
+			if !inBlock {
+				from += len([]rune(open))
+			}
+			end := runeIndex(runes, from, l.BlockComment[1])
+			if end < 0 {
+				mark(i, len(runes), synComment)
+				return classes, true
+			}
+			j := end + len([]rune(l.BlockComment[1]))
+			mark(i, j, synComment)
+			i = j
+			inBlock = false
+		case l.lineComment(runes, i):
+			mark(i, len(runes), synComment)
+			return classes, false
+		case strings.ContainsRune(l.Quotes, runes[i]):
+			j := i + 1
+			for j < len(runes) && runes[j] != runes[i] {
+				if runes[j] == '\\' && runes[i] != '`' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(runes))
+			mark(i, j, synString)
+			i = j
+		case i == keyStart:
+			mark(i, keyEnd, synKeyword)
+			i = keyEnd
+		case isWordRune(runes[i]) || runes[i] == '#':
+			j := i + 1
+			for j < len(runes) && (isWordRune(runes[j]) || (runes[j] == '.' && unicode.IsDigit(runes[i]))) {
+				j++
+			}
+			if unicode.IsDigit(runes[i]) {
+				mark(i, j, synNumber)
+			} else if l.Keywords[string(runes[i:j])] {
+				mark(i, j, synKeyword)
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return classes, inBlock
+}
+
+// runeIndex returns the rune index of the first marker at or after from, or -1
+func runeIndex(runes []rune, from int, marker string) int {
+	m := []rune(marker)
+	for i := from; i+len(m) <= len(runes); i++ {
+		if string(runes[i:i+len(m)]) == marker {
+			return i
+		}
+	}
+	return -1
+}
+
+// lineComment reports whether a line comment starts at rune i. "#" only
+// starts one at the start of the line or after a space, so that "$#" and
+// "a#b" in shell and config files are not comments.
+func (l *syntaxLang) lineComment(runes []rune, i int) bool {
+	for _, marker := range l.LineComments {
+		if !strings.HasPrefix(string(runes[i:]), marker) {
+			continue
+		}
+		if (marker == "#" || marker == ";") && i > 0 && !unicode.IsSpace(runes[i-1]) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// leadingKey returns where the key of a "key = value" or "key: value" line
+// starts and ends, or -1, -1 when the line does not start with one
+func leadingKey(runes []rune) (int, int) {
+	i := 0
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	if i < len(runes) && runes[i] == '-' {
+		// YAML list item
+		i++
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+	}
+	start := i
+	for i < len(runes) && (isWordRune(runes[i]) || strings.ContainsRune(".-/", runes[i])) {
+		i++
+	}
+	end := i
+	for i < len(runes) && (runes[i] == ' ' || runes[i] == '\t') {
+		i++
+	}
+	if end == start || i >= len(runes) || (runes[i] != '=' && runes[i] != ':') {
+		return -1, -1
+	}
+	return start, end
+}
+
+// highlightDiff classifies the text of every diff line. Block comments are
+// followed separately through the old and the new file, and every hunk
+// starts outside one.
+func highlightDiff(lang *syntaxLang, lines []DiffLine) [][]syntaxClass {
+	if lang == nil {
+		return nil
+	}
+	classes := make([][]syntaxClass, len(lines))
+	oldBlock, newBlock := false, false
+	for i, line := range lines {
+		switch line.Kind {
+		case ' ':
+			classes[i], newBlock = lang.classify(line.Text, newBlock)
+			oldBlock = newBlock
+		case '-':
+			classes[i], oldBlock = lang.classify(line.Text, oldBlock)
+		case '+':
+			classes[i], newBlock = lang.classify(line.Text, newBlock)
+		default:
+			oldBlock, newBlock = false, false
+		}
+	}
+	return classes
+}
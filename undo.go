@@ -0,0 +1,300 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// maxUndoListed is how many files the undo confirmation shows at once, and
+// how many per section the restore summary lists
+const maxUndoListed = 12
+
+// undoFormInput holds the files ticked for "snapper undochange" and the answer
+// of its confirmation
+type undoFormInput struct {
+	Snap      Snapshot
+	From, To  int
+	Changes   []*FileChange
+	Expected  string // text to type; "" for a yes/no prompt
+	Typed     string
+	Confirmed bool
+	Browsed   string // row of the file list the cursor is on
+}
+
+// undoEffect is what undoing a change does to the live file: a file created
+// in the range is deleted, a deleted one is created again, anything else is
+// modified back
+func undoEffect(change *FileChange) string {
+	switch {
+	case change.Created:
+		return "delete"
+	case change.Deleted:
+		return "create"
+	}
+	return "modify"
+}
+
+// groupByEffect sorts the changes into the files undo will create, modify and
+// delete
+func groupByEffect(changes []*FileChange) map[string][]string {
+	groups := map[string][]string{}
+	for _, change := range changes {
+		effect := undoEffect(change)
+		groups[effect] = append(groups[effect], change.Path)
+	}
+	for _, paths := range groups {
+		sort.Strings(paths)
+	}
+	return groups
+}
+
+// describe counts the files by effect for the confirmation dialog
+func (in *undoFormInput) describe() string {
+	groups := groupByEffect(in.Changes)
+	var parts []string
+	for _, effect := range []string{"create", "modify", "delete"} {
+		if n := len(groups[effect]); n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", effect, n))
+		}
+	}
+	return fmt.Sprintf("Will %s file(s)", strings.Join(parts, ", "))
+}
+
+// fileList shows every file the undo touches, grouped by effect, in a list
+// that scrolls once it is longer than maxUndoListed rows. Nothing is picked
+// from it; enter moves on to the confirmation.
+func (in *undoFormInput) fileList(title string) *huh.Select[string] {
+	groups := groupByEffect(in.Changes)
+	var options []huh.Option[string]
+	for _, effect := range []string{"create", "modify", "delete"} {
+		for _, path := range groups[effect] {
+			options = append(options, huh.NewOption(fmt.Sprintf("%-6s %s", effect, path), path))
+		}
+	}
+	description := in.describe()
+	if len(options) > maxUndoListed {
+		description += " · ↑↓ to scroll"
+	}
+	return huh.NewSelect[string]().
+		Title(title).
+		Description(description).
+		Options(options...).
+		Height(min(len(options), maxUndoListed) + 2).
+		Value(&in.Browsed)
+}
+
+// confirmed reports whether the dialog was answered with yes or the right text
+func (in *undoFormInput) confirmed() bool {
+	if in.Expected != "" {
+		return strings.TrimSpace(in.Typed) == in.Expected
+	}
+	return in.Confirmed
+}
+
+// openUndoForm asks to confirm undoing the files ticked in the status viewer
+func (m *UIState) openUndoForm() tea.Cmd {
+	v := m.StatusView
+	input := &undoFormInput{Snap: v.Snap, From: v.From, To: v.To}
+	for idx := range v.Lines {
+		if v.Ticked[idx] && v.Changes[idx] != nil {
+			input.Changes = append(input.Changes, v.Changes[idx])
+		}
+	}
+	if len(input.Changes) == 0 {
+		m.Status = "Tick files with x first"
+		return nil
+	}
+
+	if m.ConfirmRules.mode(ActionUndoChange) == confirmNone {
+		m.ActionInProgress = true
+		m.Status = fmt.Sprintf("Undoing changes to %d file(s)...", len(input.Changes))
+		return undoChangeCmd(m.Client, input)
+	}
+
+	title := fmt.Sprintf("Undo %d change(s) of %s %d..%d?", len(input.Changes), v.Snap.Config, v.From, v.To)
+	input.Expected = m.ConfirmRules.expectedText(ActionUndoChange, v.From, len(input.Changes))
+	fields := []huh.Field{input.fileList(title)}
+	if input.Expected != "" {
+		what := "the snapshot number the files are undone from"
+		fields = append(fields, typedConfirmation(input.Expected, what, &input.Typed))
+	} else {
+		fields = append(fields, huh.NewConfirm().
+			Title("Undo these changes?").
+			Affirmative("Undo changes").
+			Negative("Cancel").
+			Value(&input.Confirmed))
+	}
+
+	m.UndoInput = input
+	m.FormKind = "undochange"
+	m.Form = huh.NewForm(huh.NewGroup(fields...)).WithShowHelp(true).WithWidth(70)
+
+	m.Status = "Undo changes: confirm to revert the ticked files, esc to cancel"
+	return m.Form.Init()
+}
+
+// undoChangeCmd undoes every ticked file with a single "snapper undochange"
+// and reads each file's outcome from snapper's output. If the call fails
+// without naming the files it failed on, each file is undone on its own so
+// it reports its own outcome.
+func undoChangeCmd(client SnapperClient, input *undoFormInput) tea.Cmd {
+	return func() tea.Msg {
+		changes := undoOrder(input.Changes)
+		paths := make([]string, len(changes))
+		for i, change := range changes {
+			paths[i] = change.Path
+		}
+
+		msg := ActionResultMsg{Kind: ActionUndoChange, Snap: input.Snap}
+		output, err := client.UndoChange(input.Snap.Config, input.From, input.To, paths)
+		files, ok := undoResults(changes, output, err)
+		if !ok {
+			files = undoEachFile(client, input, changes)
+		}
+		msg.Files = files
+
+		failed := 0
+		for _, file := range files {
+			if file.Err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			msg.Err = fmt.Errorf("%d of %d file(s) failed", failed, len(files))
+		}
+		msg.Output = undoSummary(files)
+		return msg
+	}
+}
+
+// undoOrder puts the files undo deletes first, deepest first, so directories
+// are empty by the time they are removed; the rest go parents first
+func undoOrder(changes []*FileChange) []*FileChange {
+	var deletes, others []*FileChange
+	for _, change := range changes {
+		if undoEffect(change) == "delete" {
+			deletes = append(deletes, change)
+		} else {
+			others = append(others, change)
+		}
+	}
+	sort.Slice(deletes, func(i, j int) bool { return deletes[i].Path > deletes[j].Path })
+	sort.Slice(others, func(i, j int) bool { return others[i].Path < others[j].Path })
+	return append(deletes, others...)
+}
+
+// undoLine matches the per-file lines of "snapper undochange": "modifying
+// /etc/fstab" in verbose mode, and "failed to modify /etc/fstab" on error
+var undoLine = regexp.MustCompile(`^(failed to )?(create|modify|delete|creating|modifying|deleting) (/.*)$`)
+
+// parseUndoOutput maps each file snapper reports on to its line, and says
+// which of them failed
+func parseUndoOutput(output string) (lines map[string]string, failed map[string]bool) {
+	lines, failed = map[string]string{}, map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		match := undoLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		lines[match[3]] = line
+		if match[1] != "" {
+			failed[match[3]] = true
+		}
+	}
+	return lines, failed
+}
+
+// undoResults reads the outcome of each file from a combined undochange.
+// Files snapper names as failed get an error; when the call failed as a
+// whole but named no file, the outcomes are unknown and ok is false.
+func undoResults(changes []*FileChange, output string, err error) (files []FileResult, ok bool) {
+	lines, failed := parseUndoOutput(output)
+	if err != nil && len(failed) == 0 {
+		return nil, false
+	}
+	for _, change := range changes {
+		file := FileResult{Path: change.Path, Effect: undoEffect(change), Output: lines[change.Path]}
+		if failed[change.Path] {
+			file.Err = errors.New(lines[change.Path])
+		}
+		files = append(files, file)
+	}
+	return files, true
+}
+
+// undoEachFile runs one "snapper undochange" per file, in order
+func undoEachFile(client SnapperClient, input *undoFormInput, changes []*FileChange) []FileResult {
+	var files []FileResult
+	for _, change := range changes {
+		output, err := client.UndoChange(input.Snap.Config, input.From, input.To, []string{change.Path})
+		files = append(files, FileResult{
+			Path:   change.Path,
+			Effect: undoEffect(change),
+			Output: output,
+			Err:    err,
+		})
+	}
+	return files
+}
+
+// undoSummary lists the outcome of every file, failures first
+func undoSummary(files []FileResult) string {
+	var failed, done []string
+	for _, file := range files {
+		if file.Err != nil {
+			failed = append(failed, fmt.Sprintf("✗ %s %s: %s", file.Effect, file.Path, nonEmpty(firstLine(file.Output), file.Err.Error())))
+		} else {
+			done = append(done, fmt.Sprintf("✓ %s %s", file.Effect, file.Path))
+		}
+	}
+	return strings.Join(append(failed, done...), "\n")
+}
+
+// handleUndoResult marks each file's outcome in the status viewer
+func (m UIState) handleUndoResult(msg ActionResult) (tea.Model, tea.Cmd) {
+	failed := 0
+	for _, file := range msg.Files {
+		if file.Err != nil {
+			failed++
+		}
+	}
+	m.ActionMessage = msg.Output
+	if failed > 0 {
+		m.Status = fmt.Sprintf("Undo: %d of %d file(s) failed", failed, len(msg.Files))
+	} else {
+		m.Status = fmt.Sprintf("Undid changes to %d file(s)", len(msg.Files))
+	}
+
+	v := m.StatusView
+	if v == nil || v.Snap.Key() != msg.Snap.Key() {
+		return m, nil
+	}
+	results := map[string]FileResult{}
+	for _, file := range msg.Files {
+		results[file.Path] = file
+	}
+	for idx, change := range v.Changes {
+		if change == nil {
+			continue
+		}
+		file, ok := results[change.Path]
+		if !ok {
+			continue
+		}
+		if file.Err != nil {
+			// Keep failed files ticked so they can be retried
+			v.Undone[idx] = errors.New(nonEmpty(firstLine(file.Output), file.Err.Error()))
+			continue
+		}
+		v.Undone[idx] = nil
+		delete(v.Ticked, idx)
+	}
+	return m, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseUndoOutput(t *testing.T) {
+	output := `create:1 modify:2 delete:1
+creating /etc/old.conf
+modifying /etc/fstab
+failed to modify /etc/shadow
+deleting /etc/new dir/file
+some unrelated warning`
+	lines, failed := parseUndoOutput(output)
+	want := map[string]string{
+		"/etc/old.conf":     "creating /etc/old.conf",
+		"/etc/fstab":        "modifying /etc/fstab",
+		"/etc/shadow":       "failed to modify /etc/shadow",
+		"/etc/new dir/file": "deleting /etc/new dir/file",
+	}
+	if fmt.Sprint(lines) != fmt.Sprint(want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	if len(failed) != 1 || !failed["/etc/shadow"] {
+		t.Errorf("failed = %v", failed)
+	}
+}
+
+// undoInput ticks the given status lines of root 3..0
+func undoInput(lines ...string) *undoFormInput {
+	input := &undoFormInput{Snap: Snapshot{Config: "root", Number: 3}, From: 3, To: 0}
+	for _, line := range lines {
+		change, _ := parseStatusLine(line)
+		input.Changes = append(input.Changes, &change)
+	}
+	return input
+}
+
+func TestUndoChangeRunsOnce(t *testing.T) {
+	fake := newFakeClient(nil)
+	input := undoInput("c..... /etc/fstab", "+..... /etc/new", "+..... /etc/new/file", "-..... /etc/old")
+	msg := undoChangeCmd(fake, input)().(ActionResultMsg)
+	if msg.Err != nil {
+		t.Fatal(msg.Err)
+	}
+	// Deletes first, deepest first, then the rest parents first
+	want := []string{"-c root undochange 3..0 /etc/new/file /etc/new /etc/fstab /etc/old"}
+	if fmt.Sprint(fake.Calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", fake.Calls, want)
+	}
+	for _, file := range msg.Files {
+		if file.Err != nil || file.Output != "modifying "+file.Path {
+			t.Errorf("%s: output %q, err %v", file.Path, file.Output, file.Err)
+		}
+	}
+}
+
+func TestUndoChangeReadsFailuresPerFile(t *testing.T) {
+	fake := newFakeClient(nil)
+	fake.Failures["undochange:/etc/shadow"] = errors.New("permission denied")
+	input := undoInput("c..... /etc/fstab", "cp.... /etc/shadow")
+	msg := undoChangeCmd(fake, input)().(ActionResultMsg)
+	if len(fake.Calls) != 1 {
+		t.Errorf("calls = %q, want one", fake.Calls)
+	}
+	if msg.Err == nil || msg.Err.Error() != "1 of 2 file(s) failed" {
+		t.Errorf("err = %v", msg.Err)
+	}
+	results := map[string]error{}
+	for _, file := range msg.Files {
+		results[file.Path] = file.Err
+	}
+	if results["/etc/fstab"] != nil {
+		t.Errorf("/etc/fstab failed: %v", results["/etc/fstab"])
+	}
+	if err := results["/etc/shadow"]; err == nil || err.Error() != "failed to modify /etc/shadow" {
+		t.Errorf("/etc/shadow: %v", err)
+	}
+}
+
+func TestUndoChangeFallsBackToEachFile(t *testing.T) {
+	fake := newFakeClient(nil)
+	fake.Failures["undochange"] = errors.New("snapper is locked")
+	input := undoInput("c..... /etc/fstab", "c..... /etc/hosts")
+	msg := undoChangeCmd(fake, input)().(ActionResultMsg)
+	want := []string{
+		"-c root undochange 3..0 /etc/fstab /etc/hosts",
+		"-c root undochange 3..0 /etc/fstab",
+		"-c root undochange 3..0 /etc/hosts",
+	}
+	if fmt.Sprint(fake.Calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", fake.Calls, want)
+	}
+	if msg.Err == nil || msg.Err.Error() != "2 of 2 file(s) failed" {
+		t.Errorf("err = %v", msg.Err)
+	}
+	for _, file := range msg.Files {
+		if file.Err == nil || file.Output != "snapper is locked" {
+			t.Errorf("%s: output %q, err %v", file.Path, file.Output, file.Err)
+		}
+	}
+}
@@ -39,11 +39,57 @@ func flattenUserData(data map[string]string) string {
 	return strings.Join(pairs, " ")
 }
 
+// formatUserdata renders userdata in snapper's "key=value,key2=value2" argument form
+func formatUserdata(data map[string]string) string {
+	pairs := make([]string, 0, len(data))
+	for key, val := range data {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, val))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// formatConfigValues renders config settings as sorted KEY=VALUE arguments
+func formatConfigValues(values map[string]string) []string {
+	pairs := make([]string, 0, len(values))
+	for key, val := range values {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, val))
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// formatUserdataInput renders userdata the way the dialogs expect it typed
+func formatUserdataInput(data map[string]string) string {
+	return strings.ReplaceAll(formatUserdata(data), ",", ", ")
+}
+
+// parseUserdata parses "key=value, key2=value2" as typed into a form
+func parseUserdata(text string) (map[string]string, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	result := map[string]string{}
+	for _, pair := range strings.Split(text, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("userdata %q is not key=value", pair)
+		}
+		result[key] = strings.TrimSpace(val)
+	}
+	return result, nil
+}
+
 // buildSummary creates the summary line
 func buildSummary(snaps []Snapshot) string {
 	total := int64(0)
 	for _, snap := range snaps {
-		total += int64Value(snap.UsedSpace)
+		total += int64Value(snapshotBytes(snap))
 	}
 	free, err := freeSpaceForPath(rootPath)
 	usedText := humanReadableBytes(ptrInt64(total))
@@ -54,6 +100,12 @@ func buildSummary(snaps []Snapshot) string {
 	return fmt.Sprintf("Snapshots: %d | Total used: %s | Free on %s: %s", len(snaps), usedText, rootPath, freeText)
 }
 
+// firstLine returns the first line of command output
+func firstLine(output string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	return line
+}
+
 // padOrTruncate pads or truncates a string to a specific width
 func padOrTruncate(value string, width int) string {
 	if width <= 0 {
@@ -138,6 +190,42 @@ func computeStatusStart(snap Snapshot) int {
 	return 0
 }
 
+// groupByConfig splits snapshots into per-config groups, keeping first-seen config order
+func groupByConfig(snaps []Snapshot) [][]Snapshot {
+	var order []string
+	groups := map[string][]Snapshot{}
+	for _, snap := range snaps {
+		if _, ok := groups[snap.Config]; !ok {
+			order = append(order, snap.Config)
+		}
+		groups[snap.Config] = append(groups[snap.Config], snap)
+	}
+	result := make([][]Snapshot, 0, len(order))
+	for _, config := range order {
+		result = append(result, groups[config])
+	}
+	return result
+}
+
+// findSnapshot looks up a snapshot by config and number
+func findSnapshot(snaps []Snapshot, config string, number int) (Snapshot, error) {
+	for _, snap := range snaps {
+		if snap.Config == config && snap.Number == number {
+			return snap, nil
+		}
+	}
+	return Snapshot{}, fmt.Errorf("snapshot %s:%d not found", config, number)
+}
+
+// joinNumbers renders snapshot numbers as a comma-separated list
+func joinNumbers(numbers []int) string {
+	parts := make([]string, 0, len(numbers))
+	for _, n := range numbers {
+		parts = append(parts, fmt.Sprint(n))
+	}
+	return strings.Join(parts, ", ")
+}
+
 // valueForSort gets the value to use for sorting
 func valueForSort(s Snapshot, key string) string {
 	switch key {
@@ -148,7 +236,7 @@ func valueForSort(s Snapshot, key string) string {
 	case "post_number":
 		return fmt.Sprintf("%08d", intValue(s.PostNumber))
 	case "used_space":
-		return fmt.Sprintf("%016d", int64Value(s.UsedSpace))
+		return fmt.Sprintf("%016d", int64Value(snapshotBytes(s)))
 	case "userdata":
 		return flattenUserData(s.Userdata)
 	case "snapshot_type":
@@ -166,15 +254,23 @@ func valueForSort(s Snapshot, key string) string {
 	}
 }
 
-// getActionPreview returns the command preview for a snapshot
-func getActionPreview(snap Snapshot) string {
-	if snap.Number < 0 {
-		return "Select a snapshot to preview the snapper commands."
+// getActionPreview returns what Apply, Delete and Status run on the
+// targets, as described by the backend
+func getActionPreview(client SnapperClient, targets []Snapshot) []string {
+	apply := "Apply: select only one snapshot"
+	status := "Status: select only one snapshot"
+	if len(targets) == 1 {
+		snap := targets[0]
+		apply = "Apply: " + client.Describe(Operation{Kind: "rollback", Config: snap.Config, Numbers: []int{snap.Number}}).String()
+		status = "Status: " + client.Describe(Operation{Kind: "status", Config: snap.Config, Numbers: []int{computeStatusStart(snap), snap.Number}}).String()
+	}
+	var deletes []string
+	for _, group := range groupByConfig(targets) {
+		var numbers []int
+		for _, snap := range group {
+			numbers = append(numbers, snap.Number)
+		}
+		deletes = append(deletes, client.Describe(Operation{Kind: "delete", Config: group[0].Config, Numbers: numbers}).String())
 	}
-	start := computeStatusStart(snap)
-	return strings.Join([]string{
-		fmt.Sprintf("Restore: sudo snapper rollback %d", snap.Number),
-		fmt.Sprintf("Delete: sudo snapper delete %d", snap.Number),
-		fmt.Sprintf("Status: sudo snapper status %d..%d", start, snap.Number),
-	}, "\n")
+	return []string{apply, "Delete: " + strings.Join(deletes, "; "), status}
 }
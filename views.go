@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// systemViewsPath holds views shared by every user; it is never written
+const systemViewsPath = "/etc/snapper-tui/views.json"
+
+// Picker values that are not the name of a view
+const (
+	viewDefault = "\x00default"
+	viewSave    = "\x00save"
+	viewDelete  = "\x00delete"
+)
+
+// SavedView is a named combination of filter, sort order and visible columns
+type SavedView struct {
+	Name        string   `json:"name"`
+	Filter      string   `json:"filter,omitempty"`
+	SortKey     string   `json:"sort,omitempty"`    // a column key; empty keeps the default order
+	SortReverse bool     `json:"reverse,omitempty"` // sort descending
+	Columns     []string `json:"columns,omitempty"` // column keys in order; empty shows all
+	System      bool     `json:"-"`                 // read from the system file
+}
+
+// viewsFile is the layout of a views file
+type viewsFile struct {
+	Views []SavedView `json:"views"`
+}
+
+// ViewSet holds the system views and the user's own; a user view replaces a
+// system view of the same name
+type ViewSet struct {
+	System   []SavedView
+	User     []SavedView
+	UserPath string // where the user's views are saved, "" when there is nowhere
+}
+
+// ViewsSavedMsg reports writing the user's views file
+type ViewsSavedMsg struct {
+	Path string
+	Err  error
+}
+
+// viewFormInput holds the values bound to the view picker
+type viewFormInput struct {
+	Choice  string
+	Name    string
+	Columns []string
+}
+
+// defaultViewsPath is the per-user views file under $XDG_CONFIG_HOME
+func defaultViewsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "snapper-tui", "views.json")
+}
+
+// loadViewSet reads the system and user views files; missing files hold no
+// views
+func loadViewSet(systemPath, userPath string) (*ViewSet, error) {
+	system, err := readViewsFile(systemPath)
+	if err != nil {
+		return nil, err
+	}
+	for i := range system {
+		system[i].System = true
+	}
+	user, err := readViewsFile(userPath)
+	if err != nil {
+		return nil, err
+	}
+	return &ViewSet{System: system, User: user, UserPath: userPath}, nil
+}
+
+// readViewsFile reads and checks the views of one file
+func readViewsFile(path string) ([]SavedView, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file viewsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	seen := map[string]bool{}
+	for _, view := range file.Views {
+		if err := view.check(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if seen[view.Name] {
+			return nil, fmt.Errorf("%s: view %q is defined twice", path, view.Name)
+		}
+		seen[view.Name] = true
+	}
+	return file.Views, nil
+}
+
+// writeViewsFile replaces a views file, creating its directory
+func writeViewsFile(path string, views []SavedView) error {
+	if path == "" {
+		return fmt.Errorf("no views file; pass --views")
+	}
+	data, err := json.MarshalIndent(viewsFile{Views: views}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// saveViewsCmd writes the user's views in the background
+func saveViewsCmd(path string, views []SavedView) tea.Cmd {
+	views = slices.Clone(views)
+	return func() tea.Msg {
+		return ViewsSavedMsg{Path: path, Err: writeViewsFile(path, views)}
+	}
+}
+
+// check reports a view that names a missing column or has a broken filter
+func (v SavedView) check() error {
+	if strings.TrimSpace(v.Name) == "" {
+		return fmt.Errorf("a view has no name")
+	}
+	if _, err := parseFilter(v.Filter); err != nil {
+		return fmt.Errorf("view %q: filter: %w", v.Name, err)
+	}
+	if v.SortKey != "" && findColumn(v.SortKey) == nil {
+		return fmt.Errorf("view %q: unknown sort column %q; use %s", v.Name, v.SortKey, columnKeys())
+	}
+	for _, key := range v.Columns {
+		if findColumn(key) == nil {
+			return fmt.Errorf("view %q: unknown column %q; use %s", v.Name, key, columnKeys())
+		}
+	}
+	return nil
+}
+
+// describe sums up a view for the picker
+func (v SavedView) describe() string {
+	parts := []string{nonEmpty(v.Filter, "all snapshots")}
+	if v.SortKey != "" {
+		order := "↑"
+		if v.SortReverse {
+			order = "↓"
+		}
+		parts = append(parts, "sort "+v.SortKey+" "+order)
+	}
+	if len(v.Columns) > 0 {
+		parts = append(parts, fmt.Sprintf("%d columns", len(v.Columns)))
+	}
+	if v.System {
+		parts = append(parts, "system")
+	}
+	return v.Name + " — " + strings.Join(parts, " · ")
+}
+
+// findColumn returns the column with the given key, or nil
+func findColumn(key string) *ColumnSpec {
+	for i := range columnSpecs {
+		if columnSpecs[i].Key == key {
+			return &columnSpecs[i]
+		}
+	}
+	return nil
+}
+
+// columnKeys lists every column key, for error messages
+func columnKeys() string {
+	var keys []string
+	for _, spec := range columnSpecs {
+		keys = append(keys, spec.Key)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// viewColumns returns the columns of a view; no columns means all of them
+func viewColumns(keys []string) []ColumnSpec {
+	if len(keys) == 0 {
+		return columnSpecs
+	}
+	var columns []ColumnSpec
+	for _, key := range keys {
+		if spec := findColumn(key); spec != nil {
+			columns = append(columns, *spec)
+		}
+	}
+	return columns
+}
+
+// All returns every view, system views first, with user views replacing
+// system views of the same name
+func (s *ViewSet) All() []SavedView {
+	var views []SavedView
+	for _, view := range s.System {
+		if s.userIndex(view.Name) < 0 {
+			views = append(views, view)
+		}
+	}
+	return append(views, s.User...)
+}
+
+// Find returns the view with the given name
+func (s *ViewSet) Find(name string) (SavedView, bool) {
+	for _, view := range s.All() {
+		if view.Name == name {
+			return view, true
+		}
+	}
+	return SavedView{}, false
+}
+
+// put adds a user view or replaces the one with the same name
+func (s *ViewSet) put(view SavedView) {
+	if i := s.userIndex(view.Name); i >= 0 {
+		s.User[i] = view
+		return
+	}
+	s.User = append(s.User, view)
+}
+
+// remove drops a user view; a system view it replaced shows again
+func (s *ViewSet) remove(name string) {
+	if i := s.userIndex(name); i >= 0 {
+		s.User = slices.Delete(s.User, i, i+1)
+	}
+}
+
+// userIndex is the position of the user view with the given name, or -1
+func (s *ViewSet) userIndex(name string) int {
+	return slices.IndexFunc(s.User, func(v SavedView) bool { return v.Name == name })
+}
+
+// applyView switches the table to a view's filter, sort order and columns,
+// keeping the cursor on the same snapshot; the zero view is the default
+func (m *UIState) applyView(view SavedView) {
+	var cursorKey *SnapshotKey
+	if snap := m.currentSnapshot(); snap != nil {
+		key := snap.Key()
+		cursorKey = &key
+	}
+	m.ViewName = view.Name
+	m.Columns = viewColumns(view.Columns)
+	m.SortKey, m.SortReverse = columnSpecs[0].SortField, false
+	if view.SortKey != "" {
+		m.SortKey, m.SortReverse = findColumn(view.SortKey).SortField, view.SortReverse
+	}
+	m.FilterInput.SetValue(view.Filter)
+	m.Filter, m.FilterErr = parseFilter(view.Filter)
+	m.sortSnapshots()
+	m.restoreCursor(cursorKey)
+	m.setActionPreview()
+	if view.Name == "" {
+		m.Status = "Default view"
+	} else {
+		m.Status = fmt.Sprintf("View %s: %d of %d snapshots", view.Name, len(m.Visible), len(m.Snapshots))
+	}
+}
+
+// currentView captures the table's filter, sort order and columns as a view
+func (m UIState) currentView(name string, columns []string) SavedView {
+	view := SavedView{Name: name, SortKey: m.SortKey, SortReverse: m.SortReverse}
+	if m.Filter != nil {
+		view.Filter = m.Filter.Query
+	}
+	if len(columns) < len(columnSpecs) {
+		view.Columns = columns
+	}
+	return view
+}
+
+// viewModified reports whether the filter or sort order moved away from the
+// active view
+func (m UIState) viewModified() bool {
+	view, ok := m.Views.Find(m.ViewName)
+	if !ok {
+		return false
+	}
+	current := m.currentView(view.Name, nil)
+	return current.Filter != view.Filter ||
+		(view.SortKey != "" && (current.SortKey != view.SortKey || current.SortReverse != view.SortReverse))
+}
+
+// cycleView switches to the view after the active one, then back to the
+// default view
+func (m *UIState) cycleView() {
+	views := m.Views.All()
+	if len(views) == 0 {
+		m.Status = "No saved views; press V to save one"
+		return
+	}
+	next := 0
+	if m.ViewName != "" {
+		next = slices.IndexFunc(views, func(v SavedView) bool { return v.Name == m.ViewName }) + 1
+	}
+	if next >= len(views) {
+		m.applyView(SavedView{})
+		return
+	}
+	m.applyView(views[next])
+}
+
+// openViewPicker opens a dialog to switch views, save the current filter,
+// sort and columns as a view, or delete the active one
+func (m *UIState) openViewPicker() tea.Cmd {
+	input := &viewFormInput{Choice: nonEmpty(m.ViewName, viewDefault), Name: m.ViewName}
+	options := []huh.Option[string]{huh.NewOption("Default — all snapshots, all columns", viewDefault)}
+	for _, view := range m.Views.All() {
+		options = append(options, huh.NewOption(view.describe(), view.Name))
+	}
+	options = append(options, huh.NewOption("Save the current filter, sort and columns as a view…", viewSave))
+	if m.Views.userIndex(m.ViewName) >= 0 {
+		options = append(options, huh.NewOption(fmt.Sprintf("Delete view %s", m.ViewName), viewDelete))
+	}
+
+	var columns []huh.Option[string]
+	for _, spec := range columnSpecs {
+		columns = append(columns, huh.NewOption(spec.Label, spec.Key))
+		if slices.ContainsFunc(m.Columns, func(c ColumnSpec) bool { return c.Key == spec.Key }) {
+			input.Columns = append(input.Columns, spec.Key)
+		}
+	}
+
+	m.ViewInput = input
+	m.FormKind = "views"
+	m.Form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("View").
+				Options(options...).
+				Value(&input.Choice),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Name").
+				Description("Saving under an existing name replaces that view").
+				Value(&input.Name).
+				Validate(requireText("name")),
+			huh.NewMultiSelect[string]().
+				Title("Columns").
+				Options(columns...).
+				Value(&input.Columns).
+				Validate(func(keys []string) error {
+					if len(keys) == 0 {
+						return fmt.Errorf("pick at least one column")
+					}
+					return nil
+				}),
+		).WithHideFunc(func() bool { return input.Choice != viewSave }),
+	).WithShowHelp(true).WithWidth(70)
+
+	m.Status = "Views: enter to pick, esc to cancel"
+	return m.Form.Init()
+}
+
+// submitViewPicker switches to the picked view, or saves or deletes one
+func (m UIState) submitViewPicker(input *viewFormInput) (tea.Model, tea.Cmd) {
+	switch input.Choice {
+	case viewDefault:
+		m.applyView(SavedView{})
+	case viewSave:
+		// Keep the columns in table order whatever order they were ticked in
+		var columns []string
+		for _, spec := range columnSpecs {
+			if slices.Contains(input.Columns, spec.Key) {
+				columns = append(columns, spec.Key)
+			}
+		}
+		view := m.currentView(strings.TrimSpace(input.Name), columns)
+		m.Views.put(view)
+		m.applyView(view)
+		m.Status = fmt.Sprintf("Saving view %s...", view.Name)
+		return m, saveViewsCmd(m.Views.UserPath, m.Views.User)
+	case viewDelete:
+		name := m.ViewName
+		m.Views.remove(name)
+		m.ViewName = ""
+		m.Status = fmt.Sprintf("Deleting view %s...", name)
+		return m, saveViewsCmd(m.Views.UserPath, m.Views.User)
+	default:
+		if view, ok := m.Views.Find(input.Choice); ok {
+			m.applyView(view)
+		}
+	}
+	return m, nil
+}
+
+// handleViewsSaved reports where the views went
+func (m UIState) handleViewsSaved(msg ViewsSavedMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		m.Status = fmt.Sprintf("Saving views failed: %v", msg.Err)
+	} else {
+		m.Status = fmt.Sprintf("Saved views to %s", msg.Path)
+	}
+	return m, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// viewNames lists views as "name" or "name(system)"
+func viewNames(views []SavedView) string {
+	var names []string
+	for _, view := range views {
+		name := view.Name
+		if view.System {
+			name += "(system)"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestLoadViewSet(t *testing.T) {
+	dir := t.TempDir()
+	system, user := filepath.Join(dir, "system.json"), filepath.Join(dir, "user.json")
+	writeFile(t, system, `{"views": [
+		{"name": "big", "filter": "size>1G", "sort": "used_space", "reverse": true},
+		{"name": "pairs", "filter": "type:pre", "columns": ["number", "description"]}
+	]}`, 0o644)
+	writeFile(t, user, `{"views": [
+		{"name": "pairs", "filter": "type:post"},
+		{"name": "mine", "filter": "user:alice"}
+	]}`, 0o644)
+
+	set, err := loadViewSet(system, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := viewNames(set.All()); got != "big(system) pairs mine" {
+		t.Errorf("views = %q", got)
+	}
+	// The user's view replaces the system view of the same name
+	if view, ok := set.Find("pairs"); !ok || view.System || view.Filter != "type:post" || view.Columns != nil {
+		t.Errorf("pairs = %+v", view)
+	}
+	if view, _ := set.Find("big"); view.SortKey != "used_space" || !view.SortReverse {
+		t.Errorf("big = %+v", view)
+	}
+	if _, ok := set.Find("missing"); ok {
+		t.Error("found a view that does not exist")
+	}
+	if set.UserPath != user {
+		t.Errorf("UserPath = %q", set.UserPath)
+	}
+
+	// Missing files hold no views
+	empty, err := loadViewSet(filepath.Join(dir, "none.json"), "")
+	if err != nil || len(empty.All()) != 0 {
+		t.Errorf("missing files: %v, %v", viewNames(empty.All()), err)
+	}
+}
+
+func TestReadViewsFileRejectsBadViews(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{`{"views": [`, "unexpected end of JSON input"},
+		{`{"views": [{"name": "a"}, {"name": "a"}]}`, `view "a" is defined twice`},
+		{`{"views": [{"name": " "}]}`, "a view has no name"},
+		{`{"views": [{"name": "a", "filter": "desc:\"open"}]}`, `view "a": filter: unterminated quote`},
+		{`{"views": [{"name": "a", "sort": "colour"}]}`, `view "a": unknown sort column "colour"`},
+		{`{"views": [{"name": "a", "columns": ["number", "colour"]}]}`, `view "a": unknown column "colour"`},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "views.json")
+		writeFile(t, path, tt.content, 0o644)
+		_, err := readViewsFile(path)
+		if err == nil || !strings.HasPrefix(err.Error(), path+": ") || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: err = %v, want %q", tt.content, err, tt.err)
+		}
+	}
+}
+
+func TestViewSetPutAndRemove(t *testing.T) {
+	set := &ViewSet{System: []SavedView{{Name: "big", Filter: "size>1G", System: true}}}
+
+	set.put(SavedView{Name: "mine", Filter: "user:alice"})
+	set.put(SavedView{Name: "big", Filter: "size>10G"})
+	set.put(SavedView{Name: "mine", Filter: "user:bob"})
+	if got := viewNames(set.All()); got != "mine big" {
+		t.Errorf("views = %q", got)
+	}
+	if view, _ := set.Find("mine"); view.Filter != "user:bob" {
+		t.Errorf("put did not replace the view: %+v", view)
+	}
+
+	// Removing the user's copy brings the system view back
+	set.remove("big")
+	if view, _ := set.Find("big"); !view.System || view.Filter != "size>1G" {
+		t.Errorf("big after remove = %+v", view)
+	}
+	set.remove("nothing")
+	if got := viewNames(set.All()); got != "big(system) mine" {
+		t.Errorf("views = %q", got)
+	}
+}
+
+func TestWriteViewsFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapper-tui", "views.json")
+	views := []SavedView{
+		{Name: "big", Filter: `desc~"a b"`, SortKey: "date", SortReverse: true, Columns: []string{"number", "date"}},
+		{Name: "plain", System: true},
+	}
+	if err := writeViewsFile(path, views); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readViewsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Filter != views[0].Filter || got[0].SortKey != "date" || !got[0].SortReverse ||
+		strings.Join(got[0].Columns, ",") != "number,date" || got[1].System {
+		t.Errorf("read back %+v", got)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("the temp file was left behind")
+	}
+	if err := writeViewsFile("", views); err == nil {
+		t.Error("wrote views without a path")
+	}
+}
+
+func TestCycleViews(t *testing.T) {
+	m, _ := newTestModel(t, testSnapshots)
+	m.Views = &ViewSet{
+		System: []SavedView{{Name: "home", Filter: "config:home", System: true}},
+		User:   []SavedView{{Name: "timeline", Filter: "cleanup:timeline", Columns: []string{"number", "cleanup"}}},
+	}
+
+	m.cycleView()
+	if m.ViewName != "home" || len(m.Visible) != 2 || len(m.Snapshots) != 5 {
+		t.Errorf("view %q shows %d of %d", m.ViewName, len(m.Visible), len(m.Snapshots))
+	}
+	m.cycleView()
+	if m.ViewName != "timeline" || len(m.Visible) != 1 || len(m.Columns) != 2 {
+		t.Errorf("view %q shows %d rows in %d columns", m.ViewName, len(m.Visible), len(m.Columns))
+	}
+	m.cycleView()
+	if m.ViewName != "" || len(m.Visible) != 5 || len(m.Columns) != len(columnSpecs) || m.Status != "Default view" {
+		t.Errorf("default view %q shows %d rows in %d columns", m.ViewName, len(m.Visible), len(m.Columns))
+	}
+}